@@ -23,6 +23,7 @@ import (
 	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
 	"github.com/quanxiang-cloud/cabin/tailormade/resp"
 	"github.com/quanxiang-cloud/organizations/internal/logic/org/account"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
 	"github.com/quanxiang-cloud/organizations/pkg/code"
 	"github.com/quanxiang-cloud/organizations/pkg/configs"
 	"github.com/quanxiang-cloud/organizations/pkg/header2"
@@ -34,6 +35,7 @@ type Account struct {
 	conf        configs.Config
 	log         logger.AdaptedLogger
 	redisClient redis.UniversalClient
+	search      *user.Search
 }
 
 // NewAccountAPI new
@@ -43,6 +45,7 @@ func NewAccountAPI(conf configs.Config, db *gorm.DB, redisClient redis.Universal
 		conf:        conf,
 		log:         log,
 		redisClient: redisClient,
+		search:      user.GetSearch(),
 	}
 }
 
@@ -55,6 +58,8 @@ func (a *Account) CheckPWD(c *gin.Context) {
 		return
 	}
 	r.Header = c.Request.Header.Clone()
+	r.IP = c.ClientIP()
+	r.UserAgent = c.Request.UserAgent()
 
 	userAccount, err := a.account.CheckPassword(ginheader.MutateContext(c), r)
 	if err != nil {
@@ -122,8 +127,9 @@ func (a *Account) LoginGetCode(c *gin.Context) {
 		return
 	}
 	r.Model = a.conf.VerificationCode.LoginCode
-	_, err = a.account.GetCode(ginheader.MutateContext(c), r)
-	resp.Format(nil, err).Context(c)
+	r.IP = c.ClientIP()
+	res, err := a.account.GetCode(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
 	return
 }
 
@@ -137,8 +143,9 @@ func (a *Account) ResetPasswordGetCode(c *gin.Context) {
 		return
 	}
 	r.Model = a.conf.VerificationCode.ResetCode
-	_, err = a.account.GetCode(ginheader.MutateContext(c), r)
-	resp.Format(nil, err).Context(c)
+	r.IP = c.ClientIP()
+	res, err := a.account.GetCode(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
 	return
 }
 
@@ -152,8 +159,9 @@ func (a *Account) ForgetCode(c *gin.Context) {
 		return
 	}
 	r.Model = a.conf.VerificationCode.ForgetCode
-	_, err = a.account.GetCode(ginheader.MutateContext(c), r)
-	resp.Format(nil, err).Context(c)
+	r.IP = c.ClientIP()
+	res, err := a.account.GetCode(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
 	return
 }
 
@@ -167,8 +175,9 @@ func (a *Account) RegisterCode(c *gin.Context) {
 		return
 	}
 	r.Model = a.conf.VerificationCode.RegisterCode
-	_, err = a.account.GetCode(ginheader.MutateContext(c), r)
-	resp.Format(nil, err).Context(c)
+	r.IP = c.ClientIP()
+	res, err := a.account.GetCode(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
 	return
 }
 
@@ -186,3 +195,190 @@ func (a *Account) UserForgetResetPassword(c *gin.Context) {
 	resp.Format(userAccount, err).Context(c)
 	return
 }
+
+// EnrollFactor enroll second factor, with the login ticket or the login user
+func (a *Account) EnrollFactor(c *gin.Context) {
+	r := new(account.EnrollFactorRequest)
+	err := c.ShouldBind(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	if r.Ticket == "" {
+		r.UserID = header2.GetProfile(c).UserID
+	}
+	res, err := a.account.EnrollFactor(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// ConfirmFactor confirm second factor with the first code
+func (a *Account) ConfirmFactor(c *gin.Context) {
+	r := new(account.ConfirmFactorRequest)
+	err := c.ShouldBind(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	if r.Ticket == "" {
+		r.UserID = header2.GetProfile(c).UserID
+	}
+	res, err := a.account.ConfirmFactor(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// VerifyFactor second step of login
+func (a *Account) VerifyFactor(c *gin.Context) {
+	r := new(account.VerifyFactorRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.IP = c.ClientIP()
+	r.UserAgent = c.Request.UserAgent()
+	res, err := a.account.VerifyFactor(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// AdminResetFactor admin reset users second factor
+func (a *Account) AdminResetFactor(c *gin.Context) {
+	r := new(account.ResetFactorRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := a.account.ResetFactor(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// ListLocks list locked ip and accounts
+func (a *Account) ListLocks(c *gin.Context) {
+	r := new(account.ListLocksRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := a.account.ListLocks(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// Unlock unlock ip or accounts
+func (a *Account) Unlock(c *gin.Context) {
+	r := new(account.UnlockRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.UnlockBy = header2.GetProfile(c).UserID
+	res, err := a.account.Unlock(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// ListLoginEvents login audit of tenant
+func (a *Account) ListLoginEvents(c *gin.Context) {
+	r := new(account.ListLoginEventsRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := a.account.ListLoginEvents(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// SendLink email login link
+func (a *Account) SendLink(c *gin.Context) {
+	r := new(account.SendLinkRequest)
+	err := c.ShouldBind(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.IP = c.ClientIP()
+	res, err := a.account.SendLink(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// LinkLogin login with the token of login link
+func (a *Account) LinkLogin(c *gin.Context) {
+	r := new(account.LinkLoginRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.IP = c.ClientIP()
+	r.UserAgent = c.Request.UserAgent()
+	res, err := a.account.LinkLogin(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// InvitationInfo who an invitation link is for
+func (a *Account) InvitationInfo(c *gin.Context) {
+	r := new(account.InvitationInfoRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := a.account.InvitationInfo(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// AcceptInvitation set password with an invitation link
+func (a *Account) AcceptInvitation(c *gin.Context) {
+	r := new(account.AcceptInvitationRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := a.account.AcceptInvitation(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// ChangeCode send code to the new email or phone of login user
+func (a *Account) ChangeCode(c *gin.Context) {
+	r := new(account.ChangeCodeRequest)
+	err := c.ShouldBind(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.UserID = header2.GetProfile(c).UserID
+	r.IP = c.ClientIP()
+	res, err := a.account.ChangeCode(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// ChangeAccount change login email or phone of login user
+func (a *Account) ChangeAccount(c *gin.Context) {
+	r := new(account.ChangeAccountRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.UserID = header2.GetProfile(c).UserID
+	res, err := a.account.ChangeAccount(ginheader.MutateContext(c), r)
+	if err == nil {
+		a.search.PushUser(ginheader.MutateContext(c), nil, res.User)
+	}
+	resp.Format(res, err).Context(c)
+	return
+}
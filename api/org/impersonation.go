@@ -0,0 +1,132 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/impersonation"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+)
+
+const impersonationHeader = "X-Impersonation-Token"
+
+// Impersonation impersonation api
+type Impersonation struct {
+	impersonation impersonation.Impersonation
+	log           logger.AdaptedLogger
+}
+
+// NewImpersonationAPI new
+func NewImpersonationAPI(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient, log logger.AdaptedLogger) Impersonation {
+	return Impersonation{
+		impersonation: impersonation.NewImpersonation(conf, db, redisClient),
+		log:           log,
+	}
+}
+
+// Impersonate take the impersonated user as the profile of requests carrying a token of the caller,
+// every write made with it is audited
+func (i *Impersonation) Impersonate(c *gin.Context) {
+	// only this middleware marks a request as impersonated
+	header2.SetImpersonator(c, "")
+	token := c.GetHeader(impersonationHeader)
+	if token == "" {
+		c.Next()
+		return
+	}
+	actorID := header2.GetProfile(c).UserID
+	s, err := i.impersonation.Resolve(ginheader.MutateContext(c), token, actorID)
+	if err != nil {
+		resp.Format(nil, err).Context(c, http.StatusUnauthorized)
+		c.Abort()
+		return
+	}
+	header2.SetProfile(c, s.Profile())
+	header2.SetImpersonator(c, s.ActorID)
+	c.Next()
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+	i.impersonation.Record(ginheader.MutateContext(c), s, &impersonation.RecordRequest{
+		Method: c.Request.Method,
+		Path:   c.Request.URL.Path,
+		Status: c.Writer.Status(),
+		IP:     c.ClientIP(),
+	})
+}
+
+// Deny refuse impersonated requests, for routes changing credentials of the user
+func (i *Impersonation) Deny(c *gin.Context) {
+	if header2.GetImpersonator(c) != "" {
+		resp.Format(nil, error2.New(code.ImpersonatedCredential)).Context(c, http.StatusForbidden)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// Start start acting as a user
+func (i *Impersonation) Start(c *gin.Context) {
+	r := new(impersonation.StartRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	profile := header2.GetProfile(c)
+	r.ActorID = profile.UserID
+	r.ActorName = profile.UserName
+	r.IP = c.ClientIP()
+	res, err := i.impersonation.Start(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// Stop stop acting as a user
+func (i *Impersonation) Stop(c *gin.Context) {
+	r := new(impersonation.StopRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.ActorID = header2.GetProfile(c).UserID
+	r.IP = c.ClientIP()
+	res, err := i.impersonation.Stop(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// ListEvents list impersonation events
+func (i *Impersonation) ListEvents(c *gin.Context) {
+	r := new(impersonation.ListEventsRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := i.impersonation.ListEvents(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
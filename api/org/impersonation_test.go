@@ -0,0 +1,52 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+)
+
+func TestDeny(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	i := &Impersonation{}
+	engine := gin.New()
+	reached := false
+	engine.POST("/h/account/change", func(c *gin.Context) {
+		header2.SetImpersonator(c, c.GetHeader("X-Actor"))
+		c.Next()
+	}, i.Deny, func(c *gin.Context) {
+		reached = true
+		c.Status(http.StatusOK)
+	})
+
+	// the user itself
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/h/account/change", nil))
+	assert.True(t, reached)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// an admin acting as the user
+	reached = false
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/h/account/change", nil)
+	req.Header.Set("X-Actor", "admin")
+	engine.ServeHTTP(w, req)
+	assert.False(t, reached)
+}
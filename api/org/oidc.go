@@ -0,0 +1,201 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginlogger "github.com/quanxiang-cloud/cabin/tailormade/gin"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/oidc"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+)
+
+// Oidc openid connect provider api
+type Oidc struct {
+	provider oidc.Provider
+	log      logger.AdaptedLogger
+}
+
+// NewOidcAPI new
+func NewOidcAPI(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient, log logger.AdaptedLogger) (Oidc, error) {
+	provider, err := oidc.NewProvider(conf, db, redisClient)
+	if err != nil {
+		return Oidc{}, err
+	}
+	return Oidc{
+		provider: provider,
+		log:      log,
+	}, nil
+}
+
+// Discovery openid configuration
+func (o *Oidc) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, o.provider.Discovery())
+}
+
+// JWKS signing keys
+func (o *Oidc) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, o.provider.JWKS())
+}
+
+// Authorize authorization endpoint, the request goes on to the login page which posts it to Login
+func (o *Oidc) Authorize(c *gin.Context) {
+	r := new(oidc.AuthorizeRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := o.provider.Authorize(ginheader.MutateContext(c), r)
+	if err != nil {
+		resp.Format(nil, err).Context(c)
+		return
+	}
+	c.Redirect(http.StatusFound, res.RedirectURI)
+}
+
+// Login login of the authorization request, posted by the login page
+func (o *Oidc) Login(c *gin.Context) {
+	r := new(oidc.AuthorizeRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.Header = c.Request.Header.Clone()
+	r.IP = c.ClientIP()
+	r.UserAgent = c.Request.UserAgent()
+	res, err := o.provider.Authorize(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// Token token endpoint
+func (o *Oidc) Token(c *gin.Context) {
+	r := new(oidc.TokenRequest)
+	err := c.ShouldBind(r)
+	if err != nil {
+		o.oauthError(c, error2.New(code.InvalidParams))
+		return
+	}
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		r.ClientID, _ = url.QueryUnescape(id)
+		r.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+	res, err := o.provider.Token(ginheader.MutateContext(c), r)
+	if err != nil {
+		o.oauthError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, res)
+}
+
+// UserInfo userinfo endpoint
+func (o *Oidc) UserInfo(c *gin.Context) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	res, err := o.provider.UserInfo(ginheader.MutateContext(c), &oidc.UserInfoRequest{AccessToken: token})
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		o.oauthError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// AddClient register client
+func (o *Oidc) AddClient(c *gin.Context) {
+	r := new(oidc.AddClientRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.CreatedBy = header2.GetProfile(c).UserID
+	res, err := o.provider.AddClient(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// UpdateClient update client
+func (o *Oidc) UpdateClient(c *gin.Context) {
+	r := new(oidc.UpdateClientRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.UpdatedBy = header2.GetProfile(c).UserID
+	res, err := o.provider.UpdateClient(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// DeleteClient delete clients
+func (o *Oidc) DeleteClient(c *gin.Context) {
+	r := new(oidc.DeleteClientRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := o.provider.DeleteClient(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// ListClients list clients
+func (o *Oidc) ListClients(c *gin.Context) {
+	r := new(oidc.ListClientsRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := o.provider.ListClients(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// oauthError writes the oauth 2.0 error response of err
+func (o *Oidc) oauthError(c *gin.Context, err error) {
+	status, errCode := http.StatusInternalServerError, "server_error"
+	if e, ok := err.(error2.Error); ok {
+		switch e.Code {
+		case code.InvalidClient:
+			status, errCode = http.StatusUnauthorized, "invalid_client"
+		case code.InvalidGrant:
+			status, errCode = http.StatusBadRequest, "invalid_grant"
+		case code.UnsupportedGrantType:
+			status, errCode = http.StatusBadRequest, "unsupported_grant_type"
+		case code.InvalidAccessToken:
+			status, errCode = http.StatusUnauthorized, "invalid_token"
+		default:
+			status, errCode = http.StatusBadRequest, "invalid_request"
+		}
+	} else {
+		o.log.Error(err.Error(), ginlogger.GetRequestID(c))
+	}
+	c.JSON(status, gin.H{
+		"error":             errCode,
+		"error_description": err.Error(),
+	})
+}
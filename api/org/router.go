@@ -84,18 +84,39 @@ func NewRouter(ctx context.Context, c configs.Config, log logger.AdaptedLogger)
 		manageUser.POST("/list", userAPI.PageList)
 		manageUser.GET("/template", userAPI.GetTemplateFile)
 		manageUser.POST("/importFile", userAPI.ImportFile)
+		manageUser.POST("/export", userAPI.Export)
 		manageUser.GET("/info", userAPI.AdminUserInfo)
 		manageUser.PUT("/change/dep", userAPI.AdminChangeUsersDEP)
 		manageUser.GET("/index/count", userAPI.IndexCount)
+		manageUser.POST("/invitation/resend", userAPI.ResendInvitation)
+		manageUser.POST("/invitation/revoke", userAPI.RevokeInvitation)
+		manageUser.GET("/invitation/list", userAPI.ListInvitations)
+		manageUser.POST("/import/job", userAPI.SubmitImportJob)
+		manageUser.GET("/import/job/info", userAPI.ImportJobInfo)
+		manageUser.POST("/import/job/cancel", userAPI.CancelImportJob)
+		manageUser.GET("/import/job/fail", userAPI.ImportJobFailFile)
+		manageUser.GET("/import/job/list", userAPI.ListImportJobs)
 
 	}
 	accountAPI := NewAccountAPI(c, db, redisClient, log)
 	manageAccount := manage.Group("/account")
 	{
 		manageAccount.POST("/admin/reset", accountAPI.AdminResetPassword)
+		manageAccount.POST("/factor/reset", accountAPI.AdminResetFactor)
+		manageAccount.GET("/locks", accountAPI.ListLocks)
+		manageAccount.POST("/locks/unlock", accountAPI.Unlock)
+		manageAccount.GET("/login/events", accountAPI.ListLoginEvents)
 	}
 
-	viewer := v1.Group("/h")
+	impersonationAPI := NewImpersonationAPI(c, db, redisClient, log)
+	manageImpersonation := manage.Group("/impersonation")
+	{
+		manageImpersonation.POST("/start", impersonationAPI.Start)
+		manageImpersonation.POST("/stop", impersonationAPI.Stop)
+		manageImpersonation.GET("/events", impersonationAPI.ListEvents)
+	}
+
+	viewer := v1.Group("/h", impersonationAPI.Impersonate)
 	viewerAccount := viewer.Group("/account")
 	{
 
@@ -104,9 +125,18 @@ func NewRouter(ctx context.Context, c configs.Config, log logger.AdaptedLogger)
 		viewerAccount.GET("/forget/code", accountAPI.ForgetCode)
 		viewerAccount.GET("/register/code", accountAPI.RegisterCode)
 		viewerAccount.POST("/check", accountAPI.CheckPWD)
-		viewerAccount.POST("/user/reset", accountAPI.UserResetPassword)
+		viewerAccount.POST("/user/reset", impersonationAPI.Deny, accountAPI.UserResetPassword)
 		viewerAccount.POST("/user/forget", accountAPI.UserForgetResetPassword)
-		viewerAccount.POST("/user/first/reset", accountAPI.UserFirstResetPassword)
+		viewerAccount.POST("/user/first/reset", impersonationAPI.Deny, accountAPI.UserFirstResetPassword)
+		viewerAccount.POST("/check/factor", accountAPI.VerifyFactor)
+		viewerAccount.POST("/factor/enroll", impersonationAPI.Deny, accountAPI.EnrollFactor)
+		viewerAccount.POST("/factor/confirm", impersonationAPI.Deny, accountAPI.ConfirmFactor)
+		viewerAccount.GET("/link/code", impersonationAPI.Deny, accountAPI.SendLink)
+		viewerAccount.POST("/link", impersonationAPI.Deny, accountAPI.LinkLogin)
+		viewerAccount.GET("/invitation", accountAPI.InvitationInfo)
+		viewerAccount.POST("/invitation/accept", impersonationAPI.Deny, accountAPI.AcceptInvitation)
+		viewerAccount.GET("/change/code", impersonationAPI.Deny, accountAPI.ChangeCode)
+		viewerAccount.POST("/change", impersonationAPI.Deny, accountAPI.ChangeAccount)
 	}
 	viewerUser := viewer.Group("/user")
 	{
@@ -152,7 +182,20 @@ func NewRouter(ctx context.Context, c configs.Config, log logger.AdaptedLogger)
 		manageColumn.PUT("/update/name", columnAPI.Update)
 	}
 
-	oth := v1.Group("/o")
+	serviceAccountAPI := NewServiceAccountAPI(c, db, log)
+	manageService := manage.Group("/service")
+	{
+		manageService.POST("/account/add", serviceAccountAPI.Add)
+		manageService.PUT("/account/update", serviceAccountAPI.Update)
+		manageService.POST("/account/del", serviceAccountAPI.Delete)
+		manageService.GET("/account/list", serviceAccountAPI.PageList)
+		manageService.POST("/key/add", serviceAccountAPI.AddKey)
+		manageService.POST("/key/rotate", serviceAccountAPI.RotateKey)
+		manageService.POST("/key/revoke", serviceAccountAPI.RevokeKey)
+		manageService.GET("/key/list", serviceAccountAPI.ListKeys)
+	}
+
+	oth := v1.Group("/o", serviceAccountAPI.Auth)
 	otherUser := oth.Group("/user")
 	{
 
@@ -170,6 +213,49 @@ func NewRouter(ctx context.Context, c configs.Config, log logger.AdaptedLogger)
 		otherDep.POST("/del", depAPI.DeleteDepByID)
 		otherDep.GET("/max/grade", depAPI.GetMaxGrade)
 	}
+	if c.Oidc.Issuer != "" {
+		oidcAPI, err := NewOidcAPI(c, db, redisClient, log)
+		if err != nil {
+			return nil, err
+		}
+		oidcGroup := v1.Group("/oidc")
+		{
+			oidcGroup.GET("/.well-known/openid-configuration", oidcAPI.Discovery)
+			oidcGroup.GET("/jwks", oidcAPI.JWKS)
+			oidcGroup.GET("/authorize", oidcAPI.Authorize)
+			oidcGroup.POST("/authorize", oidcAPI.Login)
+			oidcGroup.POST("/token", oidcAPI.Token)
+			oidcGroup.GET("/userinfo", oidcAPI.UserInfo)
+			oidcGroup.POST("/userinfo", oidcAPI.UserInfo)
+		}
+		manageOidc := manage.Group("/oidc")
+		{
+			manageOidc.POST("/client/add", oidcAPI.AddClient)
+			manageOidc.PUT("/client/update", oidcAPI.UpdateClient)
+			manageOidc.POST("/client/del", oidcAPI.DeleteClient)
+			manageOidc.GET("/client/list", oidcAPI.ListClients)
+		}
+	}
+
+	scimAPI := NewScimAPI(c, db, redisClient, log)
+	scimGroup := v1.Group(scimPrefix, scimAPI.Auth)
+	{
+		scimGroup.GET("/ServiceProviderConfig", scimAPI.ServiceProviderConfig)
+		scimGroup.GET("/Schemas", scimAPI.Schemas)
+		scimGroup.GET("/ResourceTypes", scimAPI.ResourceTypes)
+		scimGroup.GET("/Users", scimAPI.ListUsers)
+		scimGroup.POST("/Users", scimAPI.CreateUser)
+		scimGroup.GET("/Users/:id", scimAPI.GetUser)
+		scimGroup.PUT("/Users/:id", scimAPI.ReplaceUser)
+		scimGroup.PATCH("/Users/:id", scimAPI.PatchUser)
+		scimGroup.DELETE("/Users/:id", scimAPI.DeleteUser)
+		scimGroup.GET("/Groups", scimAPI.ListGroups)
+		scimGroup.POST("/Groups", scimAPI.CreateGroup)
+		scimGroup.GET("/Groups/:id", scimAPI.GetGroup)
+		scimGroup.PUT("/Groups/:id", scimAPI.ReplaceGroup)
+		scimGroup.PATCH("/Groups/:id", scimAPI.PatchGroup)
+		scimGroup.DELETE("/Groups/:id", scimAPI.DeleteGroup)
+	}
 	if err != nil {
 		panic(err)
 	}
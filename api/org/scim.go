@@ -0,0 +1,338 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginlogger "github.com/quanxiang-cloud/cabin/tailormade/gin"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/scim"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+)
+
+const (
+	scimContentType = "application/scim+json"
+	scimPrefix      = "/scim/v2"
+)
+
+// ScimAPI scim 2.0 provisioning api
+type ScimAPI struct {
+	scim   scim.Scim
+	search *user.Search
+	conf   configs.Config
+	log    logger.AdaptedLogger
+}
+
+// NewScimAPI new
+func NewScimAPI(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient, log logger.AdaptedLogger) ScimAPI {
+	return ScimAPI{
+		scim:   scim.NewScim(conf, db, redisClient),
+		search: user.GetSearch(),
+		conf:   conf,
+		log:    log,
+	}
+}
+
+// Auth checks the bearer token and scopes the request to the tenant of the token
+func (s *ScimAPI) Auth(c *gin.Context) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	for _, v := range s.conf.Scim.Tokens {
+		if token != "" && v.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(v.Token)) == 1 {
+			c.Request.Header.Set(user.TenantID, v.TenantID)
+			c.Request.Header.Del("User-Id")
+			c.Next()
+			return
+		}
+	}
+	c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+	s.fail(c, http.StatusUnauthorized, "", "invalid bearer token")
+	c.Abort()
+}
+
+// ServiceProviderConfig service provider config
+func (s *ScimAPI) ServiceProviderConfig(c *gin.Context) {
+	s.reply(c, http.StatusOK, scim.ServiceProviderConfig())
+}
+
+// Schemas schemas
+func (s *ScimAPI) Schemas(c *gin.Context) {
+	s.reply(c, http.StatusOK, s.list(scim.Schemas()))
+}
+
+// ResourceTypes resource types
+func (s *ScimAPI) ResourceTypes(c *gin.Context) {
+	s.reply(c, http.StatusOK, s.list(scim.ResourceTypes()))
+}
+
+// ListUsers list users
+func (s *ScimAPI) ListUsers(c *gin.Context) {
+	r := new(scim.ListRequest)
+	if err := c.ShouldBindQuery(r); err != nil {
+		s.error(c, error2.New(code.InvalidParams))
+		return
+	}
+	res, err := s.scim.ListUsers(ginheader.MutateContext(c), r)
+	if err != nil {
+		s.error(c, err)
+		return
+	}
+	for _, v := range res.Resources {
+		s.userLocation(c, v.(*scim.User))
+	}
+	s.reply(c, http.StatusOK, res)
+}
+
+// GetUser get user
+func (s *ScimAPI) GetUser(c *gin.Context) {
+	res, err := s.scim.GetUser(ginheader.MutateContext(c), &scim.GetRequest{ID: c.Param("id")})
+	s.userReply(c, http.StatusOK, res, err)
+}
+
+// CreateUser create user
+func (s *ScimAPI) CreateUser(c *gin.Context) {
+	r := new(scim.User)
+	if err := c.ShouldBindJSON(r); err != nil {
+		s.log.Error(err.Error(), ginlogger.GetRequestID(c))
+		s.error(c, error2.New(code.InvalidParams))
+		return
+	}
+	res, err := s.scim.CreateUser(ginheader.MutateContext(c), r)
+	s.userReply(c, http.StatusCreated, res, err)
+}
+
+// ReplaceUser put user
+func (s *ScimAPI) ReplaceUser(c *gin.Context) {
+	r := &scim.ReplaceUserRequest{ID: c.Param("id"), User: new(scim.User)}
+	if err := c.ShouldBindJSON(r.User); err != nil {
+		s.log.Error(err.Error(), ginlogger.GetRequestID(c))
+		s.error(c, error2.New(code.InvalidParams))
+		return
+	}
+	res, err := s.scim.ReplaceUser(ginheader.MutateContext(c), r)
+	s.userReply(c, http.StatusOK, res, err)
+}
+
+// PatchUser patch user
+func (s *ScimAPI) PatchUser(c *gin.Context) {
+	r := &scim.PatchResourceRequest{ID: c.Param("id"), Patch: new(scim.PatchRequest)}
+	if err := c.ShouldBindJSON(r.Patch); err != nil {
+		s.log.Error(err.Error(), ginlogger.GetRequestID(c))
+		s.error(c, error2.New(code.InvalidPatch))
+		return
+	}
+	res, err := s.scim.PatchUser(ginheader.MutateContext(c), r)
+	s.userReply(c, http.StatusOK, res, err)
+}
+
+// DeleteUser delete user
+func (s *ScimAPI) DeleteUser(c *gin.Context) {
+	res, err := s.scim.DeleteUser(ginheader.MutateContext(c), &scim.GetRequest{ID: c.Param("id")})
+	s.userReply(c, http.StatusNoContent, res, err)
+}
+
+// ListGroups list groups
+func (s *ScimAPI) ListGroups(c *gin.Context) {
+	r := new(scim.ListRequest)
+	if err := c.ShouldBindQuery(r); err != nil {
+		s.error(c, error2.New(code.InvalidParams))
+		return
+	}
+	res, err := s.scim.ListGroups(ginheader.MutateContext(c), r)
+	if err != nil {
+		s.error(c, err)
+		return
+	}
+	for _, v := range res.Resources {
+		s.groupLocation(c, v.(*scim.Group))
+	}
+	s.reply(c, http.StatusOK, res)
+}
+
+// GetGroup get group
+func (s *ScimAPI) GetGroup(c *gin.Context) {
+	res, err := s.scim.GetGroup(ginheader.MutateContext(c), &scim.GetRequest{ID: c.Param("id")})
+	s.groupReply(c, http.StatusOK, res, err, false)
+}
+
+// CreateGroup create group
+func (s *ScimAPI) CreateGroup(c *gin.Context) {
+	r := new(scim.Group)
+	if err := c.ShouldBindJSON(r); err != nil {
+		s.log.Error(err.Error(), ginlogger.GetRequestID(c))
+		s.error(c, error2.New(code.InvalidParams))
+		return
+	}
+	res, err := s.scim.CreateGroup(ginheader.MutateContext(c), r)
+	s.groupReply(c, http.StatusCreated, res, err, true)
+}
+
+// ReplaceGroup put group
+func (s *ScimAPI) ReplaceGroup(c *gin.Context) {
+	r := &scim.ReplaceGroupRequest{ID: c.Param("id"), Group: new(scim.Group)}
+	if err := c.ShouldBindJSON(r.Group); err != nil {
+		s.log.Error(err.Error(), ginlogger.GetRequestID(c))
+		s.error(c, error2.New(code.InvalidParams))
+		return
+	}
+	res, err := s.scim.ReplaceGroup(ginheader.MutateContext(c), r)
+	s.groupReply(c, http.StatusOK, res, err, true)
+}
+
+// PatchGroup patch group
+func (s *ScimAPI) PatchGroup(c *gin.Context) {
+	r := &scim.PatchResourceRequest{ID: c.Param("id"), Patch: new(scim.PatchRequest)}
+	if err := c.ShouldBindJSON(r.Patch); err != nil {
+		s.log.Error(err.Error(), ginlogger.GetRequestID(c))
+		s.error(c, error2.New(code.InvalidPatch))
+		return
+	}
+	res, err := s.scim.PatchGroup(ginheader.MutateContext(c), r)
+	s.groupReply(c, http.StatusOK, res, err, true)
+}
+
+// DeleteGroup delete group
+func (s *ScimAPI) DeleteGroup(c *gin.Context) {
+	res, err := s.scim.DeleteGroup(ginheader.MutateContext(c), &scim.GetRequest{ID: c.Param("id")})
+	s.groupReply(c, http.StatusNoContent, res, err, true)
+}
+
+func (s *ScimAPI) userReply(c *gin.Context, status int, res *scim.UserResponse, err error) {
+	if err != nil {
+		s.error(c, err)
+		return
+	}
+	if len(res.Changed) > 0 {
+		s.search.PushUser(ginheader.MutateContext(c), nil, res.Changed...)
+	}
+	if res.User == nil {
+		c.Status(status)
+		return
+	}
+	s.userLocation(c, res.User)
+	s.reply(c, status, res.User)
+}
+
+func (s *ScimAPI) groupReply(c *gin.Context, status int, res *scim.GroupResponse, err error, changed bool) {
+	if err != nil {
+		s.error(c, err)
+		return
+	}
+	if len(res.Changed) > 0 {
+		s.search.PushUser(ginheader.MutateContext(c), nil, res.Changed...)
+	}
+	if changed {
+		s.search.PushDep(ginheader.MutateContext(c), nil)
+	}
+	if res.Group == nil {
+		c.Status(status)
+		return
+	}
+	s.groupLocation(c, res.Group)
+	s.reply(c, status, res.Group)
+}
+
+func (s *ScimAPI) userLocation(c *gin.Context, one *scim.User) {
+	if one.Meta != nil {
+		one.Meta.Location = s.location(c, "Users", one.ID)
+	}
+}
+
+func (s *ScimAPI) groupLocation(c *gin.Context, one *scim.Group) {
+	if one.Meta != nil {
+		one.Meta.Location = s.location(c, "Groups", one.ID)
+	}
+}
+
+// location absolute url of a resource, built from the request
+func (s *ScimAPI) location(c *gin.Context, resourceType, id string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	path := c.Request.URL.Path
+	if i := strings.Index(path, scimPrefix); i >= 0 {
+		path = path[:i+len(scimPrefix)]
+	}
+	return scheme + "://" + c.Request.Host + path + "/" + resourceType + "/" + id
+}
+
+func (s *ScimAPI) list(resources []map[string]interface{}) *scim.ListResponse {
+	res := &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    make([]interface{}, 0, len(resources)),
+	}
+	for _, v := range resources {
+		res.Resources = append(res.Resources, v)
+	}
+	return res
+}
+
+func (s *ScimAPI) reply(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+// error writes the scim error response of err
+func (s *ScimAPI) error(c *gin.Context, err error) {
+	status, scimType := http.StatusInternalServerError, ""
+	if e, ok := err.(error2.Error); ok {
+		switch e.Code {
+		case code.DataNotExist:
+			status = http.StatusNotFound
+		case code.AccountExist, code.NameUsed, code.TopDepExist:
+			status, scimType = http.StatusConflict, "uniqueness"
+		case code.InvalidFilter:
+			status, scimType = http.StatusBadRequest, "invalidFilter"
+		case code.TooManyResults:
+			status, scimType = http.StatusBadRequest, "tooMany"
+		case code.InvalidPatch:
+			status, scimType = http.StatusBadRequest, "invalidPath"
+		case code.InvalidParams, code.InvalidEmail, code.InvalidPhone, code.EmailRequired,
+			code.ErrCircleData, code.CanNotDel, code.InvalidDELDEP:
+			status, scimType = http.StatusBadRequest, "invalidValue"
+		}
+	} else {
+		s.log.Error(err.Error(), ginlogger.GetRequestID(c))
+	}
+	s.fail(c, status, scimType, err.Error())
+}
+
+func (s *ScimAPI) fail(c *gin.Context, status int, scimType, detail string) {
+	body := map[string]interface{}{
+		"schemas": []string{scim.SchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	s.reply(c, status, body)
+}
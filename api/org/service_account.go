@@ -0,0 +1,181 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/serviceaccount"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+)
+
+const apiKeyHeader = "X-Api-Key"
+
+// ServiceAccount service account api
+type ServiceAccount struct {
+	serviceAccount serviceaccount.ServiceAccount
+	conf           configs.Config
+	log            logger.AdaptedLogger
+}
+
+// NewServiceAccountAPI new
+func NewServiceAccountAPI(conf configs.Config, db *gorm.DB, log logger.AdaptedLogger) ServiceAccount {
+	return ServiceAccount{
+		serviceAccount: serviceaccount.NewServiceAccount(conf, db),
+		conf:           conf,
+		log:            log,
+	}
+}
+
+// Auth authenticate the api key of request and take its service account as the profile,
+// requests without a key pass unless serviceAccount.required is on
+func (s *ServiceAccount) Auth(c *gin.Context) {
+	key := c.GetHeader(apiKeyHeader)
+	if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); key == "" && strings.HasPrefix(bearer, serviceaccount.KeyPrefix) {
+		key = bearer
+	}
+	if key == "" {
+		if s.conf.ServiceAccount.Required {
+			resp.Format(nil, error2.New(code.InvalidAPIKey)).Context(c, http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Next()
+		return
+	}
+	res, err := s.serviceAccount.Authenticate(c, &serviceaccount.AuthenticateRequest{
+		Key: key,
+		IP:  c.ClientIP(),
+	})
+	if err != nil {
+		resp.Format(nil, err).Context(c, http.StatusUnauthorized)
+		c.Abort()
+		return
+	}
+	header2.SetProfile(c, header2.Profile{
+		UserID:   res.ID,
+		UserName: res.Name,
+		TenantID: res.TenantID,
+	})
+	c.Next()
+}
+
+// Add add service account
+func (s *ServiceAccount) Add(c *gin.Context) {
+	r := new(serviceaccount.AddRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.CreatedBy = header2.GetProfile(c).UserID
+	res, err := s.serviceAccount.Add(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// Update update service account
+func (s *ServiceAccount) Update(c *gin.Context) {
+	r := new(serviceaccount.UpdateRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.UpdatedBy = header2.GetProfile(c).UserID
+	res, err := s.serviceAccount.Update(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// Delete delete service accounts
+func (s *ServiceAccount) Delete(c *gin.Context) {
+	r := new(serviceaccount.DeleteRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := s.serviceAccount.Delete(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// PageList list service accounts
+func (s *ServiceAccount) PageList(c *gin.Context) {
+	r := new(serviceaccount.PageListRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := s.serviceAccount.PageList(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// AddKey add api key
+func (s *ServiceAccount) AddKey(c *gin.Context) {
+	r := new(serviceaccount.AddKeyRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.CreatedBy = header2.GetProfile(c).UserID
+	res, err := s.serviceAccount.AddKey(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// RotateKey rotate api key
+func (s *ServiceAccount) RotateKey(c *gin.Context) {
+	r := new(serviceaccount.RotateKeyRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.CreatedBy = header2.GetProfile(c).UserID
+	res, err := s.serviceAccount.RotateKey(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// RevokeKey revoke api key
+func (s *ServiceAccount) RevokeKey(c *gin.Context) {
+	r := new(serviceaccount.RevokeKeyRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := s.serviceAccount.RevokeKey(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// ListKeys list api keys
+func (s *ServiceAccount) ListKeys(c *gin.Context) {
+	r := new(serviceaccount.ListKeysRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := s.serviceAccount.ListKeys(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
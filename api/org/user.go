@@ -38,6 +38,8 @@ type UserAPI struct {
 	conf        configs.Config
 	redisClient redis.UniversalClient
 	search      *user.Search
+	invitation  user.Invitation
+	importJob   user.ImportJob
 }
 
 // NewUserAPI new
@@ -50,6 +52,8 @@ func NewUserAPI(conf configs.Config, db *gorm.DB, redisClient redis.UniversalCli
 		conf:        conf,
 		redisClient: redisClient,
 		search:      user.GetSearch(),
+		invitation:  user.NewInvitation(conf, db),
+		importJob:   user.NewImportJob(conf, db, redisClient),
 	}
 }
 
@@ -63,6 +67,7 @@ func (u *UserAPI) Add(c *gin.Context) {
 		return
 	}
 
+	r.Profile = header2.GetProfile(c)
 	r.Password = user.CreatePassword(ginheader.MutateContext(c), u.conf, u.redisClient)
 	res, err := u.user.Add(ginheader.MutateContext(c), r)
 	if err != nil {
@@ -170,6 +175,47 @@ func (u *UserAPI) UpdateUsersStatus(c *gin.Context) {
 	return
 }
 
+// ResendInvitation send a pending invitation again with a new link
+func (u *UserAPI) ResendInvitation(c *gin.Context) {
+	r := new(user.ResendInvitationRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.Profile = header2.GetProfile(c)
+	res, err := u.invitation.Resend(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// RevokeInvitation revoke a pending invitation
+func (u *UserAPI) RevokeInvitation(c *gin.Context) {
+	r := new(user.RevokeInvitationRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.Profile = header2.GetProfile(c)
+	res, err := u.invitation.Revoke(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
+// ListInvitations invitations of tenant, pending ones by default
+func (u *UserAPI) ListInvitations(c *gin.Context) {
+	r := new(user.ListInvitationRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := u.invitation.PageList(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+	return
+}
+
 // GetTemplateFile get file template
 func (u *UserAPI) GetTemplateFile(c *gin.Context) {
 	r := new(user.GetTemplateFileRequest)
@@ -189,13 +235,51 @@ func (u *UserAPI) GetTemplateFile(c *gin.Context) {
 
 }
 
+// Export download the users matching the filters of the list
+func (u *UserAPI) Export(c *gin.Context) {
+	r := new(user.ExportRequest)
+	err := c.ShouldBind(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	contentType := "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	if r.Format == user.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w := &downloadWriter{
+		c:           c,
+		contentType: contentType,
+		fileName:    user.ExportFileName(r.Format),
+	}
+	err = u.user.Export(ginheader.MutateContext(c), r, w)
+	if err != nil {
+		u.log.Error(err.Error(), ginlogger.GetRequestID(c))
+		if !c.Writer.Written() {
+			resp.Format(nil, err).Context(c)
+		}
+	}
+}
+
+// downloadWriter the response becomes a file with its first bytes, an error before them is answered as usual
+type downloadWriter struct {
+	c           *gin.Context
+	contentType string
+	fileName    string
+}
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	if !d.c.Writer.Written() {
+		d.c.Header("Content-Type", d.contentType)
+		d.c.Header("Content-Disposition", "attachment; filename="+d.fileName)
+	}
+	return d.c.Writer.Write(p)
+}
+
 // ImportFile 上传文件
 func (u *UserAPI) ImportFile(c *gin.Context) {
 
 	profile := header2.GetProfile(c)
-	file, _ := c.FormFile("file")
-	filename := file.Filename
-	index := filename[strings.LastIndex(filename, "."):]
 	r := new(user.ImportFileRequest)
 	err := c.ShouldBind(r)
 	if err != nil {
@@ -203,31 +287,119 @@ func (u *UserAPI) ImportFile(c *gin.Context) {
 		return
 	}
 	r.TenantID = profile.TenantID
-	if strings.Contains(index, "xlsx") {
-		open, _ := file.Open()
-		defer open.Close()
-		all, err := io.ReadAll(open)
-		if err != nil {
-			//todo 需要记录操作急打印日志
-			resp.Format(nil, err).Context(c)
-			return
-		}
-		importFile, err := u.user.ImportFile(ginheader.MutateContext(c), all, profile, r)
-		if err != nil {
-			//todo 需要记录操作急打印日志
-			resp.Format(nil, err).Context(c)
-			return
-		}
+	name, all, err := readImportFile(c)
+	if err != nil {
 		//todo 需要记录操作急打印日志
-		u.search.PushUser(ginheader.MutateContext(c), nil, importFile.Users...)
-		resp.Format(importFile, nil).Context(c)
+		resp.Format(nil, err).Context(c)
+		return
+	}
+	r.FileName = name
+	if r.Preview {
+		res, err := u.user.PreviewImport(ginheader.MutateContext(c), all, r)
+		resp.Format(res, err).Context(c)
+		return
+	}
+	importFile, err := u.user.ImportFile(ginheader.MutateContext(c), all, profile, r)
+	if err != nil {
+		//todo 需要记录操作急打印日志
+		resp.Format(nil, err).Context(c)
 		return
 	}
 	//todo 需要记录操作急打印日志
-	resp.Format(nil, error2.New(code.InvalidFile)).Context(c)
+	u.search.PushUser(ginheader.MutateContext(c), nil, importFile.Users...)
+	if len(importFile.CreatedDepartments) > 0 {
+		u.search.PushDep(ginheader.MutateContext(c), nil)
+	}
+	resp.Format(importFile, nil).Context(c)
 	return
 }
 
+// readImportFile name and content of the uploaded file, its format is told by the import
+func readImportFile(c *gin.Context) (string, []byte, error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return "", nil, error2.New(code.InvalidFile)
+	}
+	open, err := file.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer open.Close()
+	all, err := io.ReadAll(open)
+	if err != nil {
+		return "", nil, err
+	}
+	return file.Filename, all, nil
+}
+
+// SubmitImportJob import the uploaded file in the background
+func (u *UserAPI) SubmitImportJob(c *gin.Context) {
+	profile := header2.GetProfile(c)
+	r := new(user.ImportFileRequest)
+	err := c.ShouldBind(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	r.TenantID = profile.TenantID
+	name, all, err := readImportFile(c)
+	if err != nil {
+		resp.Format(nil, err).Context(c)
+		return
+	}
+	r.FileName = name
+	res, err := u.importJob.Submit(ginheader.MutateContext(c), all, profile, r)
+	resp.Format(res, err).Context(c)
+}
+
+// ImportJobInfo progress of an import job
+func (u *UserAPI) ImportJobInfo(c *gin.Context) {
+	r := new(user.ImportJobInfoRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := u.importJob.Info(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// CancelImportJob cancel an import job
+func (u *UserAPI) CancelImportJob(c *gin.Context) {
+	r := new(user.CancelImportJobRequest)
+	err := c.ShouldBindJSON(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := u.importJob.Cancel(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// ImportJobFailFile failed rows of an import job
+func (u *UserAPI) ImportJobFailFile(c *gin.Context) {
+	r := new(user.ImportJobFailFileRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := u.importJob.FailFile(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
+// ListImportJobs list import jobs
+func (u *UserAPI) ListImportJobs(c *gin.Context) {
+	r := new(user.ListImportJobRequest)
+	err := c.ShouldBindQuery(r)
+	if err != nil {
+		resp.Format(nil, error2.New(code.InvalidParams)).Context(c)
+		return
+	}
+	res, err := u.importJob.PageList(ginheader.MutateContext(c), r)
+	resp.Format(res, err).Context(c)
+}
+
 // AdminUserInfo admin get user info
 func (u *UserAPI) AdminUserInfo(c *gin.Context) {
 	r := new(user.SearchOneUserRequest)
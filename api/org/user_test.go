@@ -0,0 +1,39 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadWriter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	w := &downloadWriter{c: c, contentType: "text/csv; charset=utf-8", fileName: "users.csv"}
+
+	// nothing written, an error would still be answered as json
+	assert.Empty(t, c.Writer.Header().Get("Content-Disposition"))
+
+	_, err := w.Write([]byte("a,b\n"))
+	assert.Nil(t, err)
+	_, err = w.Write([]byte("c,d\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=users.csv", rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "a,b\nc,d\n", rec.Body.String())
+}
@@ -7,6 +7,8 @@ require (
 	github.com/alicebob/miniredis/v2 v2.14.1
 	github.com/elliotchance/redismock/v8 v8.11.0
 	github.com/gin-gonic/gin v1.7.7
+	github.com/go-asn1-ber/asn1-ber v1.5.1
+	github.com/go-ldap/ldap/v3 v3.4.1
 	github.com/go-logr/logr v1.2.2
 	github.com/go-logr/zapr v1.2.2
 	github.com/go-playground/validator/v10 v10.9.0
@@ -18,6 +20,8 @@ require (
 	github.com/stretchr/testify v1.7.0
 	github.com/tealeg/xlsx v1.0.5
 	go.uber.org/zap v1.19.0
+	golang.org/x/crypto v0.0.0-20210920023735-84f357641f63
+	golang.org/x/text v0.3.7
 	gopkg.in/yaml.v2 v2.4.0
 	gorm.io/driver/mysql v1.2.2
 	gorm.io/gorm v1.22.4
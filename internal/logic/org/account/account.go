@@ -17,7 +17,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
@@ -25,6 +24,7 @@ import (
 	error2 "github.com/quanxiang-cloud/cabin/error"
 	"github.com/quanxiang-cloud/cabin/logger"
 	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
 	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
 	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
 	"github.com/quanxiang-cloud/organizations/internal/models/org"
@@ -34,6 +34,7 @@ import (
 	"github.com/quanxiang-cloud/organizations/pkg/encode2"
 	"github.com/quanxiang-cloud/organizations/pkg/ladp"
 	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/page"
 	"github.com/quanxiang-cloud/organizations/pkg/random2"
 	"github.com/quanxiang-cloud/organizations/pkg/systems"
 	"github.com/quanxiang-cloud/organizations/pkg/verification"
@@ -47,6 +48,19 @@ type Account interface {
 	ForgetUpdatePassword(c context.Context, account *ForgetResetRequest) (*ForgetResetResponse, error)
 	AdminUpdatePassword(c context.Context, accounts *AdminUpdatePasswordRequest) (*AdminUpdatePasswordResponse, error)
 	GetCode(ctx context.Context, r *CodeRequest) (*CodeResponse, error)
+	EnrollFactor(c context.Context, r *EnrollFactorRequest) (*EnrollFactorResponse, error)
+	ConfirmFactor(c context.Context, r *ConfirmFactorRequest) (*ConfirmFactorResponse, error)
+	VerifyFactor(c context.Context, r *VerifyFactorRequest) (*LoginAccountResponse, error)
+	ResetFactor(c context.Context, r *ResetFactorRequest) (*ResetFactorResponse, error)
+	ListLocks(c context.Context, r *ListLocksRequest) (*ListLocksResponse, error)
+	Unlock(c context.Context, r *UnlockRequest) (*UnlockResponse, error)
+	ListLoginEvents(c context.Context, r *ListLoginEventsRequest) (*page.Page, error)
+	SendLink(c context.Context, r *SendLinkRequest) (*SendLinkResponse, error)
+	LinkLogin(c context.Context, r *LinkLoginRequest) (*LoginAccountResponse, error)
+	InvitationInfo(c context.Context, r *InvitationInfoRequest) (*InvitationInfoResponse, error)
+	AcceptInvitation(c context.Context, r *AcceptInvitationRequest) (*AcceptInvitationResponse, error)
+	ChangeCode(c context.Context, r *ChangeCodeRequest) (*CodeResponse, error)
+	ChangeAccount(c context.Context, r *ChangeAccountRequest) (*ChangeAccountResponse, error)
 }
 
 const (
@@ -57,10 +71,20 @@ const (
 	passwordLength      = 8
 	codeLength          = 6
 	redisAccountPWDErr  = "organizations:accountPWDErr:"
+	dayMillis           = 24 * 60 * 60 * 1000
+	debugModel          = "debug"
+	maxCreatePassword   = 3
 	resetPasswordStatus = -1
 	codeKey             = "code"
 )
 
+// loginTypes bit of each login type in SecurityInfo.LoginType
+var loginTypes = map[string]int64{
+	loginTypePwd:  systems.LoginTypePwd,
+	loginTypeCode: systems.LoginTypeCode,
+	loginTypeLdap: systems.LoginTypeLdap,
+}
+
 // account
 type account struct {
 	DB          *gorm.DB
@@ -72,6 +96,13 @@ type account struct {
 	depRepo     org.DepartmentRepo
 	conf        configs.Config
 	userDepRepo org.UserDepartmentRelationRepo
+	hasher      encode2.Hasher
+	factorRepo  org.AccountFactorRepo
+	historyRepo org.PasswordHistoryRepo
+	unlockRepo  org.UnlockRecordRepo
+	eventRepo   org.LoginEventRepo
+	verifyCode  verification.Code
+	inviteRepo  org.InvitationRepo
 }
 
 // NewAccount new
@@ -82,10 +113,17 @@ func NewAccount(conf configs.Config, db *gorm.DB, redisClient redis.UniversalCli
 		message:     message.NewMessage(conf.InternalNet),
 		redisClient: redisClient,
 		user:        mysql2.NewUserRepo(),
-		ldapClient:  ldap.NewLdap(conf.InternalNet),
+		ldapClient:  ldap.NewBindLdap(conf.Ldap, ldap.NewLdap(conf.InternalNet)),
 		depRepo:     mysql2.NewDepartmentRepo(),
 		conf:        conf,
 		userDepRepo: mysql2.NewUserDepartmentRelationRepo(),
+		hasher:      encode2.NewHasher(conf.PasswordHash),
+		factorRepo:  mysql2.NewAccountFactorRepo(),
+		historyRepo: mysql2.NewPasswordHistoryRepo(),
+		unlockRepo:  mysql2.NewUnlockRecordRepo(),
+		eventRepo:   mysql2.NewLoginEventRepo(),
+		verifyCode:  verification.NewCode(conf.VerificationCode, redisClient),
+		inviteRepo:  mysql2.NewInvitationRepo(),
 	}
 }
 
@@ -111,19 +149,31 @@ func (u *account) UpdatePassword(c context.Context, r *UpdatePasswordRequest) (*
 		return nil, error2.New(code.ResetAccountPasswordErr)
 	}
 
-	if accounts[0].Password == encode2.MD5Encode(r.OldPassword) {
+	if ok, _ := u.hasher.Verify(r.OldPassword, accounts[0].Password); ok {
 		//todo get info from system server
 		info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
 		f := random2.CheckPassword(r.NewPassword, info.PwdMinLen, info.PwdType)
 		if !f {
 			return nil, error2.New(code.MismatchPasswordRule)
 		}
+		depth := u.historyDepth(info)
+		if u.usedPassword(r.UserID, r.NewPassword, depth) {
+			return nil, error2.New(code.ErrPasswordUsed, depth)
+		}
+		password, err := u.hasher.Hash(r.NewPassword)
+		if err != nil {
+			return nil, err
+		}
 		tx := u.DB.Begin()
 		u2 := org.Account{
-			UserID:   accounts[0].UserID,
-			Password: encode2.MD5Encode(r.NewPassword),
+			UserID:            accounts[0].UserID,
+			Password:          password,
+			PasswordChangedAt: time2.NowUnix(),
+		}
+		err = u.accountRepo.UpdatePasswordByUserID(tx, &u2)
+		if err == nil {
+			err = u.recordPassword(tx, accounts[0].UserID, password, depth)
 		}
-		err := u.accountRepo.UpdatePasswordByUserID(tx, &u2)
 		if err != nil {
 			tx.Rollback()
 			return nil, err
@@ -161,6 +211,8 @@ type ResetPasswordResponse struct {
 
 // AdminUpdatePassword admin reset password
 func (u *account) AdminUpdatePassword(c context.Context, r *AdminUpdatePasswordRequest) (*AdminUpdatePasswordResponse, error) {
+	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
+	depth := u.historyDepth(info)
 	tx := u.DB.Begin()
 	m := make(map[string]string)
 
@@ -171,11 +223,28 @@ func (u *account) AdminUpdatePassword(c context.Context, r *AdminUpdatePasswordR
 	}
 	for k := range r.UserIDs {
 		newPWD := user.CreatePassword(c, u.conf, u.redisClient)
+		// debug mode always creates the same password
+		for i := 0; u.conf.Model != debugModel && u.usedPassword(r.UserIDs[k], newPWD, depth); i++ {
+			if i == maxCreatePassword {
+				tx.Rollback()
+				return nil, error2.New(code.ErrPasswordUsed, depth)
+			}
+			newPWD = user.CreatePassword(c, u.conf, u.redisClient)
+		}
+		password, err := u.hasher.Hash(newPWD)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 		u2 := org.Account{
-			UserID:   r.UserIDs[k],
-			Password: encode2.MD5Encode(newPWD),
+			UserID:            r.UserIDs[k],
+			Password:          password,
+			PasswordChangedAt: time2.NowUnix(),
+		}
+		err = u.accountRepo.UpdatePasswordByUserID(tx, &u2)
+		if err == nil {
+			err = u.recordPassword(tx, r.UserIDs[k], password, depth)
 		}
-		err := u.accountRepo.UpdatePasswordByUserID(tx, &u2)
 		if err != nil {
 			tx.Rollback()
 			return nil, err
@@ -185,7 +254,11 @@ func (u *account) AdminUpdatePassword(c context.Context, r *AdminUpdatePasswordR
 
 		res := u.user.Get(c, u.DB, r.UserIDs[k])
 		if r.SendMessage != nil && r.SendMessage[k].SendChannel != user.NO {
-			user.SendAccountAndPWDOrCode(c, u.message, send[res.ID].SendTo, "", u.conf.MessageTemplate.ResetPWD, newPWD, r.SendMessage[k].SendChannel)
+			phone := res.Phone
+			if verification.CheckPhone(send[res.ID].SendTo) {
+				phone = send[res.ID].SendTo
+			}
+			user.SendAccountAndPWDOrCode(c, u.message, send[res.ID].SendTo, "", phone, u.conf.MessageTemplate.ResetPWD, newPWD, r.SendMessage[k].SendChannel)
 		}
 		response := ResetPasswordResponse{}
 		response.UserID = res.ID
@@ -211,6 +284,9 @@ type LoginAccountRequest struct {
 	// login type: pwd,ldapClient,code
 	Types  string `json:"types" binding:"required"` //登录模式
 	Header http.Header
+	// client ip
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // LoginAccountResponse login response
@@ -220,16 +296,42 @@ type LoginAccountResponse struct {
 	UseStatus int    `json:"useStatus"`
 	Name      string `json:"-"`
 	UserName  string `json:"-"`
+	// second factor required by M2FA: verify or enroll, userID is empty until it is done
+	Factor       string `json:"factor,omitempty"`
+	FactorTicket string `json:"factorTicket,omitempty"`
+	// password is older than PwdExpireDays, it must be changed before going on
+	PasswordExpired bool `json:"passwordExpired,omitempty"`
 }
 
-// CheckPassword check password
+// CheckPassword check password, every attempt is kept in login audit
 func (u *account) CheckPassword(c context.Context, r *LoginAccountRequest) (*LoginAccountResponse, error) {
+	event := &org.LoginEvent{
+		Account:   r.UserName,
+		Types:     r.Types,
+		IP:        r.IP,
+		UserAgent: r.UserAgent,
+	}
+	res, err := u.checkPassword(c, r, event)
+	u.audit(c, event, err)
+	return res, err
+}
+
+func (u *account) checkPassword(c context.Context, r *LoginAccountRequest, event *org.LoginEvent) (*LoginAccountResponse, error) {
 	res := &LoginAccountResponse{}
+	//TODO get info from system server
+	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
+	unknown := tenantContext(c, "")
+	if u.lockedIP(unknown, r.IP, info) {
+		return nil, error2.New(code.LockedIP, info.IPCountWait)
+	}
 	acc := u.accountRepo.SelectByAccount(u.DB, r.UserName)
 	if acc == nil {
+		u.ipFailed(unknown, r.IP, info)
 		return nil, error2.New(code.NotExistAccountErr)
 	}
 	oldUser := u.user.Get(c, u.DB, acc.UserID)
+	event.UserID = oldUser.ID
+	event.TenantID = oldUser.TenantID
 	res.UserName = oldUser.Name
 	res.UserID = oldUser.ID
 	res.UseStatus = oldUser.UseStatus
@@ -239,68 +341,102 @@ func (u *account) CheckPassword(c context.Context, r *LoginAccountRequest) (*Log
 	res.UserID = oldUser.ID
 	res.Name = oldUser.Email
 	res.UseStatus = oldUser.UseStatus
+	// the policy of the user's tenant applies, not the one of the request, and its admins see the failures
+	c = tenantContext(c, oldUser.TenantID)
+	info = systems.GetSecurityInfo(c, u.conf, u.redisClient)
+	if u.lockedIP(c, r.IP, info) {
+		return nil, error2.New(code.LockedIP, info.IPCountWait)
+	}
 
-	//TODO get info from system server
-	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
-
-	val, err1 := u.redisClient.Get(c, redisAccountPWDErr+acc.UserID).Result()
+	errNum, err1 := u.loginErrNum(c, acc.UserID)
 	if err1 != nil {
-		if err1 != redis.Nil {
-			logger.Logger.Error(err1)
-			return res, err1
-		}
-	}
-	var errNum = 0
-	if val != "" {
-		atoi, err := strconv.Atoi(val)
-		if err != nil {
-			return res, err
-		}
-		errNum = atoi
+		return res, err1
 	}
 
 	if errNum >= int(info.PwdCount) {
 		return nil, error2.New(code.LockedAccount)
 	}
 
-	if u.conf.Ldap.Open {
-		if r.Types == loginTypePwd {
-			split := strings.Split(r.UserName, "@")
-			if split[1] == u.conf.Ldap.Regex {
-				r.Types = loginTypeLdap
-			}
-		}
+	if r.Types == loginTypePwd && ldap.UseLdap(u.conf.Ldap, oldUser.TenantID, r.UserName) {
+		r.Types = loginTypeLdap
+	}
+	event.Types = r.Types
+	if loginType, ok := loginTypes[r.Types]; !ok || !info.AllowLogin(loginType) {
+		return nil, error2.New(code.ForbiddenLoginType)
 	}
 	var flag = false
 	var err error = nil
 	switch r.Types {
 	case loginTypePwd:
-		flag, err = u.pwd(c, r, acc.Password)
+		flag, err = u.pwd(c, r, acc)
 	case loginTypeLdap:
-		c = context.WithValue(c, user.TenantID, oldUser.TenantID)
 		flag, err = u.ldap(c, r.Header, r)
 	case loginTypeCode:
 		flag, err = u.code(c, r)
 	}
 	if err != nil {
+		u.ipFailed(c, r.IP, info)
 		return nil, err
 	}
 	if !flag {
-		err = error2.New(code.AccountPasswordCountErr, int(info.PwdCount)-(errNum+1))
-		u.redisClient.SetEX(c, redisAccountPWDErr+acc.UserID, errNum+1, time.Duration(info.PwdCountWait)*time.Minute)
-		return nil, err
+		return nil, u.loginFailed(c, r.IP, info, acc.UserID, errNum)
 	}
 	u.redisClient.Del(c, redisAccountPWDErr+acc.UserID)
+	if r.Types == loginTypePwd {
+		expireAt := PasswordExpireAt(acc, info.PwdExpireDays)
+		res.PasswordExpired = expireAt != 0 && expireAt <= time2.NowUnix()
+	}
+	if info.M2FA {
+		return u.factorTicket(c, res)
+	}
 	return res, nil
 
 }
 
-// pwd password
-func (u *account) pwd(ctx context.Context, r *LoginAccountRequest, comparePassword string) (bool, error) {
-	if encode2.MD5Encode(r.Password) == comparePassword {
-		return true, nil
+// loginErrNum failed login count of user
+func (u *account) loginErrNum(c context.Context, userID string) (int, error) {
+	val, err := u.redisClient.Get(c, redisAccountPWDErr+userID).Result()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Logger.Error(err)
+			return 0, err
+		}
+	}
+	if val == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(val)
+}
+
+// pwd password, upgrade the stored hash when it uses an outdated algorithm or cost
+func (u *account) pwd(ctx context.Context, r *LoginAccountRequest, acc *org.Account) (bool, error) {
+	ok, rehash := u.hasher.Verify(r.Password, acc.Password)
+	if !ok {
+		return false, nil
+	}
+	if rehash {
+		password, err := u.hasher.Hash(r.Password)
+		if err != nil {
+			logger.Logger.Error(err)
+			return true, nil
+		}
+		err = u.accountRepo.UpdatePasswordByUserID(u.DB, &org.Account{
+			UserID:   acc.UserID,
+			Password: password,
+		})
+		if err != nil {
+			logger.Logger.Error(err)
+		}
+	}
+	return true, nil
+}
+
+// PasswordExpireAt time the password expires, 0 means never
+func PasswordExpireAt(acc *org.Account, expireDays int64) int64 {
+	if expireDays <= 0 {
+		return 0
 	}
-	return false, nil
+	return acc.PasswordChangedAt + expireDays*dayMillis
 }
 
 // ldap ldap
@@ -320,14 +456,11 @@ func (u *account) ldap(ctx context.Context, header http.Header, r *LoginAccountR
 
 // code email or phone code
 func (u *account) code(ctx context.Context, account *LoginAccountRequest) (bool, error) {
-	cacheCode := u.redisClient.Get(ctx, u.conf.VerificationCode.LoginCode+":"+account.UserName).Val()
-	if cacheCode == "" {
-		return false, error2.New(code.ExpireVerificationCode)
-	}
-	if account.Password != cacheCode || account.Password == "" {
-		return false, error2.New(code.InvalidVerificationCode)
+	err := u.verifyCode.Verify(ctx, u.conf.VerificationCode.LoginCode, account.UserName, account.Password)
+	if err != nil {
+		return false, err
 	}
-	u.redisClient.Del(ctx, u.conf.VerificationCode.LoginCode+":"+account.UserName)
+	u.verifyCode.Del(ctx, u.conf.VerificationCode.LoginCode, account.UserName)
 	return true, nil
 }
 
@@ -335,11 +468,14 @@ func (u *account) code(ctx context.Context, account *LoginAccountRequest) (bool,
 type CodeRequest struct {
 	UserName string `json:"userName" form:"userName" binding:"required,max=60,emailOrPhone"`
 	Model    string
+	// client ip
+	IP string `json:"-"`
 }
 
 // CodeResponse code response
 type CodeResponse struct {
-	Code string `json:"code"`
+	// only echoed when verificationCode.echo is on, the code is delivered by message otherwise
+	Code string `json:"code,omitempty"`
 }
 
 // GetCode get code
@@ -349,57 +485,47 @@ func (u *account) GetCode(ctx context.Context, r *CodeRequest) (*CodeResponse, e
 		if acc == nil {
 			return nil, error2.New(code.InvalidAccount)
 		}
-		user := u.user.Get(ctx, u.DB, acc.UserID)
-		ctx = context.WithValue(ctx, user.TenantID, user.TenantID)
+		oldUser := u.user.Get(ctx, u.DB, acc.UserID)
+		ctx = context.WithValue(ctx, user.TenantID, oldUser.TenantID)
 	}
 
-	rd := strings.ToLower(random2.RandomString(codeLength, 6))
+	if len(r.UserName) > accountLength {
+		return nil, error2.NewErrorWithString(code.ErrTooLong, "接收信息账户超过限制长度")
+	}
+	if r.Model == u.conf.VerificationCode.LoginCode {
+		info := systems.GetSecurityInfo(ctx, u.conf, u.redisClient)
+		if !info.AllowLogin(systems.LoginTypeCode) {
+			return nil, error2.New(code.ForbiddenLoginType)
+		}
+	}
 	var templateAlias = ""
 	switch r.Model {
 	case u.conf.VerificationCode.LoginCode:
-		b := u.redisClient.Exists(ctx, u.conf.VerificationCode.LoginCode+":"+r.UserName).Val()
-		if b == 1 {
-			return nil, error2.New(code.ValidVerificationCode)
-		}
-		u.redisClient.SetEX(ctx, u.conf.VerificationCode.LoginCode+":"+r.UserName, rd, u.conf.VerificationCode.ExpireTime*time.Second)
 		templateAlias = u.conf.MessageTemplate.LoginCode
 	case u.conf.VerificationCode.ResetCode:
-		b := u.redisClient.Exists(ctx, u.conf.VerificationCode.ResetCode+":"+r.UserName).Val()
-		if b == 1 {
-			return nil, error2.New(code.ValidVerificationCode)
-		}
-		u.redisClient.SetEX(ctx, u.conf.VerificationCode.ResetCode+":"+r.UserName, rd, u.conf.VerificationCode.ExpireTime*time.Second)
 		templateAlias = u.conf.MessageTemplate.ResetCode
-
 	case u.conf.VerificationCode.ForgetCode:
-		b := u.redisClient.Exists(ctx, u.conf.VerificationCode.ForgetCode+":"+r.UserName).Val()
-		if b == 1 {
-			return nil, error2.New(code.ValidVerificationCode)
-		}
-		u.redisClient.SetEX(ctx, u.conf.VerificationCode.ForgetCode+":"+r.UserName, rd, u.conf.VerificationCode.ExpireTime*time.Second)
 		templateAlias = u.conf.MessageTemplate.ForgetCode
 	case u.conf.VerificationCode.RegisterCode:
-		b := u.redisClient.Exists(ctx, u.conf.VerificationCode.RegisterCode+":"+r.UserName).Val()
-		if b == 1 {
-			return nil, error2.New(code.ValidVerificationCode)
-		}
-		u.redisClient.SetEX(ctx, u.conf.VerificationCode.RegisterCode+":"+r.UserName, rd, u.conf.VerificationCode.ExpireTime*time.Second)
 		templateAlias = u.conf.MessageTemplate.RegisterCode
 	}
-
-	if len(r.UserName) > accountLength {
-		return nil, error2.NewErrorWithString(code.ErrTooLong, "接收信息账户超过限制长度")
+	rd := strings.ToLower(random2.RandomString(codeLength, 6))
+	err := u.verifyCode.Create(ctx, r.Model, r.UserName, r.IP, rd)
+	if err != nil {
+		return nil, err
 	}
 
 	if verification.CheckEmail(r.UserName) {
-		user.SendAccountAndPWDOrCode(ctx, u.message, r.UserName, "", templateAlias, rd, 1)
+		user.SendAccountAndPWDOrCode(ctx, u.message, r.UserName, "", "", templateAlias, rd, user.SENDEMAIL)
 	} else if verification.CheckPhone(r.UserName) {
-		user.SendAccountAndPWDOrCode(ctx, u.message, r.UserName, "", templateAlias, rd, 2)
+		user.SendAccountAndPWDOrCode(ctx, u.message, "", "", r.UserName, templateAlias, rd, user.SENDPHONE)
 	} else {
 		return nil, error2.New(code.ErrInvalidRuleAccount)
 	}
 	res := &CodeResponse{}
-	res.Code = rd
+	if u.conf.VerificationCode.Echo && (u.conf.POC || u.conf.Model == debugModel) {
+		res.Code = rd
+	}
 	return res, nil
 
 }
@@ -429,12 +555,9 @@ func (u *account) ForgetUpdatePassword(c context.Context, r *ForgetResetRequest)
 		return nil, error2.New(code.InvalidAccount)
 	}
 
-	val := u.redisClient.Get(c, u.conf.VerificationCode.ForgetCode+":"+r.UserName).Val()
-	if val == "" {
-		return nil, error2.New(code.ExpireVerificationCode)
-	}
-	if val != r.Code {
-		return nil, error2.New(code.InvalidVerificationCode)
+	err := u.verifyCode.Verify(c, u.conf.VerificationCode.ForgetCode, r.UserName, r.Code)
+	if err != nil {
+		return nil, err
 	}
 	//todo get info from system server
 	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
@@ -443,12 +566,24 @@ func (u *account) ForgetUpdatePassword(c context.Context, r *ForgetResetRequest)
 	if !f {
 		return nil, error2.New(code.MismatchPasswordRule)
 	}
+	depth := u.historyDepth(info)
+	if u.usedPassword(oldUser.ID, r.NewPassword, depth) {
+		return nil, error2.New(code.ErrPasswordUsed, depth)
+	}
+	password, err := u.hasher.Hash(r.NewPassword)
+	if err != nil {
+		return nil, err
+	}
 	tx := u.DB.Begin()
 	u2 := org.Account{
-		UserID:   oldUser.ID,
-		Password: encode2.MD5Encode(r.NewPassword),
+		UserID:            oldUser.ID,
+		Password:          password,
+		PasswordChangedAt: time2.NowUnix(),
+	}
+	err = u.accountRepo.UpdatePasswordByUserID(tx, &u2)
+	if err == nil {
+		err = u.recordPassword(tx, oldUser.ID, password, depth)
 	}
-	err := u.accountRepo.UpdatePasswordByUserID(tx, &u2)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -457,7 +592,7 @@ func (u *account) ForgetUpdatePassword(c context.Context, r *ForgetResetRequest)
 	u3 := ForgetResetResponse{
 		UserID: oldUser.ID,
 	}
-	u.redisClient.Del(c, u.conf.VerificationCode.ForgetCode+":"+r.UserName)
+	u.verifyCode.Del(c, u.conf.VerificationCode.ForgetCode, r.UserName)
 	u.redisClient.Del(c, redisAccountPWDErr+acc.UserID)
 	return &u3, nil
 
@@ -504,12 +639,24 @@ func (u *account) FirstUpdatePassword(c context.Context, r *FirstSetPasswordRequ
 	if !f {
 		return nil, error2.New(code.MismatchPasswordRule)
 	}
+	depth := u.historyDepth(info)
+	if u.usedPassword(r.UserID, r.NewPassword, depth) {
+		return nil, error2.New(code.ErrPasswordUsed, depth)
+	}
+	password, err := u.hasher.Hash(r.NewPassword)
+	if err != nil {
+		return nil, err
+	}
 	tx := u.DB.Begin()
 	u2 := &org.Account{
-		UserID:   r.UserID,
-		Password: encode2.MD5Encode(r.NewPassword),
+		UserID:            r.UserID,
+		Password:          password,
+		PasswordChangedAt: time2.NowUnix(),
+	}
+	err = u.accountRepo.UpdatePasswordByUserID(tx, u2)
+	if err == nil {
+		err = u.recordPassword(tx, r.UserID, password, depth)
 	}
-	err := u.accountRepo.UpdatePasswordByUserID(tx, u2)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -15,21 +15,35 @@ limitations under the License.
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/elliotchance/redismock/v8"
 	"github.com/go-redis/redis/v8"
 	"github.com/golang/mock/gomock"
+	error2 "github.com/quanxiang-cloud/cabin/error"
 	"github.com/quanxiang-cloud/cabin/logger"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
 	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
 	"github.com/quanxiang-cloud/organizations/mock"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
 	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/encode2"
 	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/ladp"
+	"github.com/quanxiang-cloud/organizations/pkg/ladp/ldaptest"
 	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+	"github.com/quanxiang-cloud/organizations/pkg/totp"
+	"github.com/quanxiang-cloud/organizations/pkg/verification"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
@@ -86,12 +100,20 @@ func (suite *AccountSuite) TestCheckPassword() {
 
 	accountRepo := mock.NewMockAccountRepo(ctl)
 	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
 
 	gomock.InOrder(
 
 		accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()),
 		userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()),
 	)
+	// legacy md5 password is upgraded on login
+	accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any())
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(db *gorm.DB, event *org.LoginEvent) error {
+		assert.Equal(suite.T(), loginSuccess, event.Result)
+		assert.Equal(suite.T(), "test1@test.com", event.Account)
+		return nil
+	}).Times(2)
 
 	rq := &LoginAccountRequest{
 		UserName: "test1@test.com",
@@ -99,11 +121,14 @@ func (suite *AccountSuite) TestCheckPassword() {
 		Types:    "pwd",
 	}
 	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		conf:        suite.conf,
 		accountRepo: accountRepo,
 		user:        userRepo,
 		redisClient: suite.redisClient,
+		verifyCode:  verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
+		eventRepo:   eventRepo,
 	}
 	res, err := suite.account.CheckPassword(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
@@ -113,11 +138,14 @@ func (suite *AccountSuite) TestCheckPassword() {
 	rq.Types = "code"
 
 	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		conf:        suite.conf,
 		accountRepo: accountRepo,
 		user:        userRepo,
 		redisClient: suite.redisClient,
+		verifyCode:  verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
+		eventRepo:   eventRepo,
 	}
 	gomock.InOrder(
 
@@ -136,11 +164,15 @@ func (suite *AccountSuite) TestUpdatePassword() {
 
 	accountRepo := mock.NewMockAccountRepo(ctl)
 	userRepo := mock.NewMockUserRepo(ctl)
+	historyRepo := mock.NewMockPasswordHistoryRepo(ctl)
 
 	gomock.InOrder(
 
-		accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()),
+		accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()).Times(2),
+		historyRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any(), gomock.Any()),
 		accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Insert(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Prune(gomock.Any(), gomock.Any(), gomock.Any()),
 	)
 
 	rq := &UpdatePasswordRequest{
@@ -149,11 +181,13 @@ func (suite *AccountSuite) TestUpdatePassword() {
 		NewPassword: "654321Aa..",
 	}
 	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		conf:        suite.conf,
 		accountRepo: accountRepo,
 		user:        userRepo,
 		redisClient: suite.redisClient,
+		historyRepo: historyRepo,
 	}
 	res, err := suite.account.UpdatePassword(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
@@ -166,12 +200,17 @@ func (suite *AccountSuite) TestFirstUpdatePassword() {
 
 	accountRepo := mock.NewMockAccountRepo(ctl)
 	userRepo := mock.NewMockUserRepo(ctl)
+	historyRepo := mock.NewMockPasswordHistoryRepo(ctl)
 
 	gomock.InOrder(
 
 		accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()),
 		userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()),
+		accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any(), gomock.Any()),
 		accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Insert(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Prune(gomock.Any(), gomock.Any(), gomock.Any()),
 		userRepo.EXPECT().UpdateByID(gomock.Any(), gomock.Any(), gomock.Any()),
 	)
 
@@ -181,11 +220,13 @@ func (suite *AccountSuite) TestFirstUpdatePassword() {
 		NewPassword: "654321Aa..",
 	}
 	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		conf:        suite.conf,
 		accountRepo: accountRepo,
 		user:        userRepo,
 		redisClient: suite.redisClient,
+		historyRepo: historyRepo,
 	}
 	res, err := suite.account.FirstUpdatePassword(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
@@ -198,12 +239,17 @@ func (suite *AccountSuite) TestForgetUpdatePassword() {
 
 	accountRepo := mock.NewMockAccountRepo(ctl)
 	userRepo := mock.NewMockUserRepo(ctl)
+	historyRepo := mock.NewMockPasswordHistoryRepo(ctl)
 
 	gomock.InOrder(
 
 		accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()),
 		userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()),
+		accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any(), gomock.Any()),
 		accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Insert(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Prune(gomock.Any(), gomock.Any(), gomock.Any()),
 	)
 
 	rq := &ForgetResetRequest{
@@ -212,11 +258,14 @@ func (suite *AccountSuite) TestForgetUpdatePassword() {
 		NewPassword: "654321Aa..",
 	}
 	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		conf:        suite.conf,
 		accountRepo: accountRepo,
 		user:        userRepo,
 		redisClient: suite.redisClient,
+		verifyCode:  verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
+		historyRepo: historyRepo,
 	}
 	suite.redisClient.SetEX(suite.Ctx, suite.conf.VerificationCode.ForgetCode+":"+rq.UserName, "123456", suite.conf.VerificationCode.ExpireTime*time.Second)
 	res, err := suite.account.ForgetUpdatePassword(suite.Ctx, rq)
@@ -230,10 +279,13 @@ func (suite *AccountSuite) TestAdminUpdatePassword() {
 
 	accountRepo := mock.NewMockAccountRepo(ctl)
 	userRepo := mock.NewMockUserRepo(ctl)
+	historyRepo := mock.NewMockPasswordHistoryRepo(ctl)
 
 	gomock.InOrder(
 
 		accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any()).AnyTimes(),
+		historyRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes(),
+		historyRepo.EXPECT().Prune(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes(),
 		userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes(),
 	)
 
@@ -247,11 +299,13 @@ func (suite *AccountSuite) TestAdminUpdatePassword() {
 		},
 	}
 	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		conf:        suite.conf,
 		accountRepo: accountRepo,
 		user:        userRepo,
 		redisClient: suite.redisClient,
+		historyRepo: historyRepo,
 	}
 	res, err := suite.account.AdminUpdatePassword(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
@@ -271,13 +325,19 @@ func (suite *AccountSuite) TestGetCode() {
 		userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes(),
 	)
 
+	// several kinds of code are sent to the same recipient within a minute
+	codeConf := suite.conf.VerificationCode
+	codeConf.MinuteLimit = 0
+	sink := message.NewSink()
 	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		conf:        suite.conf,
 		accountRepo: accountRepo,
 		user:        userRepo,
 		redisClient: suite.redisClient,
-		message:     message.NewMessage(suite.conf.InternalNet),
+		verifyCode:  verification.NewCode(codeConf, suite.redisClient),
+		message:     sink,
 	}
 
 	rq := &CodeRequest{
@@ -288,6 +348,12 @@ func (suite *AccountSuite) TestGetCode() {
 	res, err := suite.account.GetCode(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
 	assert.NotNil(suite.T(), res)
+	assert.Empty(suite.T(), res.Code)
+	cacheCode := suite.redisClient.Get(suite.Ctx, rq.Model+":"+rq.UserName).Val()
+	assert.Eventually(suite.T(), func() bool {
+		emails := sink.Emails(rq.UserName)
+		return len(emails) == 1 && emails[0].Content.KeyAndValue["code"] == cacheCode
+	}, time.Second, 10*time.Millisecond)
 
 	rq.Model = "code:reset"
 	gomock.InOrder(
@@ -298,6 +364,7 @@ func (suite *AccountSuite) TestGetCode() {
 	res, err = suite.account.GetCode(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
 	assert.NotNil(suite.T(), res)
+	assert.Empty(suite.T(), res.Code)
 
 	rq.Model = "code:forget"
 	gomock.InOrder(
@@ -308,6 +375,7 @@ func (suite *AccountSuite) TestGetCode() {
 	res, err = suite.account.GetCode(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
 	assert.NotNil(suite.T(), res)
+	assert.Empty(suite.T(), res.Code)
 
 	rq.UserName = "testnull@test.com"
 	rq.Model = "code:register"
@@ -319,4 +387,873 @@ func (suite *AccountSuite) TestGetCode() {
 	res, err = suite.account.GetCode(suite.Ctx, rq)
 	assert.Nil(suite.T(), err)
 	assert.NotNil(suite.T(), res)
+	assert.Empty(suite.T(), res.Code)
+
+	rq.UserName = "13800000000"
+	res, err = suite.account.GetCode(suite.Ctx, rq)
+	assert.Nil(suite.T(), err)
+	cacheCode = suite.redisClient.Get(suite.Ctx, rq.Model+":"+rq.UserName).Val()
+	assert.Eventually(suite.T(), func() bool {
+		phones := sink.Phones(rq.UserName)
+		return len(phones) == 1 && phones[0].Content.KeyAndValue["code"] == cacheCode &&
+			phones[0].Content.TemplateID == suite.conf.MessageTemplate.RegisterCode
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (suite *AccountSuite) TestGetCodeEcho() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	conf := suite.conf
+	conf.VerificationCode.Echo = true
+	conf.POC = false
+	conf.Model = "release"
+	conf.VerificationCode.MinuteLimit = 0
+	suite.account = &account{
+		DB:          suite.db,
+		conf:        conf,
+		accountRepo: accountRepo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		message:     message.NewSink(),
+		verifyCode:  verification.NewCode(conf.VerificationCode, suite.redisClient),
+	}
+	rq := &CodeRequest{
+		UserName: "test1@test.com",
+		Model:    conf.VerificationCode.LoginCode,
+	}
+	// echo is ignored out of poc and debug model
+	res, err := suite.account.GetCode(suite.Ctx, rq)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), res.Code)
+	body, err := json.Marshal(res)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "{}", string(body))
+
+	conf.Model = debugModel
+	suite.account.(*account).conf = conf
+	rq.Model = conf.VerificationCode.ForgetCode
+	res, err = suite.account.GetCode(suite.Ctx, rq)
+	assert.Nil(suite.T(), err)
+	cacheCode := suite.redisClient.Get(suite.Ctx, rq.Model+":"+rq.UserName).Val()
+	assert.NotEmpty(suite.T(), res.Code)
+	assert.Equal(suite.T(), cacheCode, res.Code)
+}
+
+func (suite *AccountSuite) TestLinkLogin() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).Times(3)
+
+	// security info falls back to the cached one without systems server
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5, PwdCountWait: 10, LoginType: systems.LoginTypeLink})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+
+	conf := suite.conf
+	conf.LoginLink.Secret = "secret"
+	sink := message.NewSink()
+	suite.account = &account{
+		DB:          suite.db,
+		conf:        conf,
+		accountRepo: accountRepo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		message:     sink,
+		eventRepo:   eventRepo,
+		verifyCode:  verification.NewCode(conf.VerificationCode, suite.redisClient),
+	}
+	_, err := suite.account.SendLink(suite.Ctx, &SendLinkRequest{UserName: "test1@test.com"})
+	assert.Nil(suite.T(), err)
+	var link string
+	assert.Eventually(suite.T(), func() bool {
+		emails := sink.Emails("test1@test.com")
+		if len(emails) == 1 {
+			link = emails[0].Content.KeyAndValue["link"]
+		}
+		return link != ""
+	}, time.Second, 10*time.Millisecond)
+	token, err := url.QueryUnescape(strings.TrimPrefix(link, conf.LoginLink.URL))
+	assert.Nil(suite.T(), err)
+
+	// tampered token
+	_, err = suite.account.LinkLogin(suite.Ctx, &LinkLoginRequest{Token: token + "x"})
+	assert.Equal(suite.T(), error2.New(code.InvalidLoginLink), err)
+
+	res, err := suite.account.LinkLogin(suite.Ctx, &LinkLoginRequest{Token: token})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1", res.UserID)
+
+	// single use
+	_, err = suite.account.LinkLogin(suite.Ctx, &LinkLoginRequest{Token: token})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), "1", suite.redisClient.Get(suite.Ctx, redisAccountPWDErr+"1").Val())
+}
+
+func (suite *AccountSuite) TestLoginTypePolicy() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(db *gorm.DB, event *org.LoginEvent) error {
+		assert.Equal(suite.T(), int64(code.ForbiddenLoginType), event.ErrCode)
+		return nil
+	})
+
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        suite.conf,
+		accountRepo: accountRepo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		message:     message.NewSink(),
+		eventRepo:   eventRepo,
+		verifyCode:  verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
+	}
+	// code login only
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5, LoginType: systems.LoginTypeCode})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	_, err := suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+		UserName: "test1@test.com",
+		Password: "654321a..",
+		Types:    loginTypePwd,
+	})
+	assert.Equal(suite.T(), error2.New(code.ForbiddenLoginType), err)
+	_, err = suite.account.GetCode(suite.Ctx, &CodeRequest{
+		UserName: "test1@test.com",
+		Model:    suite.conf.VerificationCode.LoginCode,
+	})
+	assert.Nil(suite.T(), err)
+
+	// password login only
+	info, _ = json.Marshal(systems.SecurityInfo{PwdCount: 5, LoginType: systems.LoginTypePwd})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	_, err = suite.account.GetCode(suite.Ctx, &CodeRequest{
+		UserName: "test2@test.com",
+		Model:    suite.conf.VerificationCode.LoginCode,
+	})
+	assert.Equal(suite.T(), error2.New(code.ForbiddenLoginType), err)
+}
+
+// tenantUserRepo places the fixture users in tenants
+type tenantUserRepo struct {
+	org.UserRepo
+	tenants map[string]string
+}
+
+func (r tenantUserRepo) Get(ctx context.Context, db *gorm.DB, id string) *org.User {
+	res := r.UserRepo.Get(ctx, db, id)
+	if res == nil {
+		return nil
+	}
+	u := *res
+	u.TenantID = r.tenants[id]
+	return &u
+}
+
+func (suite *AccountSuite) TestLoginTypeTenantPolicy() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes()
+
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        suite.conf,
+		accountRepo: accountRepo,
+		user:        tenantUserRepo{UserRepo: userRepo, tenants: map[string]string{"1": "a", "2": "b"}},
+		redisClient: suite.redisClient,
+		message:     message.NewSink(),
+		eventRepo:   eventRepo,
+		verifyCode:  verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
+	}
+	// the request carries no tenant, its policy allows everything
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	// tenant a allows password login only, tenant b code login only
+	info, _ = json.Marshal(systems.SecurityInfo{PwdCount: 5, LoginType: systems.LoginTypePwd})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret:a", info, time.Minute)
+	info, _ = json.Marshal(systems.SecurityInfo{PwdCount: 5, LoginType: systems.LoginTypeCode})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret:b", info, time.Minute)
+
+	_, err := suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+		UserName: "test1@test.com",
+		Password: "wrong-password",
+		Types:    loginTypePwd,
+	})
+	assert.NotEqual(suite.T(), error2.New(code.ForbiddenLoginType), err)
+	_, err = suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+		UserName: "test2@test.com",
+		Password: "654321a..",
+		Types:    loginTypePwd,
+	})
+	assert.Equal(suite.T(), error2.New(code.ForbiddenLoginType), err)
+
+	_, err = suite.account.GetCode(suite.Ctx, &CodeRequest{
+		UserName: "test1@test.com",
+		Model:    suite.conf.VerificationCode.LoginCode,
+	})
+	assert.Equal(suite.T(), error2.New(code.ForbiddenLoginType), err)
+	_, err = suite.account.GetCode(suite.Ctx, &CodeRequest{
+		UserName: "test2@test.com",
+		Model:    suite.conf.VerificationCode.LoginCode,
+	})
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *AccountSuite) TestSendLinkTenantPolicy() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	conf := suite.conf
+	conf.LoginLink.Secret = "secret"
+	sink := message.NewSink()
+	suite.account = &account{
+		DB:          suite.db,
+		conf:        conf,
+		accountRepo: accountRepo,
+		user:        tenantUserRepo{UserRepo: userRepo, tenants: map[string]string{"1": "a", "2": "b"}},
+		redisClient: suite.redisClient,
+		message:     sink,
+		verifyCode:  verification.NewCode(conf.VerificationCode, suite.redisClient),
+	}
+	// the request carries no tenant, its policy allows links, tenant a does not
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5, LoginType: systems.LoginTypeLink})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret:b", info, time.Minute)
+	info, _ = json.Marshal(systems.SecurityInfo{PwdCount: 5, LoginType: systems.LoginTypePwd})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret:a", info, time.Minute)
+
+	_, err := suite.account.SendLink(suite.Ctx, &SendLinkRequest{UserName: "test1@test.com"})
+	assert.Equal(suite.T(), error2.New(code.ForbiddenLoginType), err)
+	_, err = suite.account.SendLink(suite.Ctx, &SendLinkRequest{UserName: "test2@test.com"})
+	assert.Nil(suite.T(), err)
+	assert.Eventually(suite.T(), func() bool {
+		return len(sink.Emails("test2@test.com")) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(suite.T(), sink.Emails("test1@test.com"))
+}
+
+func (suite *AccountSuite) TestLdapLogin() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	server, err := ldaptest.NewServer(&ldaptest.Entry{
+		DN:         "cn=test1,dc=test,dc=com",
+		Password:   "ldap-pwd",
+		Attributes: map[string][]string{"mail": {"test1@test.com"}},
+	})
+	assert.Nil(suite.T(), err)
+	defer server.Close()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	results := make([]int, 0)
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(db *gorm.DB, event *org.LoginEvent) error {
+		assert.Equal(suite.T(), loginTypeLdap, event.Types)
+		results = append(results, event.Result)
+		return nil
+	}).Times(2)
+
+	conf := suite.conf
+	conf.Ldap = configs.Ldap{
+		Open: true,
+		Directories: []configs.LdapDirectory{{
+			Domains: []string{"test.com"},
+			URL:     server.URL,
+			BaseDN:  "dc=test,dc=com",
+		}},
+	}
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        conf,
+		accountRepo: accountRepo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		ldapClient:  ldap.NewBindLdap(conf.Ldap, nil),
+		eventRepo:   eventRepo,
+		verifyCode:  verification.NewCode(conf.VerificationCode, suite.redisClient),
+	}
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	// the local password no longer counts once the domain is served by a directory
+	_, err = suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+		UserName: "test1@test.com",
+		Password: "654321a..",
+		Types:    loginTypePwd,
+	})
+	assert.NotNil(suite.T(), err)
+	res, err := suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+		UserName: "test1@test.com",
+		Password: "ldap-pwd",
+		Types:    loginTypePwd,
+	})
+	assert.Nil(suite.T(), err)
+	assert.NotNil(suite.T(), res)
+	assert.Equal(suite.T(), []int{loginFail, loginSuccess}, results)
+}
+
+func (suite *AccountSuite) TestAcceptInvitation() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	historyRepo := mock.NewMockPasswordHistoryRepo(ctl)
+	inviteRepo := mock.NewMockInvitationRepo(ctl)
+
+	one := org.Invitation{
+		ID:        "invitation",
+		UserID:    "1",
+		NonceHash: user.HashInvitationNonce("nonce"),
+		Status:    consts.InvitationPending,
+		ExpireAt:  time.Now().Add(time.Hour).UnixNano() / 1e6,
+	}
+	inviteRepo.EXPECT().Get(gomock.Any(), "invitation").DoAndReturn(func(db *gorm.DB, id string) *org.Invitation {
+		res := one
+		return &res
+	}).AnyTimes()
+	// test1 changed the first password already
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), "1").AnyTimes()
+	gomock.InOrder(
+		accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Insert(gomock.Any(), gomock.Any()),
+		historyRepo.EXPECT().Prune(gomock.Any(), gomock.Any(), gomock.Any()),
+		inviteRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(tx *gorm.DB, req *org.Invitation) error {
+			assert.Equal(suite.T(), consts.InvitationAccepted, req.Status)
+			return nil
+		}),
+	)
+	accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()).AnyTimes()
+	historyRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	conf := suite.conf
+	conf.Invitation.Secret = "secret"
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        conf,
+		accountRepo: accountRepo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		historyRepo: historyRepo,
+		inviteRepo:  inviteRepo,
+	}
+	token := encode2.SignToken("secret", "invitation", "nonce")
+
+	info, err := suite.account.InvitationInfo(suite.Ctx, &InvitationInfoRequest{Token: token})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "test1@test.com", info.UserName)
+
+	// signed by another secret, or resent with a new nonce
+	_, err = suite.account.AcceptInvitation(suite.Ctx, &AcceptInvitationRequest{
+		Token:       encode2.SignToken("other", "invitation", "nonce"),
+		NewPassword: "654321Aa..",
+	})
+	assert.Equal(suite.T(), error2.New(code.InvalidInvitation), err)
+	_, err = suite.account.AcceptInvitation(suite.Ctx, &AcceptInvitationRequest{
+		Token:       encode2.SignToken("secret", "invitation", "old"),
+		NewPassword: "654321Aa..",
+	})
+	assert.Equal(suite.T(), error2.New(code.InvalidInvitation), err)
+
+	res, err := suite.account.AcceptInvitation(suite.Ctx, &AcceptInvitationRequest{
+		Token:       token,
+		NewPassword: "654321Aa..",
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1", res.UserID)
+
+	one.ExpireAt = time.Now().Add(-time.Minute).UnixNano() / 1e6
+	_, err = suite.account.AcceptInvitation(suite.Ctx, &AcceptInvitationRequest{
+		Token:       token,
+		NewPassword: "654321Aa..",
+	})
+	assert.Equal(suite.T(), error2.New(code.InvalidInvitation), err)
+}
+
+func (suite *AccountSuite) TestAcceptInvitationTenantPolicy() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	userRepo := mock.NewMockUserRepo(ctl)
+	inviteRepo := mock.NewMockInvitationRepo(ctl)
+	inviteRepo.EXPECT().Get(gomock.Any(), "invitation").Return(&org.Invitation{
+		ID:        "invitation",
+		UserID:    "1",
+		TenantID:  "t1",
+		NonceHash: user.HashInvitationNonce("nonce"),
+		Status:    consts.InvitationPending,
+		ExpireAt:  time.Now().Add(time.Hour).UnixNano() / 1e6,
+	}).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), "1").AnyTimes()
+
+	conf := suite.conf
+	conf.Invitation.Secret = "secret"
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        conf,
+		user:        tenantUserRepo{UserRepo: userRepo, tenants: map[string]string{"1": "t1"}},
+		redisClient: suite.redisClient,
+		inviteRepo:  inviteRepo,
+	}
+	// the request carries no tenant, the invitee is of t1 which asks for longer passwords
+	info, _ := json.Marshal(systems.SecurityInfo{PwdMinLen: 8, PwdType: 15})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	info, _ = json.Marshal(systems.SecurityInfo{PwdMinLen: 16, PwdType: 15})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret:t1", info, time.Minute)
+
+	_, err := suite.account.AcceptInvitation(suite.Ctx, &AcceptInvitationRequest{
+		Token:       encode2.SignToken("secret", "invitation", "nonce"),
+		NewPassword: "654321Aa..",
+	})
+	assert.Equal(suite.T(), error2.New(code.MismatchPasswordRule), err)
+}
+
+func (suite *AccountSuite) TestChangeAccount() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().SelectByEmailOrPhone(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(tx *gorm.DB, res *org.Account) error {
+		assert.Equal(suite.T(), "1", res.ID)
+		assert.Equal(suite.T(), "new1@test.com", res.Account)
+		return nil
+	})
+	userRepo.EXPECT().UpdateByID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, tx *gorm.DB, r *org.User) error {
+		assert.Equal(suite.T(), "new1@test.com", r.Email)
+		return nil
+	})
+
+	conf := suite.conf
+	conf.VerificationCode.Echo = true
+	conf.Model = debugModel
+	suite.account = &account{
+		DB:          suite.db,
+		conf:        conf,
+		accountRepo: accountRepo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		message:     message.NewSink(),
+		verifyCode:  verification.NewCode(conf.VerificationCode, suite.redisClient),
+	}
+
+	// the address of another account
+	_, err := suite.account.ChangeCode(suite.Ctx, &ChangeCodeRequest{UserName: "test2@test.com", UserID: "1"})
+	assert.Equal(suite.T(), error2.New(code.AccountExist), err)
+
+	res, err := suite.account.ChangeCode(suite.Ctx, &ChangeCodeRequest{UserName: "new1@test.com", UserID: "1"})
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), res.Code)
+	suite.redisClient.Set(suite.Ctx, consts.RedisTokenUserInfo+"1", "{}", time.Minute)
+
+	// the code is bound to the user who asked for it
+	_, err = suite.account.ChangeAccount(suite.Ctx, &ChangeAccountRequest{UserName: "new1@test.com", Code: res.Code, UserID: "2"})
+	assert.Equal(suite.T(), error2.New(code.InvalidVerificationCode), err)
+
+	changed, err := suite.account.ChangeAccount(suite.Ctx, &ChangeAccountRequest{UserName: "new1@test.com", Code: res.Code, UserID: "1"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "new1@test.com", changed.User.Email)
+	assert.Equal(suite.T(), int64(0), suite.redisClient.Exists(suite.Ctx, consts.RedisTokenUserInfo+"1").Val())
+}
+
+func (suite *AccountSuite) TestLocks() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	unlockRepo := mock.NewMockUnlockRecordRepo(ctl)
+	unlockRepo.EXPECT().InsertBranch(gomock.Any(), gomock.Any()).AnyTimes()
+	acc := &account{
+		DB:          suite.db,
+		conf:        suite.conf,
+		redisClient: suite.redisClient,
+		unlockRepo:  unlockRepo,
+		message:     message.NewMessage(suite.conf.InternalNet),
+	}
+	info := &systems.SecurityInfo{IPCount: 2, IPCountWait: 5, PwdCount: 2, PwdCountWait: 5}
+	acc.ipFailed(suite.Ctx, "10.0.0.1", info)
+	acc.ipFailed(suite.Ctx, "10.0.0.1", info)
+	acc.ipFailed(suite.Ctx, "10.0.0.2", info)
+	_ = acc.loginFailed(suite.Ctx, "", info, "1", 1)
+
+	// only counters which reached the limit are indexed
+	keys := suite.redisClient.ZRange(suite.Ctx, lockIndex(suite.Ctx), 0, -1).Val()
+	assert.ElementsMatch(suite.T(), []string{ipErrPrefix(suite.Ctx) + "10.0.0.1", redisAccountPWDErr + "1"}, keys)
+	locks, err := acc.locks(suite.Ctx, lockIP, ipErrPrefix(suite.Ctx), info.IPCount)
+	assert.Nil(suite.T(), err)
+	if assert.Len(suite.T(), locks, 1) {
+		assert.Equal(suite.T(), "10.0.0.1", locks[0].Target)
+		assert.Equal(suite.T(), int64(2), locks[0].Count)
+		assert.True(suite.T(), locks[0].TTL > 0)
+	}
+
+	// a cleared counter leaves the index when listed
+	suite.redisClient.Del(suite.Ctx, redisAccountPWDErr+"1")
+	locks, err = acc.locks(suite.Ctx, lockAccount, redisAccountPWDErr, info.PwdCount)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), locks)
+
+	// ip counters of another tenant are neither seen nor cleared
+	other := header2.SetContext(context.Background(), user.TenantID, "other")
+	assert.False(suite.T(), acc.lockedIP(other, "10.0.0.1", info))
+	locks, err = acc.locks(other, lockIP, ipErrPrefix(other), info.IPCount)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), locks)
+	res, err := acc.Unlock(other, &UnlockRequest{Type: lockIP, Targets: []string{"10.0.0.1"}})
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), res.Targets)
+	assert.True(suite.T(), acc.lockedIP(suite.Ctx, "10.0.0.1", info))
+
+	res, err = acc.Unlock(suite.Ctx, &UnlockRequest{Type: lockIP, Targets: []string{"10.0.0.1"}})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"10.0.0.1"}, res.Targets)
+	assert.Empty(suite.T(), suite.redisClient.ZRange(suite.Ctx, lockIndex(suite.Ctx), 0, -1).Val())
+}
+
+func (suite *AccountSuite) TestLocksOfUserTenant() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes()
+	unlockRepo := mock.NewMockUnlockRecordRepo(ctl)
+	unlockRepo.EXPECT().InsertBranch(gomock.Any(), gomock.Any()).AnyTimes()
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        suite.conf,
+		accountRepo: accountRepo,
+		user:        tenantUserRepo{UserRepo: userRepo, tenants: map[string]string{"1": "t1"}},
+		redisClient: suite.redisClient,
+		message:     message.NewSink(),
+		eventRepo:   eventRepo,
+		unlockRepo:  unlockRepo,
+	}
+	info, _ := json.Marshal(systems.SecurityInfo{IPCount: 2, IPCountWait: 5, PwdCount: 2, PwdCountWait: 5})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret:t1", info, time.Minute)
+
+	// the login request carries no tenant, the user is of t1
+	for i := 0; i < 2; i++ {
+		_, err := suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+			UserName: "test1@test.com",
+			Password: "wrong-password",
+			Types:    loginTypePwd,
+			IP:       "10.0.0.1",
+		})
+		assert.NotNil(suite.T(), err)
+	}
+	_, err := suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+		UserName: "nobody@test.com",
+		Password: "wrong-password",
+		Types:    loginTypePwd,
+		IP:       "10.0.0.1",
+	})
+	assert.Equal(suite.T(), error2.New(code.NotExistAccountErr), err)
+
+	t1 := header2.SetContext(context.Background(), user.TenantID, "t1")
+	keys := suite.redisClient.ZRange(suite.Ctx, lockIndex(t1), 0, -1).Val()
+	assert.ElementsMatch(suite.T(), []string{ipErrPrefix(t1) + "10.0.0.1", redisAccountPWDErr + "1"}, keys)
+	assert.Empty(suite.T(), suite.redisClient.ZRange(suite.Ctx, lockIndex(suite.Ctx), 0, -1).Val())
+	// failures of unknown accounts count in no tenant
+	assert.Equal(suite.T(), "1", suite.redisClient.Get(suite.Ctx, ipErrPrefix(suite.Ctx)+"10.0.0.1").Val())
+
+	// the admin of t1 lists and clears them
+	res, err := suite.account.ListLocks(t1, &ListLocksRequest{})
+	assert.Nil(suite.T(), err)
+	targets := make([]string, 0)
+	for _, v := range res.Locks {
+		targets = append(targets, v.Type+":"+v.Target)
+	}
+	assert.ElementsMatch(suite.T(), []string{"ip:10.0.0.1", "account:1"}, targets)
+	res, err = suite.account.ListLocks(suite.Ctx, &ListLocksRequest{})
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), res.Locks)
+
+	unlocked, err := suite.account.Unlock(t1, &UnlockRequest{Type: lockAccount, Targets: []string{"1"}})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"1"}, unlocked.Targets)
+	unlocked, err = suite.account.Unlock(t1, &UnlockRequest{Type: lockIP, Targets: []string{"10.0.0.1"}})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"10.0.0.1"}, unlocked.Targets)
+	assert.Empty(suite.T(), suite.redisClient.ZRange(suite.Ctx, lockIndex(t1), 0, -1).Val())
+}
+
+func (suite *AccountSuite) TestFactorLogin() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	factorRepo := mock.NewMockAccountFactorRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo.EXPECT().SelectByUserID(gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes()
+
+	// the stored factor row
+	var stored *org.AccountFactor
+	factorRepo.EXPECT().SelectByUserID(gomock.Any(), "1").DoAndReturn(func(db *gorm.DB, userID string) *org.AccountFactor {
+		if stored == nil {
+			return nil
+		}
+		one := *stored
+		return &one
+	}).AnyTimes()
+	factorRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(tx *gorm.DB, req *org.AccountFactor) error {
+		one := *req
+		stored = &one
+		return nil
+	})
+	factorRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(tx *gorm.DB, req *org.AccountFactor) error {
+		one := *req
+		stored = &one
+		return nil
+	}).AnyTimes()
+	factorRepo.EXPECT().UpdateRecoveryCodes(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(tx *gorm.DB, req *org.AccountFactor, old string) (int64, error) {
+		if stored.ID != req.ID || stored.RecoveryCodes != old {
+			return 0, nil
+		}
+		stored.RecoveryCodes = req.RecoveryCodes
+		return 1, nil
+	}).AnyTimes()
+	factorRepo.EXPECT().DeleteByUserID(gomock.Any(), "1").DoAndReturn(func(tx *gorm.DB, userID ...string) error {
+		stored = nil
+		return nil
+	})
+
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        suite.conf,
+		accountRepo: accountRepo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		eventRepo:   eventRepo,
+		factorRepo:  factorRepo,
+		verifyCode:  verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
+	}
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5, PwdCountWait: 5, M2FA: true})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	login := func() *LoginAccountResponse {
+		res, err := suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+			UserName: "test1@test.com",
+			Password: "654321a..",
+			Types:    loginTypePwd,
+		})
+		assert.Nil(suite.T(), err)
+		assert.Empty(suite.T(), res.UserID)
+		assert.NotEmpty(suite.T(), res.FactorTicket)
+		return res
+	}
+
+	// the first login enrolls
+	res := login()
+	assert.Equal(suite.T(), factorEnroll, res.Factor)
+	enrolled, err := suite.account.EnrollFactor(suite.Ctx, &EnrollFactorRequest{Ticket: res.FactorTicket})
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), enrolled.Secret)
+	now := time.Now()
+	value, _ := totp.Code(enrolled.Secret, now)
+	_, err = suite.account.ConfirmFactor(suite.Ctx, &ConfirmFactorRequest{Ticket: res.FactorTicket, Code: "000000" + value})
+	assert.Equal(suite.T(), error2.New(code.InvalidFactorCode), err)
+	confirmed, err := suite.account.ConfirmFactor(suite.Ctx, &ConfirmFactorRequest{Ticket: res.FactorTicket, Code: value})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), confirmed.RecoveryCodes, recoveryCodeNum)
+	assert.Equal(suite.T(), "1", confirmed.UserID)
+	// the ticket is used up
+	_, err = suite.account.VerifyFactor(suite.Ctx, &VerifyFactorRequest{Ticket: res.FactorTicket, Code: value})
+	assert.Equal(suite.T(), error2.New(code.ExpireFactorTicket), err)
+
+	// later logins verify, a totp step is accepted once
+	res = login()
+	assert.Equal(suite.T(), factorVerify, res.Factor)
+	_, err = suite.account.VerifyFactor(suite.Ctx, &VerifyFactorRequest{Ticket: res.FactorTicket, Code: value})
+	assert.Equal(suite.T(), error2.New(code.AccountPasswordCountErr, 4), err)
+	value, _ = totp.Code(enrolled.Secret, now.Add(totp.Period*time.Second))
+	verified, err := suite.account.VerifyFactor(suite.Ctx, &VerifyFactorRequest{Ticket: res.FactorTicket, Code: value})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1", verified.UserID)
+	// success clears the failures
+	assert.Empty(suite.T(), suite.redisClient.Get(suite.Ctx, redisAccountPWDErr+"1").Val())
+
+	// a recovery code is accepted once
+	res = login()
+	verified, err = suite.account.VerifyFactor(suite.Ctx, &VerifyFactorRequest{Ticket: res.FactorTicket, Code: strings.ToUpper(confirmed.RecoveryCodes[0])})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1", verified.UserID)
+	assert.Len(suite.T(), strings.Split(stored.RecoveryCodes, ","), recoveryCodeNum-1)
+	res = login()
+	_, err = suite.account.VerifyFactor(suite.Ctx, &VerifyFactorRequest{Ticket: res.FactorTicket, Code: confirmed.RecoveryCodes[0]})
+	assert.NotNil(suite.T(), err)
+
+	// concurrent logins read the same codes, only one consumes it
+	first := suite.account.(*account).factorRepo.SelectByUserID(suite.db, "1")
+	second := suite.account.(*account).factorRepo.SelectByUserID(suite.db, "1")
+	assert.True(suite.T(), suite.account.(*account).checkRecoveryCode(suite.Ctx, first, confirmed.RecoveryCodes[1]))
+	assert.False(suite.T(), suite.account.(*account).checkRecoveryCode(suite.Ctx, second, confirmed.RecoveryCodes[1]))
+
+	// after a reset the next login enrolls again
+	_, err = suite.account.ResetFactor(suite.Ctx, &ResetFactorRequest{UserIDs: []string{"1"}})
+	assert.Nil(suite.T(), err)
+	res = login()
+	assert.Equal(suite.T(), factorEnroll, res.Factor)
+}
+
+func (suite *AccountSuite) TestFactorTenantPolicy() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	factorRepo := mock.NewMockAccountFactorRepo(ctl)
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes()
+	secret, _ := totp.GenerateSecret()
+	factorRepo.EXPECT().SelectByUserID(gomock.Any(), "1").Return(&org.AccountFactor{
+		ID:     "f1",
+		UserID: "1",
+		Secret: secret,
+		Status: factorEnabled,
+	}).AnyTimes()
+
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        suite.conf,
+		accountRepo: accountRepo,
+		user:        tenantUserRepo{UserRepo: userRepo, tenants: map[string]string{"1": "t1"}},
+		redisClient: suite.redisClient,
+		eventRepo:   eventRepo,
+		factorRepo:  factorRepo,
+	}
+	// the request carries no tenant, the user is of t1 which allows one failure
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5, PwdCountWait: 5})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	info, _ = json.Marshal(systems.SecurityInfo{PwdCount: 1, PwdCountWait: 5})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret:t1", info, time.Minute)
+	ticket, err := suite.account.(*account).factorTicket(suite.Ctx, &LoginAccountResponse{UserID: "1"})
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.account.VerifyFactor(suite.Ctx, &VerifyFactorRequest{Ticket: ticket.FactorTicket, Code: "wrong"})
+	assert.Equal(suite.T(), error2.New(code.AccountPasswordCountErr, 0), err)
+	value, _ := totp.Code(secret, time.Now())
+	_, err = suite.account.VerifyFactor(suite.Ctx, &VerifyFactorRequest{Ticket: ticket.FactorTicket, Code: value})
+	assert.Equal(suite.T(), error2.New(code.LockedAccount), err)
+	t1 := header2.SetContext(context.Background(), user.TenantID, "t1")
+	assert.Equal(suite.T(), []string{redisAccountPWDErr + "1"}, suite.redisClient.ZRange(suite.Ctx, lockIndex(t1), 0, -1).Val())
+}
+
+func TestPasswordExpireAt(t *testing.T) {
+	acc := &org.Account{PasswordChangedAt: 1000}
+	assert.Equal(t, int64(0), PasswordExpireAt(acc, 0))
+	assert.Equal(t, int64(1000+90*dayMillis), PasswordExpireAt(acc, 90))
+}
+
+// changedAccountRepo sets the time the fixture passwords were changed
+type changedAccountRepo struct {
+	org.AccountRepo
+	changedAt int64
+}
+
+func (r changedAccountRepo) SelectByAccount(db *gorm.DB, account string) *org.Account {
+	res := r.AccountRepo.SelectByAccount(db, account)
+	if res == nil {
+		return nil
+	}
+	acc := *res
+	acc.PasswordChangedAt = r.changedAt
+	return &acc
+}
+
+func (suite *AccountSuite) TestPasswordExpired() {
+	ctl := gomock.NewController(suite.t)
+	defer ctl.Finish()
+
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	eventRepo := mock.NewMockLoginEventRepo(ctl)
+	accountRepo.EXPECT().SelectByAccount(gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo.EXPECT().UpdatePasswordByUserID(gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes()
+	repo := &changedAccountRepo{AccountRepo: accountRepo}
+	suite.account = &account{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
+		DB:          suite.db,
+		conf:        suite.conf,
+		accountRepo: repo,
+		user:        userRepo,
+		redisClient: suite.redisClient,
+		eventRepo:   eventRepo,
+		verifyCode:  verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
+	}
+	login := func() bool {
+		res, err := suite.account.CheckPassword(suite.Ctx, &LoginAccountRequest{
+			UserName: "test1@test.com",
+			Password: "654321a..",
+			Types:    loginTypePwd,
+		})
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), "1", res.UserID)
+		return res.PasswordExpired
+	}
+
+	info, _ := json.Marshal(systems.SecurityInfo{PwdCount: 5, PwdExpireDays: 90})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	repo.changedAt = time2.NowUnix() - 91*dayMillis
+	assert.True(suite.T(), login())
+	repo.changedAt = time2.NowUnix() - 89*dayMillis
+	assert.False(suite.T(), login())
+
+	// passwords never expire
+	info, _ = json.Marshal(systems.SecurityInfo{PwdCount: 5})
+	suite.redisClient.Set(suite.Ctx, "orgs:systems:secret", info, time.Minute)
+	repo.changedAt = time2.NowUnix() - 91*dayMillis
+	assert.False(suite.T(), login())
 }
@@ -0,0 +1,78 @@
+package account
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+const (
+	loginTypeFactor = "factor"
+	loginSuccess    = 1
+	loginFail       = -1
+)
+
+// audit keep the login attempt, failure of writing does not block login
+func (u *account) audit(c context.Context, event *org.LoginEvent, err error) {
+	event.ID = id2.HexUUID(true)
+	event.CreatedAt = time2.NowUnix()
+	event.Result = loginSuccess
+	if err != nil {
+		event.Result = loginFail
+		event.ErrCode = errCode(err)
+	}
+	if e := u.eventRepo.Insert(u.DB, event); e != nil {
+		logger.Logger.Error(e)
+	}
+}
+
+func errCode(err error) int64 {
+	switch e := err.(type) {
+	case error2.Error:
+		return e.Code
+	case *error2.Error:
+		return e.Code
+	}
+	return error2.Internal
+}
+
+// ListLoginEventsRequest login audit query
+type ListLoginEventsRequest struct {
+	UserID string `json:"userID" form:"userID"`
+	//1:success,-1:fail,0:all
+	Result int `json:"result" form:"result"`
+	// created_at range in milliseconds, 0 for unlimited
+	Begin int64 `json:"begin" form:"begin"`
+	End   int64 `json:"end" form:"end"`
+	Page  int   `json:"page" form:"page"`
+	Limit int   `json:"limit" form:"limit"`
+}
+
+// ListLoginEvents login attempts of current tenant, newest first
+func (u *account) ListLoginEvents(c context.Context, r *ListLoginEventsRequest) (*page.Page, error) {
+	list, total := u.eventRepo.PageList(c, u.DB, r.UserID, r.Result, r.Begin, r.End, r.Page, r.Limit)
+	if list == nil {
+		list = make([]org.LoginEvent, 0)
+	}
+	return &page.Page{
+		Data:       list,
+		TotalCount: total,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package account
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/random2"
+	"github.com/quanxiang-cloud/organizations/pkg/verification"
+)
+
+// changeValue the code kept for a new address is bound to the user asking for it
+func changeValue(userID, code string) string {
+	return userID + ":" + code
+}
+
+// checkChange the user can log in and the new address is not taken
+func (u *account) checkChange(c context.Context, userID, userName string) (*org.User, error) {
+	oldUser := u.user.Get(c, u.DB, userID)
+	if oldUser == nil || oldUser.UseStatus != consts.NormalStatus {
+		return nil, error2.New(code.InvalidAccount)
+	}
+	if userName == oldUser.Email || userName == oldUser.Phone {
+		return nil, error2.New(code.AccountExist)
+	}
+	if u.accountRepo.SelectByAccount(u.DB, userName) != nil {
+		return nil, error2.New(code.AccountExist)
+	}
+	if u.user.SelectByEmailOrPhone(c, u.DB, userName) != nil {
+		return nil, error2.New(code.AccountExist)
+	}
+	return oldUser, nil
+}
+
+// ChangeCodeRequest send code to new login address request
+type ChangeCodeRequest struct {
+	//new email or phone
+	UserName string `json:"userName" form:"userName" binding:"required,max=60,emailOrPhone"`
+	UserID   string `json:"-"`
+	IP       string `json:"-"`
+}
+
+// ChangeCode send a verification code to the new email or phone of login user
+func (u *account) ChangeCode(c context.Context, r *ChangeCodeRequest) (*CodeResponse, error) {
+	if len(r.UserName) > accountLength {
+		return nil, error2.NewErrorWithString(code.ErrTooLong, "接收信息账户超过限制长度")
+	}
+	_, err := u.checkChange(c, r.UserID, r.UserName)
+	if err != nil {
+		return nil, err
+	}
+	rd := strings.ToLower(random2.RandomString(codeLength, 6))
+	err = u.verifyCode.Create(c, u.conf.VerificationCode.ChangeCode, r.UserName, r.IP, changeValue(r.UserID, rd))
+	if err != nil {
+		return nil, err
+	}
+	if verification.CheckEmail(r.UserName) {
+		user.SendAccountAndPWDOrCode(c, u.message, r.UserName, "", "", u.conf.MessageTemplate.ChangeCode, rd, user.SENDEMAIL)
+	} else if verification.CheckPhone(r.UserName) {
+		user.SendAccountAndPWDOrCode(c, u.message, "", "", r.UserName, u.conf.MessageTemplate.ChangeCode, rd, user.SENDPHONE)
+	} else {
+		return nil, error2.New(code.ErrInvalidRuleAccount)
+	}
+	res := &CodeResponse{}
+	if u.conf.VerificationCode.Echo && (u.conf.POC || u.conf.Model == debugModel) {
+		res.Code = rd
+	}
+	return res, nil
+}
+
+// ChangeAccountRequest change login email or phone request
+type ChangeAccountRequest struct {
+	//new email or phone
+	UserName string `json:"userName" binding:"required,max=60,emailOrPhone"`
+	Code     string `json:"code" binding:"required"`
+	UserID   string `json:"-"`
+}
+
+// ChangeAccountResponse change login email or phone response
+type ChangeAccountResponse struct {
+	User *org.User `json:"-"`
+}
+
+// ChangeAccount replace the email or phone of login user and its login account with the verified one,
+// a phone without login account only changes the user
+func (u *account) ChangeAccount(c context.Context, r *ChangeAccountRequest) (*ChangeAccountResponse, error) {
+	oldUser, err := u.checkChange(c, r.UserID, r.UserName)
+	if err != nil {
+		return nil, err
+	}
+	err = u.verifyCode.Verify(c, u.conf.VerificationCode.ChangeCode, r.UserName, changeValue(r.UserID, r.Code))
+	if err != nil {
+		return nil, err
+	}
+	old := oldUser.Phone
+	isEmail := verification.CheckEmail(r.UserName)
+	if isEmail {
+		old = oldUser.Email
+	}
+	now := time2.NowUnix()
+	tx := u.DB.Begin()
+	for _, v := range u.accountRepo.SelectByUserID(u.DB, oldUser.ID) {
+		if old == "" || v.Account != old {
+			continue
+		}
+		err = u.accountRepo.Update(tx, &org.Account{
+			ID:        v.ID,
+			Account:   r.UserName,
+			UpdatedAt: now,
+			UpdatedBy: oldUser.ID,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	changed := *oldUser
+	if isEmail {
+		changed.Email = r.UserName
+	} else {
+		changed.Phone = r.UserName
+	}
+	changed.UpdatedAt = now
+	changed.UpdatedBy = oldUser.ID
+	err = u.user.UpdateByID(c, tx, &changed)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.Commit()
+	u.verifyCode.Del(c, u.conf.VerificationCode.ChangeCode, r.UserName)
+	u.redisClient.Del(c, consts.RedisTokenUserInfo+oldUser.ID)
+	return &ChangeAccountResponse{
+		User: &changed,
+	}, nil
+}
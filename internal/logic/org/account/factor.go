@@ -0,0 +1,329 @@
+package account
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/encode2"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+	"github.com/quanxiang-cloud/organizations/pkg/totp"
+)
+
+const (
+	factorVerify        = "verify"
+	factorEnroll        = "enroll"
+	factorPending       = 0
+	factorEnabled       = 1
+	factorSkew          = 1
+	recoveryCodeNum     = 10
+	redisFactorTicket   = "organizations:factorTicket:"
+	redisFactorUsed     = "organizations:factorUsed:"
+	defaultTicketExpire = 300
+	defaultIssuer       = "QuanxiangCloud"
+)
+
+// factorTicket replace the login result with a ticket for the second step
+func (u *account) factorTicket(c context.Context, res *LoginAccountResponse) (*LoginAccountResponse, error) {
+	state := factorEnroll
+	factor := u.factorRepo.SelectByUserID(u.DB, res.UserID)
+	if factor != nil && factor.Status == factorEnabled {
+		state = factorVerify
+	}
+	expire := u.conf.TwoFactor.TicketExpire
+	if expire == 0 {
+		expire = defaultTicketExpire
+	}
+	ticket := id2.HexUUID(true)
+	marshal, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	err = u.redisClient.SetEX(c, redisFactorTicket+ticket, string(marshal), expire*time.Second).Err()
+	if err != nil {
+		return nil, err
+	}
+	return &LoginAccountResponse{
+		Factor:       state,
+		FactorTicket: ticket,
+	}, nil
+}
+
+// factorSession login result kept by the ticket, or the login user when ticket is empty
+func (u *account) factorSession(c context.Context, ticket, userID string) (*LoginAccountResponse, error) {
+	if ticket == "" {
+		if userID == "" {
+			return nil, error2.New(code.ExpireFactorTicket)
+		}
+		return &LoginAccountResponse{UserID: userID}, nil
+	}
+	val := u.redisClient.Get(c, redisFactorTicket+ticket).Val()
+	res := &LoginAccountResponse{}
+	if val == "" || json.Unmarshal([]byte(val), res) != nil || res.UserID == "" {
+		return nil, error2.New(code.ExpireFactorTicket)
+	}
+	return res, nil
+}
+
+// factorLogin finish login after second factor passed
+func (u *account) factorLogin(c context.Context, ticket string, res *LoginAccountResponse) *LoginAccountResponse {
+	u.redisClient.Del(c, redisFactorTicket+ticket)
+	oldUser := u.user.Get(c, u.DB, res.UserID)
+	if oldUser != nil {
+		res.UseStatus = oldUser.UseStatus
+		res.Name = oldUser.Email
+		res.UserName = oldUser.Name
+	}
+	return res
+}
+
+// checkTOTP validate code and reject a step which has been used
+func (u *account) checkTOTP(c context.Context, factor *org.AccountFactor, value string) bool {
+	step, ok := totp.Validate(factor.Secret, value, time.Now(), factorSkew)
+	if !ok {
+		return false
+	}
+	key := redisFactorUsed + factor.UserID + ":" + strconv.FormatUint(step, 10)
+	return u.redisClient.SetNX(c, key, 1, (2*factorSkew+1)*totp.Period*time.Second).Val()
+}
+
+// checkRecoveryCode consume a recovery code
+func (u *account) checkRecoveryCode(c context.Context, factor *org.AccountFactor, value string) bool {
+	if factor.RecoveryCodes == "" {
+		return false
+	}
+	hashed := encode2.SHA256Encode(strings.ToLower(strings.TrimSpace(value)))
+	codes := strings.Split(factor.RecoveryCodes, ",")
+	for k := range codes {
+		if codes[k] == hashed {
+			old := factor.RecoveryCodes
+			factor.RecoveryCodes = strings.Join(append(codes[:k], codes[k+1:]...), ",")
+			factor.UpdatedAt = time2.NowUnix()
+			// only one of concurrent logins with the same code changes the row
+			affected, err := u.factorRepo.UpdateRecoveryCodes(u.DB, factor, old)
+			return err == nil && affected == 1
+		}
+	}
+	return false
+}
+
+// EnrollFactorRequest enroll second factor request
+type EnrollFactorRequest struct {
+	// ticket from login check when enroll is required, or empty for the login user
+	Ticket string `json:"ticket"`
+	UserID string `json:"-"`
+}
+
+// EnrollFactorResponse enroll second factor response
+type EnrollFactorResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// EnrollFactor generate a pending secret, it takes effect after confirmed
+func (u *account) EnrollFactor(c context.Context, r *EnrollFactorRequest) (*EnrollFactorResponse, error) {
+	session, err := u.factorSession(c, r.Ticket, r.UserID)
+	if err != nil {
+		return nil, err
+	}
+	userID := session.UserID
+	accounts := u.accountRepo.SelectByUserID(u.DB, userID)
+	if accounts == nil {
+		return nil, error2.New(code.NotExistAccountErr)
+	}
+	factor := u.factorRepo.SelectByUserID(u.DB, userID)
+	if factor != nil && factor.Status == factorEnabled {
+		return nil, error2.New(code.ErrFactorEnabled)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	nowUnix := time2.NowUnix()
+	if factor == nil {
+		err = u.factorRepo.Insert(u.DB, &org.AccountFactor{
+			ID:        id2.ShortID(0),
+			UserID:    userID,
+			Secret:    secret,
+			Status:    factorPending,
+			CreatedAt: nowUnix,
+			UpdatedAt: nowUnix,
+			UpdatedBy: userID,
+		})
+	} else {
+		factor.Secret = secret
+		factor.UpdatedAt = nowUnix
+		factor.UpdatedBy = userID
+		err = u.factorRepo.Update(u.DB, factor)
+	}
+	if err != nil {
+		return nil, err
+	}
+	issuer := u.conf.TwoFactor.Issuer
+	if issuer == "" {
+		issuer = defaultIssuer
+	}
+	return &EnrollFactorResponse{
+		Secret: secret,
+		URI:    totp.URI(issuer, accounts[0].Account, secret),
+	}, nil
+}
+
+// ConfirmFactorRequest confirm second factor request
+type ConfirmFactorRequest struct {
+	Ticket string `json:"ticket"`
+	Code   string `json:"code" binding:"required"`
+	UserID string `json:"-"`
+}
+
+// ConfirmFactorResponse confirm second factor response
+type ConfirmFactorResponse struct {
+	// recovery codes only show once
+	RecoveryCodes []string `json:"recoveryCodes"`
+	// login result when confirmed with a ticket
+	*LoginAccountResponse
+}
+
+// ConfirmFactor enable the pending secret with the first code
+func (u *account) ConfirmFactor(c context.Context, r *ConfirmFactorRequest) (*ConfirmFactorResponse, error) {
+	session, err := u.factorSession(c, r.Ticket, r.UserID)
+	if err != nil {
+		return nil, err
+	}
+	userID := session.UserID
+	factor := u.factorRepo.SelectByUserID(u.DB, userID)
+	if factor == nil {
+		return nil, error2.New(code.ErrFactorNotEnroll)
+	}
+	if factor.Status == factorEnabled {
+		return nil, error2.New(code.ErrFactorEnabled)
+	}
+	if !u.checkTOTP(c, factor, r.Code) {
+		return nil, error2.New(code.InvalidFactorCode)
+	}
+	codes, err := totp.RecoveryCodes(recoveryCodeNum)
+	if err != nil {
+		return nil, err
+	}
+	hashed := make([]string, 0, len(codes))
+	for k := range codes {
+		hashed = append(hashed, encode2.SHA256Encode(codes[k]))
+	}
+	factor.RecoveryCodes = strings.Join(hashed, ",")
+	factor.Status = factorEnabled
+	factor.UpdatedAt = time2.NowUnix()
+	factor.UpdatedBy = userID
+	err = u.factorRepo.Update(u.DB, factor)
+	if err != nil {
+		return nil, err
+	}
+	res := &ConfirmFactorResponse{
+		RecoveryCodes: codes,
+	}
+	if r.Ticket != "" {
+		res.LoginAccountResponse = u.factorLogin(c, r.Ticket, session)
+	}
+	return res, nil
+}
+
+// VerifyFactorRequest second step of login
+type VerifyFactorRequest struct {
+	Ticket string `json:"ticket" binding:"required"`
+	// totp code or recovery code
+	Code      string `json:"code" binding:"required"`
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// VerifyFactor verify the second factor, failures share the password lockout
+func (u *account) VerifyFactor(c context.Context, r *VerifyFactorRequest) (*LoginAccountResponse, error) {
+	event := &org.LoginEvent{
+		Types:     loginTypeFactor,
+		IP:        r.IP,
+		UserAgent: r.UserAgent,
+	}
+	res, err := u.verifyFactor(c, r, event)
+	u.audit(c, event, err)
+	return res, err
+}
+
+func (u *account) verifyFactor(c context.Context, r *VerifyFactorRequest, event *org.LoginEvent) (*LoginAccountResponse, error) {
+	session, err := u.factorSession(c, r.Ticket, "")
+	if err != nil {
+		return nil, err
+	}
+	userID := session.UserID
+	event.UserID = userID
+	if one := u.user.Get(c, u.DB, userID); one != nil {
+		event.TenantID = one.TenantID
+		c = tenantContext(c, one.TenantID)
+	}
+	factor := u.factorRepo.SelectByUserID(u.DB, userID)
+	if factor == nil || factor.Status != factorEnabled {
+		return nil, error2.New(code.ErrFactorNotEnroll)
+	}
+	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
+	errNum, err := u.loginErrNum(c, userID)
+	if err != nil {
+		return nil, err
+	}
+	if errNum >= int(info.PwdCount) {
+		u.redisClient.Del(c, redisFactorTicket+r.Ticket)
+		return nil, error2.New(code.LockedAccount)
+	}
+	if u.lockedIP(c, r.IP, info) {
+		return nil, error2.New(code.LockedIP, info.IPCountWait)
+	}
+	if !u.checkTOTP(c, factor, r.Code) && !u.checkRecoveryCode(c, factor, r.Code) {
+		return nil, u.loginFailed(c, r.IP, info, userID, errNum)
+	}
+	u.redisClient.Del(c, redisAccountPWDErr+userID)
+	return u.factorLogin(c, r.Ticket, session), nil
+}
+
+// ResetFactorRequest admin reset second factor request
+type ResetFactorRequest struct {
+	UserIDs []string `json:"userIDs" binding:"required"`
+}
+
+// ResetFactorResponse admin reset second factor response
+type ResetFactorResponse struct {
+}
+
+// ResetFactor remove second factor of users of the tenant, they enroll again at next login
+func (u *account) ResetFactor(c context.Context, r *ResetFactorRequest) (*ResetFactorResponse, error) {
+	users := u.tenantUsers(c, r.UserIDs...)
+	userIDs := make([]string, 0, len(users))
+	for _, id := range r.UserIDs {
+		if users[id] != nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		return &ResetFactorResponse{}, nil
+	}
+	err := u.factorRepo.DeleteByUserID(u.DB, userIDs...)
+	if err != nil {
+		return nil, err
+	}
+	return &ResetFactorResponse{}, nil
+}
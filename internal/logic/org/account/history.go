@@ -0,0 +1,66 @@
+package account
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+)
+
+// historyDepth number of recent passwords which can not be reused, tenant setting first
+func (u *account) historyDepth(info *systems.SecurityInfo) int {
+	if info.PwdHistory > 0 {
+		return int(info.PwdHistory)
+	}
+	return u.conf.PasswordHistory
+}
+
+// usedPassword password is the current one or one of the recent depth ones
+func (u *account) usedPassword(userID, password string, depth int) bool {
+	if depth <= 0 {
+		return false
+	}
+	accounts := u.accountRepo.SelectByUserID(u.DB, userID)
+	if len(accounts) > 0 {
+		if ok, _ := u.hasher.Verify(password, accounts[0].Password); ok {
+			return true
+		}
+	}
+	for _, h := range u.historyRepo.SelectByUserID(u.DB, userID, depth) {
+		if ok, _ := u.hasher.Verify(password, h.Password); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPassword keep the new password in history, drop the ones beyond depth
+func (u *account) recordPassword(tx *gorm.DB, userID, hashed string, depth int) error {
+	if depth <= 0 {
+		return nil
+	}
+	err := u.historyRepo.Insert(tx, &org.PasswordHistory{
+		ID:        id2.ShortID(0),
+		UserID:    userID,
+		Password:  hashed,
+		CreatedAt: time2.NowUnix(),
+	})
+	if err != nil {
+		return err
+	}
+	return u.historyRepo.Prune(tx, userID, depth)
+}
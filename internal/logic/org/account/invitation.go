@@ -0,0 +1,135 @@
+package account
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"crypto/subtle"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/encode2"
+	"github.com/quanxiang-cloud/organizations/pkg/random2"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+)
+
+// invitation pending invitation of the token and its user
+func (u *account) invitation(c context.Context, token string) (*org.Invitation, *org.User, error) {
+	id, nonce, ok := encode2.ParseToken(u.conf.Invitation.Secret, token)
+	if !ok {
+		return nil, nil, error2.New(code.InvalidInvitation)
+	}
+	one := u.inviteRepo.Get(u.DB, id)
+	// a signed token whose nonce changed was resent
+	if one == nil || one.Status != consts.InvitationPending || one.ExpireAt <= time2.NowUnix() ||
+		subtle.ConstantTimeCompare([]byte(one.NonceHash), []byte(user.HashInvitationNonce(nonce))) != 1 {
+		return nil, nil, error2.New(code.InvalidInvitation)
+	}
+	oldUser := u.user.Get(c, u.DB, one.UserID)
+	if oldUser == nil || oldUser.UseStatus != consts.NormalStatus {
+		return nil, nil, error2.New(code.InvalidAccount)
+	}
+	return one, oldUser, nil
+}
+
+// InvitationInfoRequest invitation info request
+type InvitationInfoRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// InvitationInfoResponse who is invited, for the accept page
+type InvitationInfoResponse struct {
+	UserName string `json:"userName"`
+	Name     string `json:"name"`
+	ExpireAt int64  `json:"expireAt"`
+}
+
+// InvitationInfo check the token of an invitation without using it
+func (u *account) InvitationInfo(c context.Context, r *InvitationInfoRequest) (*InvitationInfoResponse, error) {
+	one, oldUser, err := u.invitation(c, r.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &InvitationInfoResponse{
+		UserName: oldUser.Email,
+		Name:     oldUser.Name,
+		ExpireAt: one.ExpireAt,
+	}, nil
+}
+
+// AcceptInvitationRequest accept invitation request
+type AcceptInvitationRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,password"`
+}
+
+// AcceptInvitationResponse accept invitation response
+type AcceptInvitationResponse struct {
+	UserID string `json:"userID"`
+}
+
+// AcceptInvitation the invitee sets the password, it counts as the first password change
+func (u *account) AcceptInvitation(c context.Context, r *AcceptInvitationRequest) (*AcceptInvitationResponse, error) {
+	one, oldUser, err := u.invitation(c, r.Token)
+	if err != nil {
+		return nil, err
+	}
+	// password rules of the tenant of the invitee, the request carries none
+	info := systems.GetSecurityInfo(tenantContext(c, oldUser.TenantID), u.conf, u.redisClient)
+	f := random2.CheckPassword(r.NewPassword, info.PwdMinLen, info.PwdType)
+	if !f {
+		return nil, error2.New(code.MismatchPasswordRule)
+	}
+	depth := u.historyDepth(info)
+	if u.usedPassword(oldUser.ID, r.NewPassword, depth) {
+		return nil, error2.New(code.ErrPasswordUsed, depth)
+	}
+	password, err := u.hasher.Hash(r.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	now := time2.NowUnix()
+	tx := u.DB.Begin()
+	err = u.accountRepo.UpdatePasswordByUserID(tx, &org.Account{
+		UserID:            oldUser.ID,
+		Password:          password,
+		PasswordChangedAt: now,
+	})
+	if err == nil {
+		err = u.recordPassword(tx, oldUser.ID, password, depth)
+	}
+	if err == nil && oldUser.PasswordStatus&consts.NormalStatus == 0 {
+		oldUser.PasswordStatus = oldUser.PasswordStatus + 1
+		err = u.user.UpdateByID(c, tx, oldUser)
+	}
+	if err == nil {
+		one.Status = consts.InvitationAccepted
+		one.AcceptedAt = now
+		one.UpdatedAt = now
+		one.UpdatedBy = oldUser.ID
+		err = u.inviteRepo.Update(tx, one)
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.Commit()
+	u.redisClient.Del(c, redisAccountPWDErr+oldUser.ID)
+	return &AcceptInvitationResponse{
+		UserID: oldUser.ID,
+	}, nil
+}
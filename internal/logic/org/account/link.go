@@ -0,0 +1,171 @@
+package account
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"net/url"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/encode2"
+	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+)
+
+const (
+	loginTypeLink = "link"
+)
+
+// linkEnabled link login needs a secret and the tenant allowing it
+func (u *account) linkEnabled(info *systems.SecurityInfo) bool {
+	return u.conf.LoginLink.Secret != "" && info.AllowLogin(systems.LoginTypeLink)
+}
+
+// SendLinkRequest send login link request
+type SendLinkRequest struct {
+	UserName string `json:"userName" form:"userName" binding:"required,max=60,emailOrPhone"`
+	IP       string `json:"-"`
+}
+
+// SendLinkResponse send login link response
+type SendLinkResponse struct {
+}
+
+// SendLink email a single use login link, it expires with verification codes
+func (u *account) SendLink(c context.Context, r *SendLinkRequest) (*SendLinkResponse, error) {
+	acc := u.accountRepo.SelectByAccount(u.DB, r.UserName)
+	if acc == nil {
+		return nil, error2.New(code.InvalidAccount)
+	}
+	oldUser := u.user.Get(c, u.DB, acc.UserID)
+	if oldUser == nil || oldUser.UseStatus != consts.NormalStatus {
+		return nil, error2.New(code.InvalidAccount)
+	}
+	if oldUser.Email == "" {
+		return nil, error2.New(code.EmailRequired)
+	}
+	// the policy of the tenant of the user, the request carries none
+	info := systems.GetSecurityInfo(tenantContext(c, oldUser.TenantID), u.conf, u.redisClient)
+	if !u.linkEnabled(info) {
+		return nil, error2.New(code.ForbiddenLoginType)
+	}
+	nonce := id2.HexUUID(true)
+	err := u.verifyCode.Create(c, u.conf.VerificationCode.LoginLink, r.UserName, r.IP, nonce)
+	if err != nil {
+		return nil, err
+	}
+	token := encode2.SignToken(u.conf.LoginLink.Secret, r.UserName, nonce)
+	req := &message.CreateReq{}
+	req.Email = &message.Email{
+		To: []string{oldUser.Email},
+		Content: &message.Content{
+			TemplateID: u.conf.MessageTemplate.LoginLink,
+			KeyAndValue: map[string]string{
+				"name": oldUser.Name,
+				"link": u.conf.LoginLink.URL + url.QueryEscape(token),
+			},
+		},
+	}
+	// sent after the response, so not bound to the request
+	ctx := tenantContext(context.Background(), oldUser.TenantID)
+	go func() {
+		err := u.message.SendMessage(ctx, []*message.CreateReq{req})
+		if err != nil {
+			logger.Logger.Error(err)
+		}
+	}()
+	return &SendLinkResponse{}, nil
+}
+
+// LinkLoginRequest login with link request
+type LinkLoginRequest struct {
+	Token     string `json:"token" binding:"required"`
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// LinkLogin login with the token of a link, it shares lockout and audit with password login
+func (u *account) LinkLogin(c context.Context, r *LinkLoginRequest) (*LoginAccountResponse, error) {
+	event := &org.LoginEvent{
+		Types:     loginTypeLink,
+		IP:        r.IP,
+		UserAgent: r.UserAgent,
+	}
+	res, err := u.linkLogin(c, r, event)
+	u.audit(c, event, err)
+	return res, err
+}
+
+func (u *account) linkLogin(c context.Context, r *LinkLoginRequest, event *org.LoginEvent) (*LoginAccountResponse, error) {
+	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
+	unknown := tenantContext(c, "")
+	if u.lockedIP(unknown, r.IP, info) {
+		return nil, error2.New(code.LockedIP, info.IPCountWait)
+	}
+	userName, nonce, ok := encode2.ParseToken(u.conf.LoginLink.Secret, r.Token)
+	if !ok {
+		u.ipFailed(unknown, r.IP, info)
+		return nil, error2.New(code.InvalidLoginLink)
+	}
+	event.Account = userName
+	acc := u.accountRepo.SelectByAccount(u.DB, userName)
+	if acc == nil {
+		u.ipFailed(unknown, r.IP, info)
+		return nil, error2.New(code.InvalidLoginLink)
+	}
+	oldUser := u.user.Get(c, u.DB, acc.UserID)
+	if oldUser == nil {
+		return nil, error2.New(code.InvalidAccount)
+	}
+	event.UserID = oldUser.ID
+	event.TenantID = oldUser.TenantID
+	res := &LoginAccountResponse{
+		UserID:    oldUser.ID,
+		UseStatus: oldUser.UseStatus,
+		Name:      oldUser.Email,
+		UserName:  oldUser.Name,
+	}
+	if oldUser.UseStatus != consts.NormalStatus {
+		return res, error2.New(code.InvalidAccount)
+	}
+	c = tenantContext(c, oldUser.TenantID)
+	info = systems.GetSecurityInfo(c, u.conf, u.redisClient)
+	if !u.linkEnabled(info) {
+		return nil, error2.New(code.ForbiddenLoginType)
+	}
+	if u.lockedIP(c, r.IP, info) {
+		return nil, error2.New(code.LockedIP, info.IPCountWait)
+	}
+	errNum, err := u.loginErrNum(c, acc.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if errNum >= int(info.PwdCount) {
+		return nil, error2.New(code.LockedAccount)
+	}
+	// a signed token whose nonce is gone was used or replaced
+	if u.verifyCode.Verify(c, u.conf.VerificationCode.LoginLink, userName, nonce) != nil {
+		return nil, u.loginFailed(c, r.IP, info, acc.UserID, errNum)
+	}
+	u.verifyCode.Del(c, u.conf.VerificationCode.LoginLink, userName)
+	u.redisClient.Del(c, redisAccountPWDErr+acc.UserID)
+	if info.M2FA {
+		return u.factorTicket(c, res)
+	}
+	return res, nil
+}
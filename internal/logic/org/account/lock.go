@@ -0,0 +1,315 @@
+package account
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+)
+
+const (
+	redisIPErr = "organizations:loginIPErr:"
+	// sorted set of the counters of a tenant that reached their limit, scored by expiry
+	redisLockIndex = "organizations:loginLocks:"
+	lockIP         = "ip"
+	lockAccount    = "account"
+)
+
+// lockedIP failed login from ip reached IPCount
+func (u *account) lockedIP(c context.Context, ip string, info *systems.SecurityInfo) bool {
+	if ip == "" || info.IPCount <= 0 {
+		return false
+	}
+	val, err := u.redisClient.Get(c, ipErrPrefix(c)+ip).Int64()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Logger.Error(err)
+		}
+		return false
+	}
+	return val >= info.IPCount
+}
+
+// ipFailed count a failed login from ip, the window starts at the first failure
+func (u *account) ipFailed(c context.Context, ip string, info *systems.SecurityInfo) {
+	if ip == "" || info.IPCount <= 0 {
+		return
+	}
+	key := ipErrPrefix(c) + ip
+	num, err := u.redisClient.Incr(c, key).Result()
+	if err != nil {
+		logger.Logger.Error(err)
+		return
+	}
+	if num == 1 {
+		u.redisClient.Expire(c, key, time.Duration(info.IPCountWait)*time.Minute)
+	}
+	if num == info.IPCount {
+		u.indexLock(c, key, u.redisClient.TTL(c, key).Val())
+	}
+}
+
+// tenantContext failures of a known account count in the tenant of its user, whose admins list and clear them,
+// failures of unknown accounts count in no tenant
+func tenantContext(c context.Context, tenantID string) context.Context {
+	return context.WithValue(c, user.TenantID, tenantID)
+}
+
+// ipErrPrefix failures of an ip are counted per tenant, admins only see and clear their own
+func ipErrPrefix(c context.Context) string {
+	_, tenantID := ginheader.GetTenantID(c).Wreck()
+	return redisIPErr + tenantID + ":"
+}
+
+// loginFailed count a failed attempt of ip and account
+func (u *account) loginFailed(c context.Context, ip string, info *systems.SecurityInfo, userID string, errNum int) error {
+	u.ipFailed(c, ip, info)
+	wait := time.Duration(info.PwdCountWait) * time.Minute
+	u.redisClient.SetEX(c, redisAccountPWDErr+userID, errNum+1, wait)
+	if int64(errNum+1) >= info.PwdCount {
+		u.indexLock(c, redisAccountPWDErr+userID, wait)
+	}
+	return error2.New(code.AccountPasswordCountErr, int(info.PwdCount)-(errNum+1))
+}
+
+// lockIndex index of the locks of current tenant, a login indexes its failures in the tenant of the user
+func lockIndex(c context.Context) string {
+	_, tenantID := ginheader.GetTenantID(c).Wreck()
+	return redisLockIndex + tenantID
+}
+
+// indexLock remember a locked counter, ListLocks reads the index instead of scanning keys
+func (u *account) indexLock(c context.Context, key string, wait time.Duration) {
+	err := u.redisClient.ZAdd(c, lockIndex(c), &redis.Z{
+		Score:  float64(time.Now().Add(wait).Unix()),
+		Member: key,
+	}).Err()
+	if err != nil {
+		logger.Logger.Error(err)
+	}
+}
+
+// Lock locked ip or account
+type Lock struct {
+	// ip or account
+	Type string `json:"type"`
+	// ip or user id
+	Target string `json:"target"`
+	Count  int64  `json:"count"`
+	// seconds until unlocked
+	TTL int64 `json:"ttl"`
+	// user of account lock
+	UserName string `json:"userName,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// ListLocksRequest list locks request
+type ListLocksRequest struct {
+	// ip or account, empty for both
+	Type string `json:"type" form:"type"`
+}
+
+// ListLocksResponse list locks response
+type ListLocksResponse struct {
+	Locks []Lock `json:"locks"`
+}
+
+// ListLocks ip and account which reached the failure limit
+func (u *account) ListLocks(c context.Context, r *ListLocksRequest) (*ListLocksResponse, error) {
+	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
+	res := &ListLocksResponse{
+		Locks: make([]Lock, 0),
+	}
+	if r.Type == "" || r.Type == lockIP {
+		locks, err := u.locks(c, lockIP, ipErrPrefix(c), info.IPCount)
+		if err != nil {
+			return nil, err
+		}
+		res.Locks = append(res.Locks, locks...)
+	}
+	if r.Type == "" || r.Type == lockAccount {
+		locks, err := u.locks(c, lockAccount, redisAccountPWDErr, info.PwdCount)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(locks))
+		for k := range locks {
+			ids = append(ids, locks[k].Target)
+		}
+		// counters are global, only users of current tenant are shown
+		users := u.tenantUsers(c, ids...)
+		for k := range locks {
+			if one, ok := users[locks[k].Target]; ok {
+				locks[k].UserName = one.Name
+				locks[k].Email = one.Email
+				res.Locks = append(res.Locks, locks[k])
+			}
+		}
+	}
+	return res, nil
+}
+
+// tenantUsers users of current tenant by id
+func (u *account) tenantUsers(c context.Context, id ...string) map[string]*org.User {
+	users := make(map[string]*org.User)
+	if len(id) == 0 {
+		return users
+	}
+	for _, one := range u.user.List(c, u.DB, id...) {
+		users[one.ID] = one
+	}
+	return users
+}
+
+func (u *account) locks(c context.Context, types, prefix string, limit int64) ([]Lock, error) {
+	locks := make([]Lock, 0)
+	if limit <= 0 {
+		return locks, nil
+	}
+	index := lockIndex(c)
+	// expired locks leave the index
+	err := u.redisClient.ZRemRangeByScore(c, index, "-inf", strconv.FormatInt(time.Now().Unix(), 10)).Err()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := u.redisClient.ZRange(c, index, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		num, err := u.redisClient.Get(c, key).Int64()
+		if err == redis.Nil || (err == nil && num < limit) {
+			// cleared by a successful login or an admin
+			u.redisClient.ZRem(c, index, key)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		ttl := u.redisClient.TTL(c, key).Val()
+		locks = append(locks, Lock{
+			Type:   types,
+			Target: strings.TrimPrefix(key, prefix),
+			Count:  num,
+			TTL:    int64(ttl / time.Second),
+		})
+	}
+	return locks, nil
+}
+
+// UnlockRequest unlock request
+type UnlockRequest struct {
+	// ip or account
+	Type string `json:"type" binding:"required"`
+	// ips or user ids
+	Targets  []string `json:"targets" binding:"required"`
+	UnlockBy string   `json:"-"`
+}
+
+// UnlockResponse unlock response
+type UnlockResponse struct {
+	Targets []string `json:"targets"`
+}
+
+// Unlock clear failure counters
+func (u *account) Unlock(c context.Context, r *UnlockRequest) (*UnlockResponse, error) {
+	var prefix string
+	users := make(map[string]*org.User)
+	switch r.Type {
+	case lockIP:
+		prefix = ipErrPrefix(c)
+	case lockAccount:
+		prefix = redisAccountPWDErr
+		users = u.tenantUsers(c, r.Targets...)
+	default:
+		return nil, error2.New(code.InvalidParams)
+	}
+	_, tenantID := ginheader.GetTenantID(c).Wreck()
+	nowUnix := time2.NowUnix()
+	res := &UnlockResponse{
+		Targets: make([]string, 0, len(r.Targets)),
+	}
+	records := make([]org.UnlockRecord, 0, len(r.Targets))
+	notices := make([]*message.CreateReq, 0)
+	// one by one, keys may be in different slots of a cluster
+	for _, target := range r.Targets {
+		if r.Type == lockAccount && users[target] == nil {
+			continue
+		}
+		num, err := u.redisClient.Get(c, prefix+target).Int64()
+		if err == redis.Nil {
+			continue
+		}
+		if err := u.redisClient.Del(c, prefix+target).Err(); err != nil {
+			return nil, err
+		}
+		u.redisClient.ZRem(c, lockIndex(c), prefix+target)
+		res.Targets = append(res.Targets, target)
+		records = append(records, org.UnlockRecord{
+			ID:        id2.ShortID(0),
+			Types:     r.Type,
+			Target:    target,
+			FailCount: num,
+			TenantID:  tenantID,
+			CreatedAt: nowUnix,
+			CreatedBy: r.UnlockBy,
+		})
+		if one := users[target]; one != nil && one.Email != "" && u.conf.MessageTemplate.Unlock != "" {
+			mesReq := new(message.CreateReq)
+			mesReq.Email = &message.Email{
+				To: []string{one.Email},
+				Content: &message.Content{
+					TemplateID: u.conf.MessageTemplate.Unlock,
+					KeyAndValue: map[string]string{
+						"name": one.Name,
+					},
+				},
+			}
+			notices = append(notices, mesReq)
+		}
+	}
+	err := u.unlockRepo.InsertBranch(u.DB, records...)
+	if err != nil {
+		logger.Logger.Error(err)
+	}
+	if len(notices) > 0 {
+		// the request context ends with the response, the notice only keeps the tenant
+		ctx := header2.SetContext(context.Background(), user.TenantID, tenantID)
+		go func() {
+			err := u.message.SendMessage(ctx, notices)
+			if err != nil {
+				logger.Logger.Error(err)
+			}
+		}()
+	}
+	return res, nil
+}
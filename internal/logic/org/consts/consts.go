@@ -44,6 +44,8 @@ const (
 
 	OwnerDepName = "所在部门名称"
 
+	OwnerLeaderName = "直属上级"
+
 	NamePhoneEmailNotNull = "姓名、私人邮箱、公司邮箱不能为空！"
 
 	NameLengthIsLong = "姓名长度超过限制"
@@ -65,6 +67,39 @@ const (
 	PhoneExist = "手机帐户已被占用"
 
 	RelationDepartmentFail = "关联部门失败"
+
+	CreateDepartmentFail = "创建部门失败"
+
+	ImportJobInterrupted = "导入任务已中断，请重新导入"
+
+	RemarkName = "失败原因"
+)
+
+// Department attr
+const (
+	DepAttrCOM = 1
+
+	DepAttrDEP = 2
+)
+
+// Invitation status
+const (
+	InvitationPending = 1
+
+	InvitationAccepted = 2
+
+	InvitationRevoked = -1
+)
+
+// Import job status
+const (
+	ImportJobRunning = 1
+
+	ImportJobFinished = 2
+
+	ImportJobFailed = -1
+
+	ImportJobCanceled = -2
 )
 
 // SYSTEM column
@@ -116,6 +151,8 @@ const (
 	ENTRYTIME = "entryTime"
 
 	SOURCE = "source"
+
+	ROW = "row"
 )
 
 // Field type
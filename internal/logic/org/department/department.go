@@ -49,12 +49,9 @@ type Department interface {
 }
 
 const (
-	allStatus  = 0
-	firsGrade  = 1
-	exist      = 1
-	notExist   = -1
-	depAttrDEP = 2
-	depAttrCOM = 1
+	allStatus = 0
+	exist     = 1
+	notExist  = -1
 )
 
 // department
@@ -240,7 +237,7 @@ func (d *department) Add(c context.Context, r *AddRequest) (res *AddResponse, er
 		CreatedBy: r.CreatBy,
 	}
 	if r.Attr == 0 {
-		insertData.Attr = depAttrDEP
+		insertData.Attr = consts.DepAttrDEP
 	} else {
 		insertData.Attr = r.Attr
 	}
@@ -252,7 +249,7 @@ func (d *department) Add(c context.Context, r *AddRequest) (res *AddResponse, er
 		insertData.Grade = p.Grade + 1
 	} else {
 		insertData.SuperPID = id
-		insertData.Grade = firsGrade
+		insertData.Grade = consts.FirsGrade
 	}
 	err = d.depRepo.Insert(c, tx, &insertData)
 	if err != nil {
@@ -0,0 +1,313 @@
+package impersonation
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/goalie"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+const (
+	redisImpersonation = "organizations:impersonation:"
+
+	tokenSize        = 32
+	defaultExpire    = 1800
+	defaultAdminRole = "super"
+)
+
+// event actions
+const (
+	ActionStart = "start"
+	ActionStop  = "stop"
+	ActionWrite = "write"
+)
+
+// Impersonation admins acting as other users, every step of it is audited
+type Impersonation interface {
+	Start(c context.Context, r *StartRequest) (*StartResponse, error)
+	Stop(c context.Context, r *StopRequest) (*StopResponse, error)
+	Resolve(c context.Context, token, actorID string) (*Session, error)
+	Record(c context.Context, s *Session, r *RecordRequest)
+	ListEvents(c context.Context, r *ListEventsRequest) (*page.Page, error)
+}
+
+type impersonation struct {
+	DB          *gorm.DB
+	conf        configs.Config
+	redisClient redis.UniversalClient
+	userRepo    org.UserRepo
+	userDepRepo org.UserDepartmentRelationRepo
+	eventRepo   org.ImpersonationEventRepo
+	goalie      goalie.Goalie
+}
+
+// NewImpersonation new
+func NewImpersonation(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient) Impersonation {
+	return &impersonation{
+		DB:          db,
+		conf:        conf,
+		redisClient: redisClient,
+		userRepo:    mysql2.NewUserRepo(),
+		userDepRepo: mysql2.NewUserDepartmentRelationRepo(),
+		eventRepo:   mysql2.NewImpersonationEventRepo(),
+		goalie:      goalie.NewGoalie(conf.InternalNet),
+	}
+}
+
+// Session an admin acting as a user
+type Session struct {
+	ID           string `json:"id"`
+	ActorID      string `json:"actorID"`
+	ActorName    string `json:"actorName"`
+	UserID       string `json:"userID"`
+	UserName     string `json:"userName"`
+	DepartmentID string `json:"departmentID"`
+	TenantID     string `json:"tenantID"`
+	ExpireAt     int64  `json:"expireAt"`
+}
+
+// Profile the profile requests are handled with while impersonating
+func (s *Session) Profile() header2.Profile {
+	return header2.Profile{
+		UserID:       s.UserID,
+		UserName:     s.UserName,
+		DepartmentID: s.DepartmentID,
+		TenantID:     s.TenantID,
+	}
+}
+
+// StartRequest start acting as a user
+type StartRequest struct {
+	UserID    string `json:"userID" binding:"required"`
+	Reason    string `json:"reason" binding:"required,max=255"`
+	ActorID   string `json:"-"`
+	ActorName string `json:"-"`
+	IP        string `json:"-"`
+}
+
+// StartResponse the token is only returned here
+type StartResponse struct {
+	Token     string `json:"token"`
+	SessionID string `json:"sessionID"`
+	UserID    string `json:"userID"`
+	UserName  string `json:"userName"`
+	ExpireAt  int64  `json:"expireAt"`
+}
+
+// Start start acting as a user, admins and the actor self can not be impersonated
+func (i *impersonation) Start(c context.Context, r *StartRequest) (*StartResponse, error) {
+	if r.UserID == r.ActorID {
+		return nil, error2.New(code.ForbiddenImpersonation)
+	}
+	_, tenantID := ginheader.GetTenantID(c).Wreck()
+	user := i.userRepo.Get(c, i.DB, r.UserID)
+	if user == nil || user.UseStatus == consts.DelStatus || user.TenantID != tenantID {
+		return nil, error2.New(code.DataNotExist)
+	}
+	if user.UseStatus != consts.NormalStatus {
+		return nil, error2.New(code.InvalidAccount)
+	}
+	admin, err := i.isAdmin(c, r.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if admin {
+		return nil, error2.New(code.ForbiddenImpersonation)
+	}
+
+	s := &Session{
+		ID:        id2.HexUUID(true),
+		ActorID:   r.ActorID,
+		ActorName: r.ActorName,
+		UserID:    user.ID,
+		UserName:  user.Name,
+		TenantID:  tenantID,
+	}
+	if deps := i.userDepRepo.SelectByUserIDs(i.DB, user.ID); len(deps) > 0 {
+		s.DepartmentID = deps[0].DepID
+	}
+	expire := i.conf.Impersonation.Expire
+	if expire <= 0 {
+		expire = defaultExpire
+	}
+	s.ExpireAt = time2.NowUnix() + int64(expire)*1000
+
+	buf := make([]byte, tokenSize)
+	if _, err = rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	marshal, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	err = i.redisClient.SetEX(c, redisImpersonation+hashToken(token), string(marshal), expire*time.Second).Err()
+	if err != nil {
+		return nil, err
+	}
+	i.record(c, s, &org.ImpersonationEvent{
+		Action: ActionStart,
+		Reason: r.Reason,
+		IP:     r.IP,
+	})
+	return &StartResponse{
+		Token:     token,
+		SessionID: s.ID,
+		UserID:    s.UserID,
+		UserName:  s.UserName,
+		ExpireAt:  s.ExpireAt,
+	}, nil
+}
+
+// isAdmin the user owns one of the admin roles, it fails closed when roles can not be read
+func (i *impersonation) isAdmin(c context.Context, userID string) (bool, error) {
+	res, err := i.goalie.UserRoles(c, &goalie.UserRolesRequest{UserID: userID})
+	if err != nil {
+		logger.Logger.Error("get user roles", err)
+		return false, err
+	}
+	adminRoles := i.conf.Impersonation.AdminRoles
+	if len(adminRoles) == 0 {
+		adminRoles = []string{defaultAdminRole}
+	}
+	for _, role := range res.Roles {
+		for _, tag := range adminRoles {
+			if role.Tag == tag {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// StopRequest stop acting as a user
+type StopRequest struct {
+	Token   string `json:"token" binding:"required"`
+	ActorID string `json:"-"`
+	IP      string `json:"-"`
+}
+
+// StopResponse stop response
+type StopResponse struct {
+}
+
+// Stop end the session of token, only its actor can stop it
+func (i *impersonation) Stop(c context.Context, r *StopRequest) (*StopResponse, error) {
+	s, err := i.Resolve(c, r.Token, r.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	i.redisClient.Del(c, redisImpersonation+hashToken(r.Token))
+	i.record(c, s, &org.ImpersonationEvent{
+		Action: ActionStop,
+		IP:     r.IP,
+	})
+	return &StopResponse{}, nil
+}
+
+// Resolve the session of token, it must be alive and started by actor
+func (i *impersonation) Resolve(c context.Context, token, actorID string) (*Session, error) {
+	val := i.redisClient.Get(c, redisImpersonation+hashToken(token)).Val()
+	if val == "" {
+		return nil, error2.New(code.InvalidImpersonation)
+	}
+	s := new(Session)
+	if err := json.Unmarshal([]byte(val), s); err != nil {
+		return nil, error2.New(code.InvalidImpersonation)
+	}
+	if s.ActorID != actorID {
+		return nil, error2.New(code.InvalidImpersonation)
+	}
+	return s, nil
+}
+
+// RecordRequest a write made while impersonating
+type RecordRequest struct {
+	Method string
+	Path   string
+	Status int
+	IP     string
+}
+
+// Record audit a write made while impersonating
+func (i *impersonation) Record(c context.Context, s *Session, r *RecordRequest) {
+	i.record(c, s, &org.ImpersonationEvent{
+		Action: ActionWrite,
+		Method: r.Method,
+		Path:   r.Path,
+		Status: r.Status,
+		IP:     r.IP,
+	})
+}
+
+// record the audit must not break the request, failures are only logged
+func (i *impersonation) record(c context.Context, s *Session, event *org.ImpersonationEvent) {
+	event.ID = id2.ShortID(0)
+	event.SessionID = s.ID
+	event.ActorID = s.ActorID
+	event.UserID = s.UserID
+	event.TenantID = s.TenantID
+	event.CreatedAt = time2.NowUnix()
+	if err := i.eventRepo.Insert(i.DB, event); err != nil {
+		logger.Logger.Error("record impersonation event", err)
+	}
+}
+
+// ListEventsRequest list impersonation events
+type ListEventsRequest struct {
+	SessionID string `json:"sessionID" form:"sessionID"`
+	ActorID   string `json:"actorID" form:"actorID"`
+	UserID    string `json:"userID" form:"userID"`
+	Page      int    `json:"page" form:"page"`
+	Limit     int    `json:"limit" form:"limit"`
+}
+
+// ListEvents impersonation events of current tenant, newest first
+func (i *impersonation) ListEvents(c context.Context, r *ListEventsRequest) (*page.Page, error) {
+	list, total := i.eventRepo.PageList(c, i.DB, r.SessionID, r.ActorID, r.UserID, r.Page, r.Limit)
+	if list == nil {
+		list = make([]org.ImpersonationEvent, 0)
+	}
+	return &page.Page{
+		Data:       list,
+		TotalCount: total,
+	}, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
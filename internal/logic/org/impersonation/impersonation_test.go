@@ -0,0 +1,106 @@
+package impersonation
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/mock"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/goalie"
+)
+
+func TestImpersonation(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	ctrl := gomock.NewController(t)
+	userRepo := mock.NewMockUserRepo(ctrl)
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userDepRepo := mock.NewMockUserDepartmentRelationRepo(ctrl)
+	userDepRepo.EXPECT().SelectByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	goalieAPI := mock.NewMockGoalie(ctrl)
+	goalieAPI.EXPECT().UserRoles(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r *goalie.UserRolesRequest) (*goalie.UserRolesResponse, error) {
+		if r.UserID == "2" {
+			return &goalie.UserRolesResponse{Roles: []goalie.Role{{ID: "r1", Name: "超级管理员", Tag: "super"}}}, nil
+		}
+		return &goalie.UserRolesResponse{}, nil
+	}).AnyTimes()
+	events := make([]*org.ImpersonationEvent, 0)
+	eventRepo := mock.NewMockImpersonationEventRepo(ctrl)
+	eventRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, e *org.ImpersonationEvent) error {
+		events = append(events, e)
+		return nil
+	}).AnyTimes()
+
+	i := &impersonation{
+		conf:        configs.Config{},
+		redisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		userRepo:    userRepo,
+		userDepRepo: userDepRepo,
+		eventRepo:   eventRepo,
+		goalie:      goalieAPI,
+	}
+	ctx := context.Background()
+
+	_, err = i.Start(ctx, &StartRequest{UserID: "0", Reason: "self", ActorID: "0"})
+	assert.Equal(t, error2.New(code.ForbiddenImpersonation), err)
+	_, err = i.Start(ctx, &StartRequest{UserID: "2", Reason: "admin", ActorID: "0"})
+	assert.Equal(t, error2.New(code.ForbiddenImpersonation), err)
+	_, err = i.Start(ctx, &StartRequest{UserID: "none", Reason: "missing", ActorID: "0"})
+	assert.Equal(t, error2.New(code.DataNotExist), err)
+	assert.Empty(t, events)
+
+	res, err := i.Start(ctx, &StartRequest{UserID: "1", Reason: "ticket 42", ActorID: "0", IP: "127.0.0.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test1", res.UserName)
+	assert.NotEmpty(t, res.Token)
+
+	// only the actor can use the token
+	_, err = i.Resolve(ctx, res.Token, "2")
+	assert.Equal(t, error2.New(code.InvalidImpersonation), err)
+	s, err := i.Resolve(ctx, res.Token, "0")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", s.Profile().UserID)
+	assert.Equal(t, res.SessionID, s.ID)
+
+	i.Record(ctx, s, &RecordRequest{Method: "PUT", Path: "/api/v1/org/h/user/update/avatar", Status: 200})
+
+	_, err = i.Stop(ctx, &StopRequest{Token: res.Token, ActorID: "0"})
+	assert.NoError(t, err)
+	_, err = i.Resolve(ctx, res.Token, "0")
+	assert.Equal(t, error2.New(code.InvalidImpersonation), err)
+
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, ActionStart, events[0].Action)
+		assert.Equal(t, "ticket 42", events[0].Reason)
+		assert.Equal(t, ActionWrite, events[1].Action)
+		assert.Equal(t, ActionStop, events[2].Action)
+		for _, e := range events {
+			assert.Equal(t, res.SessionID, e.SessionID)
+			assert.Equal(t, "0", e.ActorID)
+			assert.Equal(t, "1", e.UserID)
+		}
+	}
+}
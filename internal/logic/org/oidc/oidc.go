@@ -0,0 +1,704 @@
+package oidc
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/account"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/jws"
+	"github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+const (
+	redisOidcCode      = "organizations:oidcCode:"
+	defaultCodeExpire  = 60
+	defaultTokenExpire = 3600
+	responseTypeCode   = "code"
+	grantTypeCode      = "authorization_code"
+	methodS256         = "S256"
+	methodPlain        = "plain"
+	typAccessToken     = "at+jwt"
+	typIDToken         = "JWT"
+	publicClient       = 1
+	secretSize         = 32
+
+	scopeOpenID  = "openid"
+	scopeProfile = "profile"
+	scopeEmail   = "email"
+	scopePhone   = "phone"
+)
+
+var supportedScopes = []string{scopeOpenID, scopeProfile, scopeEmail, scopePhone}
+
+// Provider openid connect provider, authorization code flow with pkce
+type Provider interface {
+	Discovery() map[string]interface{}
+	JWKS() jws.JWKS
+	Authorize(c context.Context, r *AuthorizeRequest) (*AuthorizeResponse, error)
+	Token(c context.Context, r *TokenRequest) (*TokenResponse, error)
+	UserInfo(c context.Context, r *UserInfoRequest) (map[string]interface{}, error)
+
+	AddClient(c context.Context, r *AddClientRequest) (*AddClientResponse, error)
+	UpdateClient(c context.Context, r *UpdateClientRequest) (*UpdateClientResponse, error)
+	DeleteClient(c context.Context, r *DeleteClientRequest) (*DeleteClientResponse, error)
+	ListClients(c context.Context, r *ListClientsRequest) (*page.Page, error)
+}
+
+type provider struct {
+	DB          *gorm.DB
+	conf        configs.Config
+	redisClient redis.UniversalClient
+	account     account.Account
+	user        user.User
+	clientRepo  org.OidcClientRepo
+	signer      *jws.Signer
+}
+
+// NewProvider new, it fails when the signing key can not be loaded
+func NewProvider(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient) (Provider, error) {
+	signer, err := jws.NewSigner(conf.Oidc.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &provider{
+		DB:          db,
+		conf:        conf,
+		redisClient: redisClient,
+		account:     account.NewAccount(conf, db, redisClient),
+		user:        user.NewUser(conf, db, redisClient),
+		clientRepo:  mysql2.NewOidcClientRepo(),
+		signer:      signer,
+	}, nil
+}
+
+// Discovery openid provider metadata
+func (p *provider) Discovery() map[string]interface{} {
+	issuer := strings.TrimSuffix(p.conf.Oidc.Issuer, "/")
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{responseTypeCode},
+		"grant_types_supported":                 []string{grantTypeCode},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jws.AlgRS256},
+		"scopes_supported":                      supportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{methodS256, methodPlain},
+		"claims_supported": []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "at_hash",
+			"name", "picture", "position", "job_number", "email", "phone_number",
+			"tenant_id", "departments",
+		},
+	}
+}
+
+// JWKS public keys of token signatures
+func (p *provider) JWKS() jws.JWKS {
+	return p.signer.JWKS()
+}
+
+// AuthorizeRequest authorization request, login fields are sent by the login page
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type"`
+	ClientID            string `json:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	Nonce               string `json:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+	// none: fail with login_required instead of showing the login page
+	Prompt string `json:"prompt" form:"prompt"`
+
+	// password login, same as /h/account/check
+	UserName string `json:"userName" form:"-"`
+	Password string `json:"password" form:"-"`
+	Types    string `json:"types" form:"-"`
+	// second step of login when the first one answered a factor ticket
+	FactorTicket string `json:"factorTicket" form:"-"`
+	FactorCode   string `json:"factorCode" form:"-"`
+
+	Header    http.Header `json:"-" form:"-"`
+	IP        string      `json:"-" form:"-"`
+	UserAgent string      `json:"-" form:"-"`
+}
+
+// AuthorizeResponse redirect to the client or login page, or the next login step
+type AuthorizeResponse struct {
+	RedirectURI     string `json:"redirectURI,omitempty"`
+	Factor          string `json:"factor,omitempty"`
+	FactorTicket    string `json:"factorTicket,omitempty"`
+	PasswordExpired bool   `json:"passwordExpired,omitempty"`
+}
+
+// grant kept by an authorization code
+type grant struct {
+	ClientID            string `json:"clientID"`
+	RedirectURI         string `json:"redirectURI"`
+	UserID              string `json:"userID"`
+	Scope               string `json:"scope"`
+	Nonce               string `json:"nonce"`
+	CodeChallenge       string `json:"codeChallenge"`
+	CodeChallengeMethod string `json:"codeChallengeMethod"`
+	AuthTime            int64  `json:"authTime"`
+}
+
+// Authorize check the request, login the user and redirect to the client with a code.
+// Errors of an unknown client or redirect uri are returned, other errors go to the client.
+func (p *provider) Authorize(c context.Context, r *AuthorizeRequest) (*AuthorizeResponse, error) {
+	client := p.clientRepo.Get(p.DB, r.ClientID)
+	if client == nil {
+		return nil, error2.New(code.InvalidClient)
+	}
+	redirectURI, ok := matchRedirectURI(client, r.RedirectURI)
+	if !ok {
+		return nil, error2.New(code.InvalidRedirectURI)
+	}
+	if r.ResponseType != responseTypeCode {
+		return redirectError(redirectURI, r.State, "unsupported_response_type"), nil
+	}
+	if !hasScope(r.Scope, scopeOpenID) {
+		return redirectError(redirectURI, r.State, "invalid_scope"), nil
+	}
+	if r.CodeChallengeMethod == "" && r.CodeChallenge != "" {
+		r.CodeChallengeMethod = methodPlain
+	}
+	if (client.Public == publicClient && r.CodeChallenge == "") ||
+		(r.CodeChallenge != "" && r.CodeChallengeMethod != methodS256 && r.CodeChallengeMethod != methodPlain) {
+		return redirectError(redirectURI, r.State, "invalid_request"), nil
+	}
+
+	// codes are only issued to users logging in here, no identity header is trusted
+	var userID string
+	switch {
+	case r.FactorTicket != "":
+		res, err := p.account.VerifyFactor(c, &account.VerifyFactorRequest{
+			Ticket:    r.FactorTicket,
+			Code:      r.FactorCode,
+			IP:        r.IP,
+			UserAgent: r.UserAgent,
+		})
+		if err != nil {
+			return nil, err
+		}
+		userID = res.UserID
+	case r.UserName != "":
+		res, err := p.account.CheckPassword(c, &account.LoginAccountRequest{
+			UserName:  r.UserName,
+			Password:  r.Password,
+			Types:     r.Types,
+			Header:    r.Header,
+			IP:        r.IP,
+			UserAgent: r.UserAgent,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if res.FactorTicket != "" || res.PasswordExpired {
+			return &AuthorizeResponse{
+				Factor:          res.Factor,
+				FactorTicket:    res.FactorTicket,
+				PasswordExpired: res.PasswordExpired,
+			}, nil
+		}
+		userID = res.UserID
+	case r.Prompt == "none" || p.conf.Oidc.LoginURL == "":
+		return redirectError(redirectURI, r.State, "login_required"), nil
+	default:
+		return &AuthorizeResponse{RedirectURI: withQuery(p.conf.Oidc.LoginURL, authorizeQuery(r))}, nil
+	}
+
+	one, err := p.user.OthGetOneUser(c, &user.TokenUserRequest{ID: userID})
+	if err != nil || one.UseStatus != consts.NormalStatus || one.TenantID != client.TenantID {
+		return redirectError(redirectURI, r.State, "access_denied"), nil
+	}
+	authCode, err := p.newCode(c, &grant{
+		ClientID:            client.ID,
+		RedirectURI:         r.RedirectURI,
+		UserID:              userID,
+		Scope:               r.Scope,
+		Nonce:               r.Nonce,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+		AuthTime:            time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("code", authCode)
+	if r.State != "" {
+		query.Set("state", r.State)
+	}
+	return &AuthorizeResponse{RedirectURI: withQuery(redirectURI, query)}, nil
+}
+
+func (p *provider) newCode(c context.Context, g *grant) (string, error) {
+	expire := p.conf.Oidc.CodeExpire
+	if expire == 0 {
+		expire = defaultCodeExpire
+	}
+	marshal, err := json.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	authCode := id2.HexUUID(true)
+	err = p.redisClient.SetEX(c, redisOidcCode+authCode, string(marshal), expire*time.Second).Err()
+	if err != nil {
+		return "", err
+	}
+	return authCode, nil
+}
+
+// useCode grant of the code, a code is only used once
+func (p *provider) useCode(c context.Context, authCode string) (*grant, error) {
+	val := p.redisClient.Get(c, redisOidcCode+authCode).Val()
+	if val == "" || p.redisClient.Del(c, redisOidcCode+authCode).Val() != 1 {
+		return nil, error2.New(code.InvalidGrant)
+	}
+	g := new(grant)
+	if err := json.Unmarshal([]byte(val), g); err != nil {
+		return nil, error2.New(code.InvalidGrant)
+	}
+	return g, nil
+}
+
+// TokenRequest token request, client credentials of basic auth are copied in by the handler
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+}
+
+// TokenResponse token response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// accessClaims claims of access token
+type accessClaims struct {
+	Iss      string `json:"iss"`
+	Sub      string `json:"sub"`
+	Aud      string `json:"aud"`
+	ClientID string `json:"client_id"`
+	Exp      int64  `json:"exp"`
+	Iat      int64  `json:"iat"`
+	Jti      string `json:"jti"`
+	Scope    string `json:"scope"`
+	TenantID string `json:"tenant_id"`
+}
+
+// Token exchange an authorization code for an access token and id token
+func (p *provider) Token(c context.Context, r *TokenRequest) (*TokenResponse, error) {
+	if r.GrantType != grantTypeCode {
+		return nil, error2.New(code.UnsupportedGrantType)
+	}
+	client := p.clientRepo.Get(p.DB, r.ClientID)
+	if client == nil || !checkSecret(client, r.ClientSecret) {
+		return nil, error2.New(code.InvalidClient)
+	}
+	g, err := p.useCode(c, r.Code)
+	if err != nil {
+		return nil, err
+	}
+	if g.ClientID != client.ID || g.RedirectURI != r.RedirectURI || !checkVerifier(g, r.CodeVerifier) {
+		return nil, error2.New(code.InvalidGrant)
+	}
+	one, err := p.user.OthGetOneUser(c, &user.TokenUserRequest{ID: g.UserID})
+	if err != nil || one.UseStatus != consts.NormalStatus {
+		return nil, error2.New(code.InvalidGrant)
+	}
+
+	expire := int64(p.conf.Oidc.TokenExpire)
+	if expire == 0 {
+		expire = defaultTokenExpire
+	}
+	now := time.Now().Unix()
+	issuer := strings.TrimSuffix(p.conf.Oidc.Issuer, "/")
+	accessToken, err := p.signer.Sign(typAccessToken, &accessClaims{
+		Iss:      issuer,
+		Sub:      one.ID,
+		Aud:      client.ID,
+		ClientID: client.ID,
+		Exp:      now + expire,
+		Iat:      now,
+		Jti:      id2.HexUUID(true),
+		Scope:    g.Scope,
+		TenantID: one.TenantID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims := userClaims(one, g.Scope)
+	claims["iss"] = issuer
+	claims["aud"] = client.ID
+	claims["exp"] = now + expire
+	claims["iat"] = now
+	claims["auth_time"] = g.AuthTime
+	claims["at_hash"] = jws.HalfHash(accessToken)
+	if g.Nonce != "" {
+		claims["nonce"] = g.Nonce
+	}
+	idToken, err := p.signer.Sign(typIDToken, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   expire,
+		IDToken:     idToken,
+		Scope:       g.Scope,
+	}, nil
+}
+
+// UserInfoRequest userinfo request
+type UserInfoRequest struct {
+	AccessToken string
+}
+
+// UserInfo claims of the user of access token
+func (p *provider) UserInfo(c context.Context, r *UserInfoRequest) (map[string]interface{}, error) {
+	claims := new(accessClaims)
+	header, err := p.signer.Verify(r.AccessToken, claims)
+	if err != nil || header.Typ != typAccessToken ||
+		claims.Iss != strings.TrimSuffix(p.conf.Oidc.Issuer, "/") || claims.Exp <= time.Now().Unix() {
+		return nil, error2.New(code.InvalidAccessToken)
+	}
+	one, err := p.user.OthGetOneUser(c, &user.TokenUserRequest{ID: claims.Sub})
+	if err != nil || one.UseStatus != consts.NormalStatus {
+		return nil, error2.New(code.InvalidAccessToken)
+	}
+	return userClaims(one, claims.Scope), nil
+}
+
+// department claim, path is the names from the top department down
+type department struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// userClaims claims of user allowed by scope, tenant and departments are always there
+func userClaims(one *user.TokenUserResponse, scope string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub":       one.ID,
+		"tenant_id": one.TenantID,
+	}
+	departments := make([]department, 0, len(one.DEP))
+	for _, chain := range one.DEP {
+		// chain goes from the department of user up to the top
+		if len(chain) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(chain))
+		for i := len(chain) - 1; i >= 0; i-- {
+			names = append(names, chain[i].Name)
+		}
+		departments = append(departments, department{
+			ID:   chain[0].ID,
+			Name: chain[0].Name,
+			Path: strings.Join(names, "/"),
+		})
+	}
+	claims["departments"] = departments
+	if hasScope(scope, scopeProfile) {
+		claims["name"] = one.Name
+		setClaim(claims, "picture", one.Avatar)
+		setClaim(claims, "position", one.Position)
+		setClaim(claims, "job_number", one.JobNumber)
+	}
+	if hasScope(scope, scopeEmail) {
+		setClaim(claims, "email", one.Email)
+	}
+	if hasScope(scope, scopePhone) {
+		setClaim(claims, "phone_number", one.Phone)
+	}
+	return claims
+}
+
+func setClaim(claims map[string]interface{}, name, value string) {
+	if value != "" {
+		claims[name] = value
+	}
+}
+
+// AddClientRequest register a client
+type AddClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	RedirectURIs []string `json:"redirectURIs" binding:"required,min=1"`
+	// public clients, as single page or mobile apps, have no secret and must use pkce
+	Public    bool   `json:"public"`
+	CreatedBy string `json:"-"`
+}
+
+// AddClientResponse the secret is only returned here
+type AddClientResponse struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// AddClient register a client of current tenant
+func (p *provider) AddClient(c context.Context, r *AddClientRequest) (*AddClientResponse, error) {
+	if !validRedirectURIs(r.RedirectURIs) {
+		return nil, error2.New(code.InvalidRedirectURI)
+	}
+	now := time2.NowUnix()
+	client := &org.OidcClient{
+		ID:           id2.HexUUID(true),
+		Name:         r.Name,
+		RedirectURIs: strings.Join(r.RedirectURIs, ","),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		CreatedBy:    r.CreatedBy,
+	}
+	res := &AddClientResponse{ID: client.ID}
+	if r.Public {
+		client.Public = publicClient
+	} else {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		client.Secret = hashSecret(secret)
+		res.Secret = secret
+	}
+	if err := p.clientRepo.Insert(c, p.DB, client); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpdateClientRequest update a client
+type UpdateClientRequest struct {
+	ID           string   `json:"id" binding:"required"`
+	Name         string   `json:"name" binding:"required,max=64"`
+	RedirectURIs []string `json:"redirectURIs" binding:"required,min=1"`
+	// ResetSecret replace the secret of a confidential client
+	ResetSecret bool   `json:"resetSecret"`
+	UpdatedBy   string `json:"-"`
+}
+
+// UpdateClientResponse the new secret when it was reset
+type UpdateClientResponse struct {
+	Secret string `json:"secret,omitempty"`
+}
+
+// UpdateClient update a client of current tenant
+func (p *provider) UpdateClient(c context.Context, r *UpdateClientRequest) (*UpdateClientResponse, error) {
+	client := p.getClient(c, r.ID)
+	if client == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	if !validRedirectURIs(r.RedirectURIs) {
+		return nil, error2.New(code.InvalidRedirectURI)
+	}
+	client.Name = r.Name
+	client.RedirectURIs = strings.Join(r.RedirectURIs, ",")
+	client.UpdatedAt = time2.NowUnix()
+	client.UpdatedBy = r.UpdatedBy
+	res := &UpdateClientResponse{}
+	if r.ResetSecret && client.Public != publicClient {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		client.Secret = hashSecret(secret)
+		res.Secret = secret
+	}
+	if err := p.clientRepo.Update(p.DB, client); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteClientRequest delete clients
+type DeleteClientRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1"`
+}
+
+// DeleteClientResponse delete clients response
+type DeleteClientResponse struct {
+}
+
+// DeleteClient delete clients of current tenant, issued tokens live until they expire
+func (p *provider) DeleteClient(c context.Context, r *DeleteClientRequest) (*DeleteClientResponse, error) {
+	if err := p.clientRepo.Delete(c, p.DB, r.IDs...); err != nil {
+		return nil, err
+	}
+	return &DeleteClientResponse{}, nil
+}
+
+// ListClientsRequest list clients
+type ListClientsRequest struct {
+	Page  int `json:"page" form:"page"`
+	Limit int `json:"limit" form:"limit"`
+}
+
+// ListClients clients of current tenant
+func (p *provider) ListClients(c context.Context, r *ListClientsRequest) (*page.Page, error) {
+	list, total := p.clientRepo.PageList(c, p.DB, r.Page, r.Limit)
+	if list == nil {
+		list = make([]org.OidcClient, 0)
+	}
+	return &page.Page{
+		Data:       list,
+		TotalCount: total,
+	}, nil
+}
+
+func (p *provider) getClient(c context.Context, id string) *org.OidcClient {
+	client := p.clientRepo.Get(p.DB, id)
+	_, tenantID := ginheader.GetTenantID(c).Wreck()
+	if client == nil || client.TenantID != tenantID {
+		return nil
+	}
+	return client
+}
+
+// matchRedirectURI the registered uri equal to redirectURI, or the only one when it is empty
+func matchRedirectURI(client *org.OidcClient, redirectURI string) (string, bool) {
+	uris := strings.Split(client.RedirectURIs, ",")
+	if redirectURI == "" {
+		return uris[0], len(uris) == 1 && uris[0] != ""
+	}
+	for _, v := range uris {
+		if v == redirectURI {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func validRedirectURIs(uris []string) bool {
+	for _, v := range uris {
+		u, err := url.Parse(v)
+		if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" || strings.Contains(v, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func checkSecret(client *org.OidcClient, secret string) bool {
+	if client.Public == publicClient {
+		return secret == ""
+	}
+	return secret != "" && subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(client.Secret)) == 1
+}
+
+// checkVerifier pkce check of code_verifier against the code_challenge
+func checkVerifier(g *grant, verifier string) bool {
+	if g.CodeChallenge == "" {
+		return verifier == ""
+	}
+	challenge := verifier
+	if g.CodeChallengeMethod == methodS256 {
+		sum := sha256.Sum256([]byte(verifier))
+		challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return verifier != "" && subtle.ConstantTimeCompare([]byte(challenge), []byte(g.CodeChallenge)) == 1
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func hasScope(scope, want string) bool {
+	for _, v := range strings.Fields(scope) {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeQuery parameters of authorization request, handed to the login page
+func authorizeQuery(r *AuthorizeRequest) url.Values {
+	query := url.Values{}
+	for k, v := range map[string]string{
+		"response_type":         r.ResponseType,
+		"client_id":             r.ClientID,
+		"redirect_uri":          r.RedirectURI,
+		"scope":                 r.Scope,
+		"state":                 r.State,
+		"nonce":                 r.Nonce,
+		"code_challenge":        r.CodeChallenge,
+		"code_challenge_method": r.CodeChallengeMethod,
+	} {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	return query
+}
+
+func redirectError(redirectURI, state, errCode string) *AuthorizeResponse {
+	query := url.Values{}
+	query.Set("error", errCode)
+	if state != "" {
+		query.Set("state", state)
+	}
+	return &AuthorizeResponse{RedirectURI: withQuery(redirectURI, query)}
+}
+
+func withQuery(rawURL string, query url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + query.Encode()
+}
@@ -0,0 +1,268 @@
+package oidc
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/account"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/mock"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/jws"
+)
+
+type fakeAccount struct {
+	account.Account
+}
+
+func (f *fakeAccount) CheckPassword(c context.Context, r *account.LoginAccountRequest) (*account.LoginAccountResponse, error) {
+	if r.UserName == "zhangsan@test.com" && r.Password == "654321a.." {
+		return &account.LoginAccountResponse{UserID: "1", UseStatus: consts.NormalStatus}, nil
+	}
+	return nil, error2.New(code.InvalidPWD)
+}
+
+type fakeUser struct {
+	user.User
+}
+
+func (f *fakeUser) OthGetOneUser(c context.Context, r *user.TokenUserRequest) (*user.TokenUserResponse, error) {
+	if r.ID != "1" {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return &user.TokenUserResponse{
+		ID:        "1",
+		Name:      "zhangsan",
+		Email:     "zhangsan@test.com",
+		Phone:     "13800000000",
+		UseStatus: consts.NormalStatus,
+		TenantID:  "t1",
+		DEP: [][]user.DepOneResponse{{
+			{ID: "d2", Name: "dev", PID: "d1"},
+			{ID: "d1", Name: "QCC"},
+		}},
+	}, nil
+}
+
+func newProvider(t *testing.T, clients ...*org.OidcClient) *provider {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mr.Close)
+	signer, err := jws.NewSigner("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientRepo := mock.NewMockOidcClientRepo(gomock.NewController(t))
+	clientRepo.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, id string) *org.OidcClient {
+		for _, v := range clients {
+			if v.ID == id {
+				return v
+			}
+		}
+		return nil
+	}).AnyTimes()
+	conf := configs.Config{}
+	conf.Oidc.Issuer = "http://org.test.com/api/v1/org/oidc/"
+	conf.Oidc.LoginURL = "http://home.test.com/login"
+	return &provider{
+		conf:        conf,
+		redisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		account:     &fakeAccount{},
+		user:        &fakeUser{},
+		clientRepo:  clientRepo,
+		signer:      signer,
+	}
+}
+
+func redirectQuery(t *testing.T, res *AuthorizeResponse) url.Values {
+	u, err := url.Parse(res.RedirectURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
+
+func TestAuthorizeAndToken(t *testing.T) {
+	secret := "s3cret"
+	p := newProvider(t, &org.OidcClient{
+		ID:           "app",
+		RedirectURIs: "http://app.test.com/callback,http://app.test.com/other",
+		Secret:       hashSecret(secret),
+		TenantID:     "t1",
+	})
+	ctx := context.Background()
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	r := &AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "app",
+		RedirectURI:         "http://app.test.com/callback",
+		Scope:               "openid profile email",
+		State:               "xyz",
+		Nonce:               "n-0S6",
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		CodeChallengeMethod: "S256",
+	}
+
+	// no login user, the login page gets the request
+	res, err := p.Authorize(ctx, r)
+	assert.NoError(t, err)
+	assert.Contains(t, res.RedirectURI, "http://home.test.com/login?")
+	assert.Equal(t, "app", redirectQuery(t, res).Get("client_id"))
+
+	r.UserName, r.Password = "zhangsan@test.com", "bad"
+	_, err = p.Authorize(ctx, r)
+	assert.Equal(t, error2.New(code.InvalidPWD), err)
+
+	r.Password = "654321a.."
+	res, err = p.Authorize(ctx, r)
+	assert.NoError(t, err)
+	query := redirectQuery(t, res)
+	assert.Equal(t, "xyz", query.Get("state"))
+	authCode := query.Get("code")
+	assert.NotEmpty(t, authCode)
+
+	token := &TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         authCode,
+		RedirectURI:  "http://app.test.com/callback",
+		ClientID:     "app",
+		ClientSecret: "wrong",
+		CodeVerifier: verifier,
+	}
+	_, err = p.Token(ctx, token)
+	assert.Equal(t, error2.New(code.InvalidClient), err)
+
+	token.ClientSecret = secret
+	tokens, err := p.Token(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+
+	claims := make(map[string]interface{})
+	_, err = p.signer.Verify(tokens.IDToken, &claims)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://org.test.com/api/v1/org/oidc", claims["iss"])
+	assert.Equal(t, "app", claims["aud"])
+	assert.Equal(t, "1", claims["sub"])
+	assert.Equal(t, "t1", claims["tenant_id"])
+	assert.Equal(t, "n-0S6", claims["nonce"])
+	assert.Equal(t, "zhangsan@test.com", claims["email"])
+	assert.Equal(t, jws.HalfHash(tokens.AccessToken), claims["at_hash"])
+	assert.Nil(t, claims["phone_number"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": "d2", "name": "dev", "path": "QCC/dev"}}, claims["departments"])
+
+	info, err := p.UserInfo(ctx, &UserInfoRequest{AccessToken: tokens.AccessToken})
+	assert.NoError(t, err)
+	assert.Equal(t, "zhangsan", info["name"])
+	_, err = p.UserInfo(ctx, &UserInfoRequest{AccessToken: tokens.IDToken})
+	assert.Equal(t, error2.New(code.InvalidAccessToken), err)
+
+	// a code is used once
+	_, err = p.Token(ctx, token)
+	assert.Equal(t, error2.New(code.InvalidGrant), err)
+}
+
+func TestTokenPKCE(t *testing.T) {
+	p := newProvider(t, &org.OidcClient{
+		ID:           "spa",
+		RedirectURIs: "http://spa.test.com/callback",
+		Public:       publicClient,
+		TenantID:     "t1",
+	})
+	ctx := context.Background()
+	r := &AuthorizeRequest{
+		ResponseType: "code",
+		ClientID:     "spa",
+		Scope:        "openid",
+		UserName:     "zhangsan@test.com",
+		Password:     "654321a..",
+	}
+	// public clients must send a challenge
+	res, err := p.Authorize(ctx, r)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid_request", redirectQuery(t, res).Get("error"))
+
+	r.CodeChallenge = "plain-verifier-plain-verifier-plain-verifier"
+	res, err = p.Authorize(ctx, r)
+	assert.NoError(t, err)
+	token := &TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         redirectQuery(t, res).Get("code"),
+		ClientID:     "spa",
+		CodeVerifier: "another-verifier",
+	}
+	_, err = p.Token(ctx, token)
+	assert.Equal(t, error2.New(code.InvalidGrant), err)
+
+	res, err = p.Authorize(ctx, r)
+	assert.NoError(t, err)
+	token.Code = redirectQuery(t, res).Get("code")
+	token.CodeVerifier = r.CodeChallenge
+	_, err = p.Token(ctx, token)
+	assert.NoError(t, err)
+}
+
+func TestAuthorizeErrors(t *testing.T) {
+	p := newProvider(t, &org.OidcClient{
+		ID:           "app",
+		RedirectURIs: "http://app.test.com/callback",
+		TenantID:     "t2",
+	})
+	ctx := context.Background()
+	_, err := p.Authorize(ctx, &AuthorizeRequest{ClientID: "none"})
+	assert.Equal(t, error2.New(code.InvalidClient), err)
+
+	_, err = p.Authorize(ctx, &AuthorizeRequest{ClientID: "app", RedirectURI: "http://evil.test.com/callback"})
+	assert.Equal(t, error2.New(code.InvalidRedirectURI), err)
+
+	res, err := p.Authorize(ctx, &AuthorizeRequest{ResponseType: "token", ClientID: "app", State: "s"})
+	assert.NoError(t, err)
+	assert.Equal(t, "unsupported_response_type", redirectQuery(t, res).Get("error"))
+	assert.Equal(t, "s", redirectQuery(t, res).Get("state"))
+
+	res, err = p.Authorize(ctx, &AuthorizeRequest{ResponseType: "code", ClientID: "app", Scope: "profile"})
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid_scope", redirectQuery(t, res).Get("error"))
+
+	res, err = p.Authorize(ctx, &AuthorizeRequest{ResponseType: "code", ClientID: "app", Scope: "openid", Prompt: "none"})
+	assert.NoError(t, err)
+	assert.Equal(t, "login_required", redirectQuery(t, res).Get("error"))
+
+	// users of another tenant
+	res, err = p.Authorize(ctx, &AuthorizeRequest{ResponseType: "code", ClientID: "app", Scope: "openid", UserName: "zhangsan@test.com", Password: "654321a.."})
+	assert.NoError(t, err)
+	assert.Equal(t, "access_denied", redirectQuery(t, res).Get("error"))
+}
+
+func TestValidRedirectURIs(t *testing.T) {
+	assert.True(t, validRedirectURIs([]string{"https://app.test.com/cb?a=1", "com.test.app://host/cb"}))
+	assert.False(t, validRedirectURIs([]string{"/cb"}))
+	assert.False(t, validRedirectURIs([]string{"https://app.test.com/cb#x"}))
+	assert.False(t, validRedirectURIs([]string{"https://app.test.com/a,b"}))
+}
@@ -62,6 +62,7 @@ type othersServer struct {
 	conf           configs.Config
 	userLeaderRepo org.UserLeaderRelationRepo
 	search         *user.Search
+	hasher         encode2.Hasher
 }
 
 // NewOtherServer 实例
@@ -79,6 +80,7 @@ func NewOtherServer(conf configs.Config, db *gorm.DB, redisClient redis.Universa
 		conf:           conf,
 		userLeaderRepo: mysql2.NewUserLeaderRelationRepo(),
 		search:         user.GetSearch(),
+		hasher:         encode2.NewHasher(conf.PasswordHash),
 	}
 }
 
@@ -364,14 +366,19 @@ func (u *othersServer) addUserOrUpdate(c context.Context, reqData []AddUser, isU
 			pwd := random2.RandomString(int(info.PwdMinLen), info.PwdType)
 			if u.conf.Model == "debug" {
 				pwd = "654321a.."
-				account.Password = encode2.MD5Encode(pwd)
-			} else {
-				account.Password = encode2.MD5Encode(pwd)
 			}
+			password, err := u.hasher.Hash(pwd)
+			if err != nil {
+				tx.Rollback()
+				result[k].Attr = fail
+				return nil, err
+			}
+			account.Password = password
+			account.PasswordChangedAt = nowUnix
 			account.CreatedBy = profile.UserID
 			account.CreatedAt = nowUnix
 			account.UpdatedAt = nowUnix
-			err := u.insertUser(c, tx, u2, account)
+			err = u.insertUser(c, tx, u2, account)
 			if err != nil {
 				tx.Rollback()
 				result[k].Attr = fail
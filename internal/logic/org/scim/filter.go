@@ -0,0 +1,468 @@
+package scim
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+)
+
+// Filter parsed scim filter, rfc7644 3.4.2.2
+type Filter interface {
+	Match(resource map[string]interface{}) bool
+}
+
+// ParseFilter parse filter, empty filter matches all
+func ParseFilter(filter string) (Filter, error) {
+	if strings.TrimSpace(filter) == "" {
+		return matchAll{}, nil
+	}
+	p, err := newParser(filter)
+	if err != nil {
+		return nil, err
+	}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.end() {
+		return nil, error2.New(code.InvalidFilter)
+	}
+	return f, nil
+}
+
+type matchAll struct{}
+
+func (matchAll) Match(map[string]interface{}) bool { return true }
+
+type andFilter struct{ left, right Filter }
+
+func (f andFilter) Match(r map[string]interface{}) bool { return f.left.Match(r) && f.right.Match(r) }
+
+type orFilter struct{ left, right Filter }
+
+func (f orFilter) Match(r map[string]interface{}) bool { return f.left.Match(r) || f.right.Match(r) }
+
+type notFilter struct{ filter Filter }
+
+func (f notFilter) Match(r map[string]interface{}) bool { return !f.filter.Match(r) }
+
+// valuePathFilter emails[type eq "work"]
+type valuePathFilter struct {
+	path   Path
+	filter Filter
+}
+
+func (f valuePathFilter) Match(r map[string]interface{}) bool {
+	for _, v := range f.path.elements(r) {
+		if m, ok := v.(map[string]interface{}); ok && f.filter.Match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+type compareFilter struct {
+	path  Path
+	op    string
+	value interface{}
+}
+
+func (f compareFilter) Match(r map[string]interface{}) bool {
+	values := f.path.values(r)
+	if f.op == "pr" {
+		for _, v := range values {
+			if v != nil && v != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if f.op == "ne" {
+		return !compareFilter{path: f.path, op: "eq", value: f.value}.Match(r)
+	}
+	for _, v := range values {
+		if compare(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(attr interface{}, op string, value interface{}) bool {
+	switch want := value.(type) {
+	case string:
+		got, ok := attr.(string)
+		if !ok {
+			return false
+		}
+		got, want = strings.ToLower(got), strings.ToLower(want)
+		switch op {
+		case "eq":
+			return got == want
+		case "co":
+			return strings.Contains(got, want)
+		case "sw":
+			return strings.HasPrefix(got, want)
+		case "ew":
+			return strings.HasSuffix(got, want)
+		case "gt":
+			return got > want
+		case "ge":
+			return got >= want
+		case "lt":
+			return got < want
+		case "le":
+			return got <= want
+		}
+	case float64:
+		got, ok := attr.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return got == want
+		case "gt":
+			return got > want
+		case "ge":
+			return got >= want
+		case "lt":
+			return got < want
+		case "le":
+			return got <= want
+		}
+	case bool:
+		return op == "eq" && toBool(attr) == want && attr != nil
+	case nil:
+		return op == "eq" && attr == nil
+	}
+	return false
+}
+
+// Path attribute path of filters and patch operations, e.g.
+// name.givenName, emails[type eq "work"].value,
+// urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value
+type Path struct {
+	URN    string
+	Attr   string
+	Filter Filter
+	Sub    string
+}
+
+// ParsePath parse patch path
+func ParsePath(path string) (Path, error) {
+	p, err := newParser(path)
+	if err != nil {
+		return Path{}, err
+	}
+	if p.peek() == "" {
+		return Path{}, error2.New(code.InvalidPatch)
+	}
+	res, err := splitPath(p.next())
+	if err != nil {
+		return Path{}, err
+	}
+	if p.peek() == "[" {
+		p.next()
+		if res.Filter, err = p.or(); err != nil {
+			return Path{}, err
+		}
+		if p.next() != "]" {
+			return Path{}, error2.New(code.InvalidPatch)
+		}
+		if sub := p.peek(); strings.HasPrefix(sub, ".") {
+			p.next()
+			res.Sub = sub[1:]
+		}
+	}
+	if !p.end() {
+		return Path{}, error2.New(code.InvalidPatch)
+	}
+	return res, nil
+}
+
+func splitPath(token string) (Path, error) {
+	path := Path{}
+	if strings.HasPrefix(strings.ToLower(token), "urn:") {
+		i := strings.LastIndex(token, ":")
+		path.URN, token = token[:i], token[i+1:]
+		if isCoreSchema(path.URN) {
+			path.URN = ""
+		}
+	}
+	if i := strings.Index(token, "."); i >= 0 {
+		path.Attr, path.Sub = token[:i], token[i+1:]
+	} else {
+		path.Attr = token
+	}
+	if path.Attr == "" {
+		return path, error2.New(code.InvalidFilter)
+	}
+	return path, nil
+}
+
+// container the object holding the attribute, the extension for urn paths
+func (p Path) container(r map[string]interface{}) map[string]interface{} {
+	if p.URN == "" {
+		return r
+	}
+	ext, _ := get(r, p.URN).(map[string]interface{})
+	return ext
+}
+
+// elements values of the attribute, multi-valued attributes are flattened
+func (p Path) elements(r map[string]interface{}) []interface{} {
+	c := p.container(r)
+	if c == nil {
+		return nil
+	}
+	v := get(c, p.Attr)
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	if v == nil {
+		return nil
+	}
+	return []interface{}{v}
+}
+
+// values compared values, complex multi-valued attributes compare their value
+func (p Path) values(r map[string]interface{}) []interface{} {
+	res := make([]interface{}, 0)
+	for _, v := range p.elements(r) {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			res = append(res, v)
+			continue
+		}
+		if p.Sub != "" {
+			res = append(res, get(m, p.Sub))
+		} else {
+			res = append(res, get(m, "value"))
+		}
+	}
+	return res
+}
+
+// get attribute, names are case insensitive
+func get(m map[string]interface{}, name string) interface{} {
+	if v, ok := m[name]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// set attribute, keeping the existing spelling of the name
+func set(m map[string]interface{}, name string, value interface{}) {
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			m[k] = value
+			return
+		}
+	}
+	m[name] = value
+}
+
+// del attribute
+func del(m map[string]interface{}, name string) {
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			delete(m, k)
+		}
+	}
+}
+
+func toBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return strings.EqualFold(b, "true")
+	}
+	return false
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func newParser(s string) (*parser, error) {
+	tokens := make([]string, 0)
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, error2.New(code.InvalidFilter)
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(s) && !strings.ContainsRune(" \t()[]\"", rune(s[j])); j++ {
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return &parser{tokens: tokens}, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) end() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) or() (Filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Filter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Filter, error) {
+	token := p.next()
+	switch {
+	case strings.EqualFold(token, "not"):
+		if p.next() != "(" {
+			return nil, error2.New(code.InvalidFilter)
+		}
+		f, err := p.group(")")
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{filter: f}, nil
+	case token == "(":
+		return p.group(")")
+	case token == "" || strings.ContainsAny(token, "()[]\""):
+		return nil, error2.New(code.InvalidFilter)
+	}
+	path, err := splitPath(token)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() == "[" {
+		p.next()
+		f, err := p.group("]")
+		if err != nil {
+			return nil, err
+		}
+		return valuePathFilter{path: path, filter: f}, nil
+	}
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return compareFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, error2.New(code.InvalidFilter)
+	}
+	value, err := compValue(p.next())
+	if err != nil {
+		return nil, err
+	}
+	return compareFilter{path: path, op: op, value: value}, nil
+}
+
+func (p *parser) group(closing string) (Filter, error) {
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.next() != closing {
+		return nil, error2.New(code.InvalidFilter)
+	}
+	return f, nil
+}
+
+func compValue(token string) (interface{}, error) {
+	switch strings.ToLower(token) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if strings.HasPrefix(token, "\"") {
+		var s string
+		if err := json.Unmarshal([]byte(token), &s); err != nil {
+			return nil, error2.New(code.InvalidFilter)
+		}
+		return s, nil
+	}
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, error2.New(code.InvalidFilter)
+	}
+	return f, nil
+}
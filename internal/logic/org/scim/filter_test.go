@@ -0,0 +1,112 @@
+package scim
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resource(t *testing.T, data string) map[string]interface{} {
+	res := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(data), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+const zhangsan = `{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+	"id": "1",
+	"userName": "zhangsan@test.com",
+	"displayName": "Zhang San",
+	"title": "dev",
+	"active": true,
+	"emails": [
+		{"value": "zhangsan@test.com", "type": "work", "primary": true},
+		{"value": "zs@home.com", "type": "home"}
+	],
+	"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "1001"},
+	"meta": {"created": "2022-01-01T00:00:00Z"}
+}`
+
+func TestParseFilter(t *testing.T) {
+	r := resource(t, zhangsan)
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{``, true},
+		{`userName eq "ZHANGSAN@test.com"`, true},
+		{`userName eq "lisi@test.com"`, false},
+		{`userName ne "lisi@test.com"`, true},
+		{`displayName co "san"`, true},
+		{`displayName sw "Zhang"`, true},
+		{`displayName ew "Zhang"`, false},
+		{`title pr`, true},
+		{`externalId pr`, false},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`meta.created gt "2021-12-31T00:00:00Z"`, true},
+		{`meta.created lt "2021-12-31T00:00:00Z"`, false},
+		{`emails.value eq "zs@home.com"`, true},
+		{`emails[type eq "work" and value co "zhangsan"]`, true},
+		{`emails[type eq "work" and value co "zs"]`, false},
+		{`title eq "dev" and not (active eq false)`, true},
+		{`title eq "ops" or displayName sw "zhang"`, true},
+		{`(title eq "ops" or title eq "qa") and active eq true`, false},
+		{`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "1001"`, true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.filter)
+		if !assert.NoError(t, err, tt.filter) {
+			continue
+		}
+		assert.Equal(t, tt.match, f.Match(r), tt.filter)
+	}
+
+	for _, filter := range []string{
+		`userName eq`,
+		`userName xx "a"`,
+		`(userName eq "a"`,
+		`emails[type eq "work"`,
+		`userName eq "a" and`,
+		`userName eq "unterminated`,
+	} {
+		_, err := ParseFilter(filter)
+		assert.Error(t, err, filter)
+	}
+}
+
+func TestAttrEq(t *testing.T) {
+	f, err := ParseFilter(`userName eq "zhangsan@test.com"`)
+	assert.NoError(t, err)
+	userName, ok := attrEq(f, "userName")
+	assert.True(t, ok)
+	assert.Equal(t, "zhangsan@test.com", userName)
+	_, ok = attrEq(f, "displayName")
+	assert.False(t, ok)
+
+	f, err = ParseFilter(`displayName eq "研发部"`)
+	assert.NoError(t, err)
+	name, ok := attrEq(f, "displayName")
+	assert.True(t, ok)
+	assert.Equal(t, "研发部", name)
+
+	f, err = ParseFilter(`userName eq "a" or userName eq "b"`)
+	assert.NoError(t, err)
+	_, ok = attrEq(f, "userName")
+	assert.False(t, ok)
+}
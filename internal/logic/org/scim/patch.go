@@ -0,0 +1,241 @@
+package scim
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"fmt"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+)
+
+const (
+	opAdd     = "add"
+	opReplace = "replace"
+	opRemove  = "remove"
+)
+
+// PatchRequest patch request, rfc7644 3.5.2
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation patch operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// Apply apply operations to the resource in order
+func Apply(resource map[string]interface{}, operations []PatchOperation) error {
+	for _, v := range operations {
+		if err := apply(resource, strings.ToLower(v.Op), v.Path, v.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(resource map[string]interface{}, op, path string, value interface{}) error {
+	if op != opAdd && op != opReplace && op != opRemove {
+		return error2.New(code.InvalidPatch)
+	}
+	if path == "" {
+		// the value holds attribute paths and their values
+		values, ok := value.(map[string]interface{})
+		if !ok || op == opRemove {
+			return error2.New(code.InvalidPatch)
+		}
+		for k, v := range values {
+			if ext, ok := v.(map[string]interface{}); ok && isExtensionSchema(k) {
+				for k1, v1 := range ext {
+					if err := apply(resource, op, k+":"+k1, v1); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := apply(resource, op, k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	p, err := ParsePath(path)
+	if err != nil {
+		return error2.New(code.InvalidPatch)
+	}
+	container := p.container(resource)
+	if container == nil {
+		if op == opRemove {
+			return nil
+		}
+		container = make(map[string]interface{})
+		set(resource, p.URN, container)
+	}
+	if p.Filter != nil {
+		return applyFiltered(container, op, p, value)
+	}
+
+	current := get(container, p.Attr)
+	if p.Sub != "" {
+		switch attr := current.(type) {
+		case map[string]interface{}:
+			setOrDel(attr, op, p.Sub, value)
+		case []interface{}:
+			for _, v := range attr {
+				if m, ok := v.(map[string]interface{}); ok {
+					setOrDel(m, op, p.Sub, value)
+				}
+			}
+		default:
+			if op != opRemove {
+				set(container, p.Attr, map[string]interface{}{p.Sub: value})
+			}
+		}
+		return nil
+	}
+
+	list, multi := current.([]interface{})
+	switch op {
+	case opAdd:
+		if multi {
+			set(container, p.Attr, append(list, asList(value)...))
+			return nil
+		}
+		set(container, p.Attr, value)
+	case opReplace:
+		set(container, p.Attr, value)
+	case opRemove:
+		if multi && value != nil {
+			// remove the listed members, as sent by some providers
+			set(container, p.Attr, without(list, asList(value)))
+			return nil
+		}
+		del(container, p.Attr)
+	}
+	return nil
+}
+
+// applyFiltered emails[type eq "work"].value
+func applyFiltered(container map[string]interface{}, op string, p Path, value interface{}) error {
+	list, _ := get(container, p.Attr).([]interface{})
+	res := make([]interface{}, 0, len(list))
+	matched := false
+	for _, v := range list {
+		m, ok := v.(map[string]interface{})
+		if !ok || !p.Filter.Match(m) {
+			res = append(res, v)
+			continue
+		}
+		matched = true
+		switch {
+		case op == opRemove && p.Sub == "":
+			continue
+		case p.Sub != "":
+			setOrDel(m, op, p.Sub, value)
+		default:
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return error2.New(code.InvalidPatch)
+			}
+			for k1, v1 := range values {
+				set(m, k1, v1)
+			}
+		}
+		res = append(res, m)
+	}
+	if !matched && op != opRemove {
+		// create the element the filter describes
+		elem, ok := filterElement(p.Filter)
+		if !ok {
+			return error2.New(code.InvalidPatch)
+		}
+		if p.Sub != "" {
+			set(elem, p.Sub, value)
+		} else if values, ok := value.(map[string]interface{}); ok {
+			for k1, v1 := range values {
+				set(elem, k1, v1)
+			}
+		}
+		res = append(res, elem)
+	}
+	set(container, p.Attr, res)
+	return nil
+}
+
+func filterElement(f Filter) (map[string]interface{}, bool) {
+	switch v := f.(type) {
+	case compareFilter:
+		if v.op != "eq" || v.path.URN != "" || v.path.Sub != "" {
+			return nil, false
+		}
+		return map[string]interface{}{v.path.Attr: v.value}, true
+	case andFilter:
+		left, ok := filterElement(v.left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := filterElement(v.right)
+		if !ok {
+			return nil, false
+		}
+		for k, v1 := range right {
+			left[k] = v1
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+func setOrDel(m map[string]interface{}, op, name string, value interface{}) {
+	if op == opRemove {
+		del(m, name)
+		return
+	}
+	set(m, name, value)
+}
+
+func asList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+// without elements of list whose value is in remove
+func without(list, remove []interface{}) []interface{} {
+	values := make(map[string]struct{}, len(remove))
+	for _, v := range remove {
+		values[elementValue(v)] = struct{}{}
+	}
+	res := make([]interface{}, 0, len(list))
+	for _, v := range list {
+		if _, ok := values[elementValue(v)]; !ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func elementValue(v interface{}) string {
+	if m, ok := v.(map[string]interface{}); ok {
+		v = get(m, "value")
+	}
+	return fmt.Sprint(v)
+}
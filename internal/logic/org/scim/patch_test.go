@@ -0,0 +1,109 @@
+package scim
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func patch(t *testing.T, data string) []PatchOperation {
+	r := new(PatchRequest)
+	if err := json.Unmarshal([]byte(data), r); err != nil {
+		t.Fatal(err)
+	}
+	return r.Operations
+}
+
+func TestApply(t *testing.T) {
+	r := resource(t, zhangsan)
+	err := Apply(r, patch(t, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "san@test.com"},
+			{"op": "add", "path": "phoneNumbers[type eq \"mobile\"].value", "value": "13800000000"},
+			{"op": "remove", "path": "title"},
+			{"op": "add", "value": {
+				"displayName": "San",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber": "1002"
+			}}
+		]
+	}`))
+	assert.NoError(t, err)
+
+	one := new(User)
+	assert.NoError(t, fromMap(r, one))
+	assert.False(t, bool(*one.Active))
+	assert.Equal(t, "San", one.DisplayName)
+	assert.Empty(t, one.Title)
+	assert.Equal(t, "san@test.com", primary(one.Emails))
+	assert.Equal(t, "13800000000", primary(one.PhoneNumbers))
+	assert.Equal(t, "mobile", one.PhoneNumbers[0].Type)
+	assert.Equal(t, "1002", one.Enterprise.EmployeeNumber)
+}
+
+func TestApplyMembers(t *testing.T) {
+	r := resource(t, `{"displayName": "dev", "members": [{"value": "1"}, {"value": "2"}]}`)
+	err := Apply(r, patch(t, `{"Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "3"}]},
+		{"op": "remove", "path": "members[value eq \"1\"]"},
+		{"op": "remove", "path": "members", "value": [{"value": "2"}]}
+	]}`))
+	assert.NoError(t, err)
+
+	one := new(Group)
+	assert.NoError(t, fromMap(r, one))
+	assert.Equal(t, []Member{{Value: "3"}}, one.Members)
+
+	err = Apply(r, patch(t, `{"Operations": [{"op": "remove", "path": "members"}]}`))
+	assert.NoError(t, err)
+	one = new(Group)
+	assert.NoError(t, fromMap(r, one))
+	assert.Empty(t, one.Members)
+}
+
+func TestApplyInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"Operations": [{"op": "move", "path": "title", "value": "x"}]}`,
+		`{"Operations": [{"op": "remove"}]}`,
+		`{"Operations": [{"op": "replace", "path": "emails[type eq", "value": "x"}]}`,
+	} {
+		r := resource(t, zhangsan)
+		assert.Error(t, Apply(r, patch(t, data)), data)
+	}
+}
+
+func TestPage(t *testing.T) {
+	resources := []interface{}{1, 2, 3, 4, 5}
+	count := 2
+	start, n := pageRange(&ListRequest{StartIndex: 2, Count: &count})
+	assert.Equal(t, 2, start)
+	assert.Equal(t, 2, n)
+	res := page(resources, start, n)
+	assert.Equal(t, 5, res.TotalResults)
+	assert.Equal(t, 2, res.StartIndex)
+	assert.Equal(t, 2, res.ItemsPerPage)
+	assert.Equal(t, []interface{}{2, 3}, res.Resources)
+
+	start, n = pageRange(&ListRequest{StartIndex: 9})
+	assert.Equal(t, maxResults, n)
+	res = page(resources, start, n)
+	assert.Equal(t, 0, res.ItemsPerPage)
+	assert.Empty(t, res.Resources)
+
+	start, _ = pageRange(&ListRequest{})
+	assert.Equal(t, 1, start)
+}
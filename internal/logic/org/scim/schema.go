@@ -0,0 +1,238 @@
+package scim
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"encoding/json"
+	"strings"
+)
+
+// scim schema urns
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaEnterpriseUser        = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+func isCoreSchema(urn string) bool {
+	return strings.EqualFold(urn, SchemaUser) || strings.EqualFold(urn, SchemaGroup)
+}
+
+func isExtensionSchema(urn string) bool {
+	return strings.EqualFold(urn, SchemaEnterpriseUser)
+}
+
+// Bool boolean also accepting "True" and "False", as sent by some providers
+type Bool bool
+
+// UnmarshalJSON unmarshal
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*b = Bool(toBool(v))
+	return nil
+}
+
+// User user resource
+type User struct {
+	Schemas      []string        `json:"schemas"`
+	ID           string          `json:"id,omitempty"`
+	ExternalID   string          `json:"externalId,omitempty"`
+	UserName     string          `json:"userName"`
+	Name         *Name           `json:"name,omitempty"`
+	DisplayName  string          `json:"displayName,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Active       *Bool           `json:"active,omitempty"`
+	Emails       []MultiValue    `json:"emails,omitempty"`
+	PhoneNumbers []MultiValue    `json:"phoneNumbers,omitempty"`
+	Groups       []Member        `json:"groups,omitempty"`
+	Enterprise   *EnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta         *Meta           `json:"meta,omitempty"`
+}
+
+// Name user name
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// MultiValue emails and phone numbers
+type MultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary Bool   `json:"primary,omitempty"`
+}
+
+// Member group member or group of user
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// EnterpriseUser enterprise user extension
+type EnterpriseUser struct {
+	EmployeeNumber string   `json:"employeeNumber,omitempty"`
+	Manager        *Manager `json:"manager,omitempty"`
+}
+
+// Manager manager of user
+type Manager struct {
+	Value       string `json:"value,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// Meta resource meta
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+// Group group resource, it is a department
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// ListResponse list response
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// ServiceProviderConfig features supported
+func ServiceProviderConfig() map[string]interface{} {
+	supported := func(ok bool) map[string]interface{} {
+		return map[string]interface{}{"supported": ok}
+	}
+	return map[string]interface{}{
+		"schemas":        []string{SchemaServiceProviderConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxResults},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication scheme using the OAuth Bearer Token Standard",
+			"primary":     true,
+		}},
+		"meta": map[string]interface{}{"resourceType": "ServiceProviderConfig"},
+	}
+}
+
+type attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []attribute `json:"subAttributes,omitempty"`
+}
+
+func attr(name, typ string, subs ...attribute) attribute {
+	return attribute{Name: name, Type: typ, Mutability: "readWrite", Returned: "default", Uniqueness: "none", SubAttributes: subs}
+}
+
+func multi(a attribute) attribute {
+	a.MultiValued = true
+	return a
+}
+
+func readOnly(a attribute) attribute {
+	a.Mutability = "readOnly"
+	return a
+}
+
+// Schemas schemas of the resources
+func Schemas() []map[string]interface{} {
+	userName := attr("userName", "string")
+	userName.Required = true
+	userName.Uniqueness = "server"
+	value := attr("value", "string")
+	return []map[string]interface{}{
+		schema(SchemaUser, "User", userName,
+			attr("name", "complex", attr("formatted", "string"), attr("familyName", "string"), attr("givenName", "string")),
+			attr("displayName", "string"),
+			attr("title", "string"),
+			attr("active", "boolean"),
+			multi(attr("emails", "complex", value, attr("type", "string"), attr("primary", "boolean"))),
+			multi(attr("phoneNumbers", "complex", value, attr("type", "string"), attr("primary", "boolean"))),
+			readOnly(multi(attr("groups", "complex", value, attr("display", "string")))),
+		),
+		schema(SchemaEnterpriseUser, "EnterpriseUser",
+			attr("employeeNumber", "string"),
+			attr("manager", "complex", value, readOnly(attr("displayName", "string"))),
+		),
+		schema(SchemaGroup, "Group",
+			attr("displayName", "string"),
+			multi(attr("members", "complex", value, readOnly(attr("display", "string")))),
+		),
+	}
+}
+
+func schema(id, name string, attrs ...attribute) map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":    []string{SchemaSchema},
+		"id":         id,
+		"name":       name,
+		"attributes": attrs,
+		"meta":       map[string]interface{}{"resourceType": "Schema"},
+	}
+}
+
+// ResourceTypes resource types
+func ResourceTypes() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"schemas":          []string{SchemaResourceType},
+			"id":               "User",
+			"name":             "User",
+			"endpoint":         "/Users",
+			"schema":           SchemaUser,
+			"schemaExtensions": []map[string]interface{}{{"schema": SchemaEnterpriseUser, "required": false}},
+			"meta":             map[string]interface{}{"resourceType": "ResourceType"},
+		},
+		{
+			"schemas":  []string{SchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   SchemaGroup,
+			"meta":     map[string]interface{}{"resourceType": "ResourceType"},
+		},
+	}
+}
@@ -0,0 +1,745 @@
+package scim
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/department"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/verification"
+)
+
+const (
+	source = "scim"
+	// maxResults most resources returned in one page
+	maxResults = 1000
+	// maxRecords most resources matched in memory, a tenant with more rejects those filters
+	maxRecords = 100000
+)
+
+// Scim scim 2.0 provisioning, users map to users and groups to departments
+type Scim interface {
+	ListUsers(c context.Context, r *ListRequest) (*ListResponse, error)
+	GetUser(c context.Context, r *GetRequest) (*UserResponse, error)
+	CreateUser(c context.Context, r *User) (*UserResponse, error)
+	ReplaceUser(c context.Context, r *ReplaceUserRequest) (*UserResponse, error)
+	PatchUser(c context.Context, r *PatchResourceRequest) (*UserResponse, error)
+	DeleteUser(c context.Context, r *GetRequest) (*UserResponse, error)
+
+	ListGroups(c context.Context, r *ListRequest) (*ListResponse, error)
+	GetGroup(c context.Context, r *GetRequest) (*GroupResponse, error)
+	CreateGroup(c context.Context, r *Group) (*GroupResponse, error)
+	ReplaceGroup(c context.Context, r *ReplaceGroupRequest) (*GroupResponse, error)
+	PatchGroup(c context.Context, r *PatchResourceRequest) (*GroupResponse, error)
+	DeleteGroup(c context.Context, r *GetRequest) (*GroupResponse, error)
+}
+
+type scim struct {
+	DB             *gorm.DB
+	conf           configs.Config
+	redisClient    redis.UniversalClient
+	user           user.User
+	dep            department.Department
+	userRepo       org.UserRepo
+	accountRepo    org.AccountRepo
+	depRepo        org.DepartmentRepo
+	userDepRepo    org.UserDepartmentRelationRepo
+	userLeaderRepo org.UserLeaderRelationRepo
+}
+
+// NewScim new
+func NewScim(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient) Scim {
+	return &scim{
+		DB:             db,
+		conf:           conf,
+		redisClient:    redisClient,
+		user:           user.NewUser(conf, db, redisClient),
+		dep:            department.NewDepartment(db),
+		userRepo:       mysql2.NewUserRepo(),
+		accountRepo:    mysql2.NewAccountRepo(),
+		depRepo:        mysql2.NewDepartmentRepo(),
+		userDepRepo:    mysql2.NewUserDepartmentRelationRepo(),
+		userLeaderRepo: mysql2.NewUserLeaderRelationRepo(),
+	}
+}
+
+// ListRequest list request
+type ListRequest struct {
+	Filter             string `form:"filter"`
+	StartIndex         int    `form:"startIndex"`
+	Count              *int   `form:"count"`
+	ExcludedAttributes string `form:"excludedAttributes"`
+}
+
+// GetRequest one resource
+type GetRequest struct {
+	ID string
+}
+
+// ReplaceUserRequest put user
+type ReplaceUserRequest struct {
+	ID   string
+	User *User
+}
+
+// ReplaceGroupRequest put group
+type ReplaceGroupRequest struct {
+	ID    string
+	Group *Group
+}
+
+// PatchResourceRequest patch user or group
+type PatchResourceRequest struct {
+	ID    string
+	Patch *PatchRequest
+}
+
+// UserResponse user resource, Changed are users to push to search
+type UserResponse struct {
+	User    *User
+	Changed []*org.User
+}
+
+// GroupResponse group resource, Changed are users to push to search
+type GroupResponse struct {
+	Group   *Group
+	Changed []*org.User
+}
+
+// ListUsers list users
+func (s *scim) ListUsers(c context.Context, r *ListRequest) (*ListResponse, error) {
+	filter, err := ParseFilter(r.Filter)
+	if err != nil {
+		return nil, err
+	}
+	start, count := pageRange(r)
+	if _, ok := filter.(matchAll); ok {
+		list, total := s.userRepo.SelectByOffset(c, s.DB, consts.DelStatus, start-1, count)
+		resources := make([]interface{}, 0, len(list))
+		for _, v := range s.toUsers(c, list...) {
+			resources = append(resources, v)
+		}
+		return listResponse(resources, int(total), start), nil
+	}
+	var list []*org.User
+	if userName, ok := attrEq(filter, "userName"); ok {
+		// the usual lookup of provisioning clients
+		if acc := s.accountRepo.SelectByAccount(s.DB, userName); acc != nil {
+			if one := s.getUser(c, acc.UserID); one != nil {
+				list = append(list, one)
+			}
+		}
+	} else {
+		// other filters are matched against the scim representation
+		all, total := s.userRepo.SelectByOffset(c, s.DB, consts.DelStatus, 0, maxRecords)
+		if total > maxRecords {
+			return nil, error2.New(code.TooManyResults)
+		}
+		list = all
+	}
+	resources := make([]interface{}, 0)
+	for _, v := range s.toUsers(c, list...) {
+		if filter.Match(toMap(v)) {
+			resources = append(resources, v)
+		}
+	}
+	return page(resources, start, count), nil
+}
+
+// GetUser get user
+func (s *scim) GetUser(c context.Context, r *GetRequest) (*UserResponse, error) {
+	one := s.getUser(c, r.ID)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return &UserResponse{User: s.toUsers(c, one)[0]}, nil
+}
+
+// CreateUser create user
+func (s *scim) CreateUser(c context.Context, r *User) (*UserResponse, error) {
+	f, err := s.fields(c, r)
+	if err != nil {
+		return nil, err
+	}
+	add := &user.AddUserRequest{
+		Name:      f.name,
+		Phone:     f.phone,
+		Email:     f.email,
+		Position:  f.position,
+		JobNumber: f.jobNumber,
+		UseStatus: f.status,
+		Source:    source,
+		Password:  user.CreatePassword(c, s.conf, s.redisClient),
+	}
+	if f.managerID != "" {
+		add.Leader = []user.LeaderRequest{{UserID: f.managerID}}
+	}
+	res, err := s.user.Add(c, add)
+	if err != nil {
+		return nil, err
+	}
+	return s.userResponse(c, res.ID)
+}
+
+// ReplaceUser put user
+func (s *scim) ReplaceUser(c context.Context, r *ReplaceUserRequest) (*UserResponse, error) {
+	old := s.getUser(c, r.ID)
+	if old == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return s.replaceUser(c, old, r.User)
+}
+
+// PatchUser patch user
+func (s *scim) PatchUser(c context.Context, r *PatchResourceRequest) (*UserResponse, error) {
+	old := s.getUser(c, r.ID)
+	if old == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	resource := toMap(s.toUsers(c, old)[0])
+	if err := Apply(resource, r.Patch.Operations); err != nil {
+		return nil, err
+	}
+	patched := new(User)
+	if err := fromMap(resource, patched); err != nil {
+		return nil, err
+	}
+	return s.replaceUser(c, old, patched)
+}
+
+// DeleteUser delete user
+func (s *scim) DeleteUser(c context.Context, r *GetRequest) (*UserResponse, error) {
+	if s.getUser(c, r.ID) == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	res, err := s.user.UpdateUserStatus(c, &user.StatusRequest{
+		ID:        r.ID,
+		UseStatus: consts.DelStatus,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UserResponse{Changed: []*org.User{res.User}}, nil
+}
+
+func (s *scim) replaceUser(c context.Context, old *org.User, r *User) (*UserResponse, error) {
+	f, err := s.fields(c, r)
+	if err != nil {
+		return nil, err
+	}
+	if f.email != old.Email && s.accountRepo.SelectByAccount(s.DB, f.email) != nil {
+		return nil, error2.New(code.AccountExist)
+	}
+	update := &user.UpdateUserRequest{
+		ID:        old.ID,
+		Name:      f.name,
+		Phone:     f.phone,
+		Email:     f.email,
+		Position:  f.position,
+		JobNumber: f.jobNumber,
+		UseStatus: old.UseStatus,
+		Source:    old.Source,
+	}
+	if f.managerID != "" {
+		update.Leader = []user.LeaderRequest{{UserID: f.managerID}}
+	}
+	if _, err = s.user.Update(c, update); err != nil {
+		return nil, err
+	}
+	if f.managerID == "" && len(s.userLeaderRepo.SelectByUserIDs(s.DB, old.ID)) > 0 {
+		if err = s.userLeaderRepo.DeleteByUserIDs(s.DB, old.ID); err != nil {
+			return nil, err
+		}
+	}
+	if active(old.UseStatus) != active(f.status) {
+		_, err = s.user.UpdateUserStatus(c, &user.StatusRequest{
+			ID:        old.ID,
+			UseStatus: f.status,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.userResponse(c, old.ID)
+}
+
+func (s *scim) userResponse(c context.Context, id string) (*UserResponse, error) {
+	one := s.getUser(c, id)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return &UserResponse{
+		User:    s.toUsers(c, one)[0],
+		Changed: []*org.User{one},
+	}, nil
+}
+
+// userFields user columns taken from the resource
+type userFields struct {
+	name      string
+	email     string
+	phone     string
+	position  string
+	jobNumber string
+	managerID string
+	status    int
+}
+
+func (s *scim) fields(c context.Context, r *User) (*userFields, error) {
+	f := &userFields{
+		name:     r.DisplayName,
+		email:    r.UserName,
+		phone:    primary(r.PhoneNumbers),
+		position: r.Title,
+		status:   consts.NormalStatus,
+	}
+	if !verification.CheckEmail(f.email) {
+		f.email = primary(r.Emails)
+	}
+	if f.email == "" {
+		return nil, error2.New(code.EmailRequired)
+	}
+	if f.name == "" && r.Name != nil {
+		f.name = r.Name.Formatted
+		if f.name == "" {
+			f.name = r.Name.FamilyName + r.Name.GivenName
+		}
+	}
+	if f.name == "" {
+		f.name = r.UserName
+	}
+	if r.Active != nil && !bool(*r.Active) {
+		f.status = consts.UnNormalStatus
+	}
+	if r.Enterprise != nil {
+		f.jobNumber = r.Enterprise.EmployeeNumber
+		if r.Enterprise.Manager != nil && r.Enterprise.Manager.Value != "" {
+			if s.getUser(c, r.Enterprise.Manager.Value) == nil {
+				return nil, error2.New(code.InvalidParams)
+			}
+			f.managerID = r.Enterprise.Manager.Value
+		}
+	}
+	return f, nil
+}
+
+// getUser user of the tenant, deleted users are missing
+func (s *scim) getUser(c context.Context, id string) *org.User {
+	one := s.userRepo.Get(c, s.DB, id)
+	if one == nil || one.UseStatus == consts.DelStatus || !sameTenant(c, one.TenantID) {
+		return nil
+	}
+	return one
+}
+
+func (s *scim) toUsers(c context.Context, list ...*org.User) []*User {
+	ids := make([]string, 0, len(list))
+	for _, v := range list {
+		ids = append(ids, v.ID)
+	}
+	groups := make(map[string][]Member)
+	if relations := s.userDepRepo.SelectByUserIDs(s.DB, ids...); len(relations) > 0 {
+		depIDs := make([]string, 0, len(relations))
+		for _, v := range relations {
+			depIDs = append(depIDs, v.DepID)
+		}
+		names := make(map[string]string)
+		for _, v := range s.depRepo.List(c, s.DB, depIDs...) {
+			names[v.ID] = v.Name
+		}
+		for _, v := range relations {
+			if name, ok := names[v.DepID]; ok {
+				groups[v.UserID] = append(groups[v.UserID], Member{Value: v.DepID, Display: name})
+			}
+		}
+	}
+	managers := make(map[string]*Manager)
+	if relations := s.userLeaderRepo.SelectByUserIDs(s.DB, ids...); len(relations) > 0 {
+		leaderIDs := make([]string, 0, len(relations))
+		for _, v := range relations {
+			leaderIDs = append(leaderIDs, v.LeaderID)
+		}
+		names := make(map[string]string)
+		for _, v := range s.userRepo.List(c, s.DB, leaderIDs...) {
+			names[v.ID] = v.Name
+		}
+		for _, v := range relations {
+			managers[v.UserID] = &Manager{Value: v.LeaderID, DisplayName: names[v.LeaderID]}
+		}
+	}
+
+	res := make([]*User, 0, len(list))
+	for _, v := range list {
+		isActive := Bool(active(v.UseStatus))
+		one := &User{
+			Schemas:     []string{SchemaUser, SchemaEnterpriseUser},
+			ID:          v.ID,
+			UserName:    v.Email,
+			Name:        &Name{Formatted: v.Name},
+			DisplayName: v.Name,
+			Title:       v.Position,
+			Active:      &isActive,
+			Emails:      []MultiValue{{Value: v.Email, Type: "work", Primary: true}},
+			Groups:      groups[v.ID],
+			Enterprise: &EnterpriseUser{
+				EmployeeNumber: v.JobNumber,
+				Manager:        managers[v.ID],
+			},
+			Meta: meta("User", v.CreatedAt, v.UpdatedAt),
+		}
+		if v.Phone != "" {
+			one.PhoneNumbers = []MultiValue{{Value: v.Phone, Type: "mobile", Primary: true}}
+		}
+		res = append(res, one)
+	}
+	return res
+}
+
+// ListGroups list groups
+func (s *scim) ListGroups(c context.Context, r *ListRequest) (*ListResponse, error) {
+	filter, err := ParseFilter(r.Filter)
+	if err != nil {
+		return nil, err
+	}
+	start, count := pageRange(r)
+	withMembers := r.ExcludedAttributes != "members"
+	name, byName := attrEq(filter, "displayName")
+	if _, ok := filter.(matchAll); ok || byName {
+		list, total := s.depRepo.SelectByOffset(c, s.DB, consts.NormalStatus, start-1, count, name)
+		resources := make([]interface{}, 0, len(list))
+		for k := range list {
+			resources = append(resources, s.toGroup(c, &list[k], withMembers))
+		}
+		return listResponse(resources, int(total), start), nil
+	}
+	// other filters are matched against the scim representation
+	list, total := s.depRepo.SelectByOffset(c, s.DB, consts.NormalStatus, 0, maxRecords, "")
+	if total > maxRecords {
+		return nil, error2.New(code.TooManyResults)
+	}
+	resources := make([]interface{}, 0)
+	for k := range list {
+		group := s.toGroup(c, &list[k], withMembers)
+		if filter.Match(toMap(group)) {
+			resources = append(resources, group)
+		}
+	}
+	return page(resources, start, count), nil
+}
+
+// GetGroup get group
+func (s *scim) GetGroup(c context.Context, r *GetRequest) (*GroupResponse, error) {
+	dep := s.getDep(c, r.ID)
+	if dep == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return &GroupResponse{Group: s.toGroup(c, dep, true)}, nil
+}
+
+// CreateGroup create group, it is added under the top department
+func (s *scim) CreateGroup(c context.Context, r *Group) (*GroupResponse, error) {
+	if r.DisplayName == "" {
+		return nil, error2.New(code.InvalidParams)
+	}
+	add := &department.AddRequest{Name: r.DisplayName}
+	if supper := s.depRepo.SelectSupper(c, s.DB); supper != nil {
+		add.PID = supper.ID
+	}
+	res, err := s.dep.Add(c, add)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := s.setMembers(c, res.ID, r.Members)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupResponse{
+		Group:   s.toGroup(c, s.getDep(c, res.ID), true),
+		Changed: changed,
+	}, nil
+}
+
+// ReplaceGroup put group
+func (s *scim) ReplaceGroup(c context.Context, r *ReplaceGroupRequest) (*GroupResponse, error) {
+	dep := s.getDep(c, r.ID)
+	if dep == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return s.replaceGroup(c, dep, r.Group)
+}
+
+// PatchGroup patch group
+func (s *scim) PatchGroup(c context.Context, r *PatchResourceRequest) (*GroupResponse, error) {
+	dep := s.getDep(c, r.ID)
+	if dep == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	resource := toMap(s.toGroup(c, dep, true))
+	if err := Apply(resource, r.Patch.Operations); err != nil {
+		return nil, err
+	}
+	patched := new(Group)
+	if err := fromMap(resource, patched); err != nil {
+		return nil, err
+	}
+	return s.replaceGroup(c, dep, patched)
+}
+
+// DeleteGroup delete group
+func (s *scim) DeleteGroup(c context.Context, r *GetRequest) (*GroupResponse, error) {
+	dep := s.getDep(c, r.ID)
+	if dep == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	members := s.members(c, dep.ID)
+	_, err := s.dep.Delete(c, &department.DelOneRequest{ID: dep.ID})
+	if err != nil {
+		return nil, err
+	}
+	return &GroupResponse{Changed: members}, nil
+}
+
+func (s *scim) replaceGroup(c context.Context, dep *org.Department, r *Group) (*GroupResponse, error) {
+	if r.DisplayName == "" {
+		return nil, error2.New(code.InvalidParams)
+	}
+	changed := make([]*org.User, 0)
+	if r.DisplayName != dep.Name {
+		res, err := s.dep.Update(c, &department.UpdateRequest{
+			ID:        dep.ID,
+			Name:      r.DisplayName,
+			UseStatus: dep.UseStatus,
+			Attr:      dep.Attr,
+			PID:       dep.PID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, res.Users...)
+	}
+	users, err := s.setMembers(c, dep.ID, r.Members)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupResponse{
+		Group:   s.toGroup(c, s.getDep(c, dep.ID), true),
+		Changed: append(changed, users...),
+	}, nil
+}
+
+// setMembers make the members of department exactly the given users
+func (s *scim) setMembers(c context.Context, depID string, members []Member) ([]*org.User, error) {
+	want := make(map[string]bool, len(members))
+	for _, v := range members {
+		want[v.Value] = true
+	}
+	have := make(map[string]bool)
+	for _, v := range s.userDepRepo.SelectByDEPID(s.DB, depID) {
+		have[v.UserID] = true
+	}
+	changed := make([]*org.User, 0)
+	tx := s.DB.Begin()
+	for id := range have {
+		if want[id] {
+			continue
+		}
+		if err := s.userDepRepo.DeleteByUserIDAndDepID(tx, id, depID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if one := s.getUser(c, id); one != nil {
+			changed = append(changed, one)
+		}
+	}
+	for id := range want {
+		if have[id] {
+			continue
+		}
+		one := s.getUser(c, id)
+		if one == nil {
+			tx.Rollback()
+			return nil, error2.New(code.InvalidParams)
+		}
+		err := s.userDepRepo.Add(tx, &org.UserDepartmentRelation{
+			ID:     id2.ShortID(0),
+			UserID: id,
+			DepID:  depID,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		changed = append(changed, one)
+	}
+	tx.Commit()
+	return changed, nil
+}
+
+// getDep department of the tenant, deleted ones are missing
+func (s *scim) getDep(c context.Context, id string) *org.Department {
+	dep := s.depRepo.Get(c, s.DB, id)
+	if dep == nil || dep.UseStatus != consts.NormalStatus || !sameTenant(c, dep.TenantID) {
+		return nil
+	}
+	return dep
+}
+
+func (s *scim) members(c context.Context, depID string) []*org.User {
+	relations := s.userDepRepo.SelectByDEPID(s.DB, depID)
+	if len(relations) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(relations))
+	for _, v := range relations {
+		ids = append(ids, v.UserID)
+	}
+	users := make([]*org.User, 0, len(ids))
+	for _, v := range s.userRepo.List(c, s.DB, ids...) {
+		if v.UseStatus != consts.DelStatus {
+			users = append(users, v)
+		}
+	}
+	return users
+}
+
+func (s *scim) toGroup(c context.Context, dep *org.Department, withMembers bool) *Group {
+	group := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          dep.ID,
+		DisplayName: dep.Name,
+		Meta:        meta("Group", dep.CreatedAt, dep.UpdatedAt),
+	}
+	if withMembers {
+		for _, v := range s.members(c, dep.ID) {
+			group.Members = append(group.Members, Member{Value: v.ID, Display: v.Name})
+		}
+	}
+	return group
+}
+
+// attrEq attr eq "x" of a top level attribute
+func attrEq(f Filter, attr string) (string, bool) {
+	v, ok := f.(compareFilter)
+	if !ok || v.op != "eq" || v.path.URN != "" || v.path.Sub != "" || !strings.EqualFold(v.path.Attr, attr) {
+		return "", false
+	}
+	value, ok := v.value.(string)
+	return value, ok
+}
+
+// pageRange 1-based start index and count of the request
+func pageRange(r *ListRequest) (start, count int) {
+	start = r.StartIndex
+	if start < 1 {
+		start = 1
+	}
+	count = maxResults
+	if r.Count != nil && *r.Count >= 0 && *r.Count < maxResults {
+		count = *r.Count
+	}
+	return start, count
+}
+
+// page pages resources matched in memory
+func page(resources []interface{}, start, count int) *ListResponse {
+	if start-1 >= len(resources) {
+		return listResponse(nil, len(resources), start)
+	}
+	end := start - 1 + count
+	if end > len(resources) {
+		end = len(resources)
+	}
+	return listResponse(resources[start-1:end], len(resources), start)
+}
+
+func listResponse(resources []interface{}, total, start int) *ListResponse {
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   start,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func primary(values []MultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func active(status int) bool {
+	return status == consts.NormalStatus || status == consts.ActiveStatus
+}
+
+func sameTenant(c context.Context, tenantID string) bool {
+	_, want := ginheader.GetTenantID(c).Wreck()
+	return tenantID == want
+}
+
+func meta(resourceType string, created, updated int64) *Meta {
+	return &Meta{
+		ResourceType: resourceType,
+		Created:      rfc3339(created),
+		LastModified: rfc3339(updated),
+	}
+}
+
+func rfc3339(millis int64) string {
+	if millis == 0 {
+		return ""
+	}
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
+
+func toMap(v interface{}) map[string]interface{} {
+	data, _ := json.Marshal(v)
+	res := make(map[string]interface{})
+	_ = json.Unmarshal(data, &res)
+	return res
+}
+
+func fromMap(m map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return error2.New(code.InvalidPatch)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return error2.New(code.InvalidPatch)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package scim
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/mock"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+)
+
+func TestListFilterInMemory(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	userRepo := mock.NewMockUserRepo(ctl)
+	depRepo := mock.NewMockDepartmentRepo(ctl)
+	userDepRepo := mock.NewMockUserDepartmentRelationRepo(ctl)
+	userDepRepo.EXPECT().SelectByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	userLeaderRepo := mock.NewMockUserLeaderRelationRepo(ctl)
+	userLeaderRepo.EXPECT().SelectByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	s := &scim{
+		userRepo:       userRepo,
+		depRepo:        depRepo,
+		userDepRepo:    userDepRepo,
+		userLeaderRepo: userLeaderRepo,
+	}
+	ctx := context.Background()
+
+	userRepo.EXPECT().SelectByOffset(gomock.Any(), gomock.Any(), consts.DelStatus, 0, maxRecords).Return([]*org.User{
+		{ID: "u1", Name: "zhangsan", Position: "dev", UseStatus: consts.NormalStatus},
+		{ID: "u2", Name: "lisi", Position: "ops", UseStatus: consts.NormalStatus},
+	}, int64(2))
+	res, err := s.ListUsers(ctx, &ListRequest{Filter: `title eq "dev"`})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res.TotalResults)
+	if assert.Len(t, res.Resources, 1) {
+		assert.Equal(t, "u1", res.Resources[0].(*User).ID)
+	}
+
+	// no partial results when the tenant has more than can be matched
+	userRepo.EXPECT().SelectByOffset(gomock.Any(), gomock.Any(), consts.DelStatus, 0, maxRecords).Return(nil, int64(maxRecords+1))
+	_, err = s.ListUsers(ctx, &ListRequest{Filter: `title eq "dev"`})
+	assert.Equal(t, error2.New(code.TooManyResults), err)
+	depRepo.EXPECT().SelectByOffset(gomock.Any(), gomock.Any(), consts.NormalStatus, 0, maxRecords, "").Return(nil, int64(maxRecords+1))
+	_, err = s.ListGroups(ctx, &ListRequest{Filter: `displayName co "dev"`})
+	assert.Equal(t, error2.New(code.TooManyResults), err)
+}
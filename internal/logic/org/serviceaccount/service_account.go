@@ -0,0 +1,397 @@
+package serviceaccount
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+const (
+	// KeyPrefix prefix of every api key, it tells keys apart from other bearer tokens
+	KeyPrefix = "qxk_"
+
+	keySize            = 32
+	prefixLength       = 12
+	defaultRotateGrace = 24 * 60 * 60
+	// last used time is written at most once per interval, in milliseconds
+	lastUsedInterval = 60 * 1000
+)
+
+// ServiceAccount service accounts and their api keys
+type ServiceAccount interface {
+	Add(c context.Context, r *AddRequest) (*AddResponse, error)
+	Update(c context.Context, r *UpdateRequest) (*UpdateResponse, error)
+	Delete(c context.Context, r *DeleteRequest) (*DeleteResponse, error)
+	PageList(c context.Context, r *PageListRequest) (*page.Page, error)
+	AddKey(c context.Context, r *AddKeyRequest) (*KeyResponse, error)
+	RotateKey(c context.Context, r *RotateKeyRequest) (*KeyResponse, error)
+	RevokeKey(c context.Context, r *RevokeKeyRequest) (*RevokeKeyResponse, error)
+	ListKeys(c context.Context, r *ListKeysRequest) (*ListKeysResponse, error)
+	Authenticate(c context.Context, r *AuthenticateRequest) (*AuthenticateResponse, error)
+}
+
+type serviceAccount struct {
+	DB          *gorm.DB
+	conf        configs.Config
+	accountRepo org.ServiceAccountRepo
+	keyRepo     org.APIKeyRepo
+}
+
+// NewServiceAccount new
+func NewServiceAccount(conf configs.Config, db *gorm.DB) ServiceAccount {
+	return &serviceAccount{
+		DB:          db,
+		conf:        conf,
+		accountRepo: mysql2.NewServiceAccountRepo(),
+		keyRepo:     mysql2.NewAPIKeyRepo(),
+	}
+}
+
+// AddRequest add service account
+type AddRequest struct {
+	Name        string `json:"name" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=255"`
+	CreatedBy   string `json:"-"`
+}
+
+// AddResponse add service account response
+type AddResponse struct {
+	ID string `json:"id"`
+}
+
+// Add add a service account to current tenant
+func (s *serviceAccount) Add(c context.Context, r *AddRequest) (*AddResponse, error) {
+	now := time2.NowUnix()
+	one := &org.ServiceAccount{
+		ID:          id2.ShortID(0),
+		Name:        r.Name,
+		Description: r.Description,
+		UseStatus:   consts.NormalStatus,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   r.CreatedBy,
+	}
+	if err := s.accountRepo.Insert(c, s.DB, one); err != nil {
+		return nil, err
+	}
+	return &AddResponse{ID: one.ID}, nil
+}
+
+// UpdateRequest update service account
+type UpdateRequest struct {
+	ID          string `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=255"`
+	//1:normal,-2:disabled
+	UseStatus int    `json:"useStatus" binding:"oneof=1 -2"`
+	UpdatedBy string `json:"-"`
+}
+
+// UpdateResponse update service account response
+type UpdateResponse struct {
+}
+
+// Update update a service account, keys of a disabled one stop working
+func (s *serviceAccount) Update(c context.Context, r *UpdateRequest) (*UpdateResponse, error) {
+	one := s.get(c, r.ID)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	one.Name = r.Name
+	one.Description = r.Description
+	one.UseStatus = r.UseStatus
+	one.UpdatedAt = time2.NowUnix()
+	one.UpdatedBy = r.UpdatedBy
+	if err := s.accountRepo.Update(s.DB, one); err != nil {
+		return nil, err
+	}
+	return &UpdateResponse{}, nil
+}
+
+// DeleteRequest delete service accounts
+type DeleteRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1"`
+}
+
+// DeleteResponse delete service accounts response
+type DeleteResponse struct {
+}
+
+// Delete delete service accounts with their keys
+func (s *serviceAccount) Delete(c context.Context, r *DeleteRequest) (*DeleteResponse, error) {
+	ids := make([]string, 0, len(r.IDs))
+	for _, id := range r.IDs {
+		if s.get(c, id) != nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return &DeleteResponse{}, nil
+	}
+	tx := s.DB.Begin()
+	if err := s.keyRepo.DeleteByServiceAccountID(tx, ids...); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.accountRepo.Delete(c, tx, ids...); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.Commit()
+	return &DeleteResponse{}, nil
+}
+
+// PageListRequest list service accounts
+type PageListRequest struct {
+	Page  int `json:"page" form:"page"`
+	Limit int `json:"limit" form:"limit"`
+}
+
+// PageList service accounts of current tenant
+func (s *serviceAccount) PageList(c context.Context, r *PageListRequest) (*page.Page, error) {
+	list, total := s.accountRepo.PageList(c, s.DB, r.Page, r.Limit)
+	if list == nil {
+		list = make([]org.ServiceAccount, 0)
+	}
+	return &page.Page{
+		Data:       list,
+		TotalCount: total,
+	}, nil
+}
+
+// AddKeyRequest add api key
+type AddKeyRequest struct {
+	ServiceAccountID string `json:"serviceAccountID" binding:"required"`
+	// milliseconds, 0 never expires
+	ExpireAt  int64  `json:"expireAt"`
+	CreatedBy string `json:"-"`
+}
+
+// KeyResponse the key is only returned here
+type KeyResponse struct {
+	ID       string `json:"id"`
+	Key      string `json:"key"`
+	Prefix   string `json:"prefix"`
+	ExpireAt int64  `json:"expireAt"`
+}
+
+// AddKey add an api key to a service account
+func (s *serviceAccount) AddKey(c context.Context, r *AddKeyRequest) (*KeyResponse, error) {
+	one := s.get(c, r.ServiceAccountID)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	if r.ExpireAt != 0 && r.ExpireAt <= time2.NowUnix() {
+		return nil, error2.New(code.InvalidParams)
+	}
+	return s.newKey(s.DB, one, r.ExpireAt, r.CreatedBy)
+}
+
+// RotateKeyRequest rotate api key
+type RotateKeyRequest struct {
+	ID string `json:"id" binding:"required"`
+	// seconds the old key keeps working, 0 for serviceAccount.rotateGrace
+	Grace     int64  `json:"grace" binding:"min=0"`
+	CreatedBy string `json:"-"`
+}
+
+// RotateKey replace a key with a new one of the same lifetime, the old one expires after the grace period
+func (s *serviceAccount) RotateKey(c context.Context, r *RotateKeyRequest) (*KeyResponse, error) {
+	key, one := s.getKey(c, r.ID)
+	if key == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	grace := r.Grace
+	if grace == 0 {
+		grace = int64(s.conf.ServiceAccount.RotateGrace)
+	}
+	if grace == 0 {
+		grace = defaultRotateGrace
+	}
+	now := time2.NowUnix()
+	var expireAt int64
+	if key.ExpireAt != 0 {
+		expireAt = now + key.ExpireAt - key.CreatedAt
+	}
+	oldExpireAt := now + grace*1000
+	if key.ExpireAt != 0 && key.ExpireAt < oldExpireAt {
+		oldExpireAt = key.ExpireAt
+	}
+	tx := s.DB.Begin()
+	if err := s.keyRepo.UpdateExpireAt(tx, key.ID, oldExpireAt); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	res, err := s.newKey(tx, one, expireAt, r.CreatedBy)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.Commit()
+	return res, nil
+}
+
+// RevokeKeyRequest revoke api key
+type RevokeKeyRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// RevokeKeyResponse revoke api key response
+type RevokeKeyResponse struct {
+}
+
+// RevokeKey delete a key, it stops working at once
+func (s *serviceAccount) RevokeKey(c context.Context, r *RevokeKeyRequest) (*RevokeKeyResponse, error) {
+	key, _ := s.getKey(c, r.ID)
+	if key == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	if err := s.keyRepo.Delete(s.DB, key.ID); err != nil {
+		return nil, err
+	}
+	return &RevokeKeyResponse{}, nil
+}
+
+// ListKeysRequest list api keys
+type ListKeysRequest struct {
+	ServiceAccountID string `json:"serviceAccountID" form:"serviceAccountID" binding:"required"`
+}
+
+// ListKeysResponse keys without their secret part
+type ListKeysResponse struct {
+	Keys []org.APIKey `json:"keys"`
+}
+
+// ListKeys keys of a service account
+func (s *serviceAccount) ListKeys(c context.Context, r *ListKeysRequest) (*ListKeysResponse, error) {
+	if s.get(c, r.ServiceAccountID) == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return &ListKeysResponse{
+		Keys: s.keyRepo.SelectByServiceAccountID(s.DB, r.ServiceAccountID),
+	}, nil
+}
+
+// AuthenticateRequest authenticate api key
+type AuthenticateRequest struct {
+	Key string
+	IP  string
+}
+
+// AuthenticateResponse service account of the key
+type AuthenticateResponse struct {
+	ID       string
+	Name     string
+	TenantID string
+}
+
+// Authenticate find the service account of key and record the key as used
+func (s *serviceAccount) Authenticate(c context.Context, r *AuthenticateRequest) (*AuthenticateResponse, error) {
+	if !strings.HasPrefix(r.Key, KeyPrefix) {
+		return nil, error2.New(code.InvalidAPIKey)
+	}
+	key := s.keyRepo.SelectByHash(s.DB, hashKey(r.Key))
+	now := time2.NowUnix()
+	if key == nil || (key.ExpireAt != 0 && key.ExpireAt <= now) {
+		return nil, error2.New(code.InvalidAPIKey)
+	}
+	one := s.accountRepo.Get(s.DB, key.ServiceAccountID)
+	if one == nil || one.UseStatus != consts.NormalStatus {
+		return nil, error2.New(code.InvalidAPIKey)
+	}
+	if now-key.LastUsedAt >= lastUsedInterval {
+		err := s.keyRepo.UpdateLastUsed(s.DB, key.ID, r.IP, now, now-lastUsedInterval)
+		if err != nil {
+			logger.Logger.Warnf("update last used of api key %s: %s", key.ID, err.Error())
+		}
+	}
+	return &AuthenticateResponse{
+		ID:       one.ID,
+		Name:     one.Name,
+		TenantID: one.TenantID,
+	}, nil
+}
+
+func (s *serviceAccount) newKey(tx *gorm.DB, one *org.ServiceAccount, expireAt int64, createdBy string) (*KeyResponse, error) {
+	b := make([]byte, keySize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	plain := KeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	key := &org.APIKey{
+		ID:               id2.ShortID(0),
+		ServiceAccountID: one.ID,
+		Prefix:           plain[:prefixLength],
+		KeyHash:          hashKey(plain),
+		ExpireAt:         expireAt,
+		TenantID:         one.TenantID,
+		CreatedAt:        time2.NowUnix(),
+		CreatedBy:        createdBy,
+	}
+	if err := s.keyRepo.Insert(tx, key); err != nil {
+		return nil, err
+	}
+	return &KeyResponse{
+		ID:       key.ID,
+		Key:      plain,
+		Prefix:   key.Prefix,
+		ExpireAt: key.ExpireAt,
+	}, nil
+}
+
+// get service account of current tenant
+func (s *serviceAccount) get(c context.Context, id string) *org.ServiceAccount {
+	one := s.accountRepo.Get(s.DB, id)
+	_, tenantID := ginheader.GetTenantID(c).Wreck()
+	if one == nil || one.TenantID != tenantID {
+		return nil
+	}
+	return one
+}
+
+// getKey key and its service account of current tenant
+func (s *serviceAccount) getKey(c context.Context, id string) (*org.APIKey, *org.ServiceAccount) {
+	key := s.keyRepo.Get(s.DB, id)
+	if key == nil {
+		return nil, nil
+	}
+	one := s.get(c, key.ServiceAccountID)
+	if one == nil {
+		return nil, nil
+	}
+	return key, one
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,149 @@
+package serviceaccount
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/mock"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+)
+
+func newServiceAccount(t *testing.T) (*serviceAccount, *mock.MockServiceAccountRepo, *mock.MockAPIKeyRepo, sqlmock.Sqlmock) {
+	conn, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		SkipInitializeWithVersion: true,
+		Conn:                      conn,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctl := gomock.NewController(t)
+	accountRepo := mock.NewMockServiceAccountRepo(ctl)
+	keyRepo := mock.NewMockAPIKeyRepo(ctl)
+	return &serviceAccount{
+		DB:          db,
+		conf:        configs.Config{},
+		accountRepo: accountRepo,
+		keyRepo:     keyRepo,
+	}, accountRepo, keyRepo, sqlMock
+}
+
+func TestAuthenticate(t *testing.T) {
+	s, accountRepo, keyRepo, _ := newServiceAccount(t)
+	ctx := context.Background()
+	now := time2.NowUnix()
+	plain := KeyPrefix + "secret"
+	keys := map[string]*org.APIKey{
+		hashKey(plain):                  {ID: "k1", ServiceAccountID: "s1", LastUsedAt: now - lastUsedInterval - 1},
+		hashKey(KeyPrefix + "recent"):   {ID: "k2", ServiceAccountID: "s1", LastUsedAt: now},
+		hashKey(KeyPrefix + "expired"):  {ID: "k3", ServiceAccountID: "s1", ExpireAt: now - 1},
+		hashKey(KeyPrefix + "disabled"): {ID: "k4", ServiceAccountID: "s2"},
+	}
+	keyRepo.EXPECT().SelectByHash(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, keyHash string) *org.APIKey {
+		return keys[keyHash]
+	}).AnyTimes()
+	accountRepo.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, id string) *org.ServiceAccount {
+		if id == "s2" {
+			return &org.ServiceAccount{ID: id, UseStatus: consts.UnNormalStatus}
+		}
+		return &org.ServiceAccount{ID: id, Name: "beisen", TenantID: "t1", UseStatus: consts.NormalStatus}
+	}).AnyTimes()
+	// only the key which was not used lately is written
+	keyRepo.EXPECT().UpdateLastUsed(gomock.Any(), "k1", "10.0.0.1", gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	res, err := s.Authenticate(ctx, &AuthenticateRequest{Key: plain, IP: "10.0.0.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, &AuthenticateResponse{ID: "s1", Name: "beisen", TenantID: "t1"}, res)
+
+	_, err = s.Authenticate(ctx, &AuthenticateRequest{Key: KeyPrefix + "recent"})
+	assert.NoError(t, err)
+
+	for _, key := range []string{"secret", KeyPrefix + "unknown", KeyPrefix + "expired", KeyPrefix + "disabled"} {
+		_, err = s.Authenticate(ctx, &AuthenticateRequest{Key: key})
+		assert.Equal(t, error2.New(code.InvalidAPIKey), err, key)
+	}
+}
+
+func TestRotateKey(t *testing.T) {
+	s, accountRepo, keyRepo, sqlMock := newServiceAccount(t)
+	ctx := header2.SetContext(context.Background(), user.TenantID, "t1")
+	now := time2.NowUnix()
+	old := &org.APIKey{
+		ID:               "k1",
+		ServiceAccountID: "s1",
+		CreatedAt:        now - 1000,
+		ExpireAt:         now + 30*24*60*60*1000,
+	}
+	keyRepo.EXPECT().Get(gomock.Any(), "k1").Return(old)
+	keyRepo.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	accountRepo.EXPECT().Get(gomock.Any(), "s1").Return(&org.ServiceAccount{ID: "s1", TenantID: "t1"}).AnyTimes()
+
+	var oldExpireAt int64
+	keyRepo.EXPECT().UpdateExpireAt(gomock.Any(), "k1", gomock.Any()).DoAndReturn(func(_ interface{}, _ string, expireAt int64) error {
+		oldExpireAt = expireAt
+		return nil
+	})
+	var added *org.APIKey
+	keyRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, key *org.APIKey) error {
+		added = key
+		return nil
+	})
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectCommit()
+
+	res, err := s.RotateKey(ctx, &RotateKeyRequest{ID: "k1", Grace: 60})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.Key, KeyPrefix))
+	assert.Equal(t, res.Key[:prefixLength], res.Prefix)
+	assert.Equal(t, hashKey(res.Key), added.KeyHash)
+	assert.Equal(t, "t1", added.TenantID)
+	// the old key works for the grace period, the new one lives as long as the old one did
+	assert.InDelta(t, now+60*1000, oldExpireAt, 1000)
+	assert.InDelta(t, now+old.ExpireAt-old.CreatedAt, res.ExpireAt, 1000)
+
+	_, err = s.RotateKey(ctx, &RotateKeyRequest{ID: "k2"})
+	assert.Equal(t, error2.New(code.DataNotExist), err)
+}
+
+func TestTenantScope(t *testing.T) {
+	s, accountRepo, keyRepo, _ := newServiceAccount(t)
+	ctx := header2.SetContext(context.Background(), user.TenantID, "t2")
+	accountRepo.EXPECT().Get(gomock.Any(), "s1").Return(&org.ServiceAccount{ID: "s1", TenantID: "t1"}).AnyTimes()
+	keyRepo.EXPECT().Get(gomock.Any(), "k1").Return(&org.APIKey{ID: "k1", ServiceAccountID: "s1"}).AnyTimes()
+
+	_, err := s.AddKey(ctx, &AddKeyRequest{ServiceAccountID: "s1"})
+	assert.Equal(t, error2.New(code.DataNotExist), err)
+	_, err = s.RevokeKey(ctx, &RevokeKeyRequest{ID: "k1"})
+	assert.Equal(t, error2.New(code.DataNotExist), err)
+	_, err = s.ListKeys(ctx, &ListKeysRequest{ServiceAccountID: "s1"})
+	assert.Equal(t, error2.New(code.DataNotExist), err)
+}
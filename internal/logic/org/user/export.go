@@ -0,0 +1,281 @@
+package user
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tealeg/xlsx"
+
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+)
+
+// users read per query while exporting
+const exportBatch = 500
+
+// columns of the table that are never exported
+var unExportColumns = map[string]bool{
+	"id":              true,
+	"tenant_id":       true,
+	"password_status": true,
+	"deleted_at":      true,
+	"deleted_by":      true,
+}
+
+// ExportRequest the filters of /m/user/list, every matching user is exported
+type ExportRequest struct {
+	SearchListUserRequest
+	//xlsx or csv, default xlsx
+	Format string `json:"format" form:"format"`
+}
+
+// ExportFileName name of the exported file
+func ExportFileName(format string) string {
+	if format == FormatCSV {
+		return "users.csv"
+	}
+	return "users.xlsx"
+}
+
+// Export write the users to w with the column names the admin configured,
+// the department and leader columns follow, the file can be imported again
+func (u *user) Export(c context.Context, r *ExportRequest, w io.Writer) error {
+	columns := u.exportColumns(c)
+	dbColumns := make([]string, 0, len(columns))
+	header := make([]string, 0, len(columns)+2)
+	for k := range columns {
+		dbColumns = append(dbColumns, columns[k].ColumnsName)
+		header = append(header, columns[k].Name)
+	}
+	header = append(header, consts.OwnerDepName, consts.OwnerLeaderName)
+
+	writer, err := newExportWriter(r.Format, w, header)
+	if err != nil {
+		return err
+	}
+	userIDs, filtered := u.filterUserIDs(c, &r.SearchListUserRequest)
+	if filtered && len(userIDs) == 0 {
+		return writer.Close()
+	}
+	departments, _ := u.depRepo.PageList(c, u.DB, consts.NormalStatus, 1, 10000)
+	depMap := make(map[string]org.Department, len(departments))
+	for _, v := range departments {
+		depMap[v.ID] = v
+	}
+	// paged by id, users updated meanwhile are neither repeated nor skipped
+	for afterID := ""; ; {
+		list := u.userRepo.SelectAfterID(c, u.DB, consts.NormalStatus, afterID, exportBatch, userIDs)
+		if len(list) == 0 {
+			break
+		}
+		ids := make([]string, 0, len(list))
+		for k := range list {
+			ids = append(ids, list[k].ID)
+		}
+		values := make(map[string]map[string]interface{}, len(list))
+		for _, v := range u.userRepo.SelectColumns(c, u.DB, dbColumns, ids...) {
+			values[exportValue(v[consts.ID])] = v
+		}
+		deps := make(map[string]string, len(list))
+		for _, v := range u.userDepRepo.SelectByUserIDs(u.DB, ids...) {
+			if _, ok := deps[v.UserID]; !ok {
+				deps[v.UserID] = departmentPath(depMap, v.DepID)
+			}
+		}
+		leaders := u.exportLeaders(c, ids)
+
+		for k := range list {
+			row := make([]string, 0, len(header))
+			value := values[list[k].ID]
+			for _, column := range dbColumns {
+				row = append(row, exportValue(value[column]))
+			}
+			row = append(row, deps[list[k].ID], strings.Join(leaders[list[k].ID], ","))
+			if err = writer.Write(row); err != nil {
+				return err
+			}
+		}
+		if len(list) < exportBatch {
+			break
+		}
+		afterID = list[len(list)-1].ID
+	}
+	return writer.Close()
+}
+
+// exportColumns columns in use, system ones first, the template columns before the admin configured any
+func (u *user) exportColumns(c context.Context) []org.UserTableColumns {
+	list, _ := u.columnRepo.GetFilter(c, u.DB, consts.FieldAdminStatus, consts.AllAttr)
+	columns := make([]org.UserTableColumns, 0, len(list))
+	for k := range list {
+		if !unExportColumns[list[k].ColumnsName] {
+			columns = append(columns, list[k])
+		}
+	}
+	if len(columns) == 0 {
+		for name, field := range u.columnRepo.GetXlsxField(c, u.DB, consts.FieldAdminStatus) {
+			if field != consts.ID {
+				columns = append(columns, org.UserTableColumns{
+					Name:        name,
+					ColumnsName: columnName(field),
+					Attr:        consts.SystemAttr,
+				})
+			}
+		}
+		sort.Slice(columns, func(i, j int) bool {
+			return columns[i].Name < columns[j].Name
+		})
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].Attr < columns[j].Attr
+	})
+	return columns
+}
+
+// exportLeaders names of the leaders of every user
+func (u *user) exportLeaders(c context.Context, ids []string) map[string][]string {
+	relations := u.userLeaderRepo.SelectByUserIDs(u.DB, ids...)
+	leaderIDs := make([]string, 0, len(relations))
+	for _, v := range relations {
+		leaderIDs = append(leaderIDs, v.LeaderID)
+	}
+	names := make(map[string]string, len(leaderIDs))
+	if len(leaderIDs) > 0 {
+		for _, v := range u.userRepo.List(c, u.DB, leaderIDs...) {
+			names[v.ID] = v.Name
+		}
+	}
+	leaders := make(map[string][]string)
+	for _, v := range relations {
+		if name, ok := names[v.LeaderID]; ok {
+			leaders[v.UserID] = append(leaders[v.UserID], name)
+		}
+	}
+	return leaders
+}
+
+// departmentPath path of the department the way import resolves it, /A/B/C
+func departmentPath(depMap map[string]org.Department, depID string) string {
+	names := make([]string, 0)
+	for depID != "" && len(names) <= len(depMap) {
+		dep, ok := depMap[depID]
+		if !ok {
+			break
+		}
+		names = append([]string{dep.Name}, names...)
+		depID = dep.PID
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+// importableColumns json field of every text column of org.User, import fills users through them
+func importableColumns() map[string]string {
+	columns := make(map[string]string)
+	t := reflect.TypeOf(org.User{})
+	for i := 0; i < t.NumField(); i++ {
+		column := strings.TrimPrefix(strings.Split(t.Field(i).Tag.Get("gorm"), ";")[0], "column:")
+		if t.Field(i).Type.Kind() == reflect.String && !unExportColumns[column] {
+			columns[column] = strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		}
+	}
+	return columns
+}
+
+// columnName db column of a template field, selfEmail is self_email
+func columnName(field string) string {
+	var b strings.Builder
+	for _, r := range field {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func exportValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprint(v)
+}
+
+// exportWriter rows reach w as they are written, nothing is held but the current row
+type exportWriter interface {
+	Write(row []string) error
+	Close() error
+}
+
+func newExportWriter(format string, w io.Writer, header []string) (exportWriter, error) {
+	if format == FormatCSV {
+		// Excel only takes a csv for utf-8 with the bom
+		if _, err := w.Write(utf8BOM); err != nil {
+			return nil, err
+		}
+		writer := &csvExportWriter{writer: csv.NewWriter(w)}
+		return writer, writer.Write(header)
+	}
+	builder := xlsx.NewStreamFileBuilder(w)
+	if err := builder.AddSheet("sheet1", header, nil); err != nil {
+		return nil, err
+	}
+	file, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxExportWriter{file: file}, nil
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvExportWriter) Write(row []string) error {
+	return c.writer.Write(row)
+}
+
+func (c *csvExportWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// xlsxExportWriter the sheet is streamed, Close ends the workbook
+type xlsxExportWriter struct {
+	file *xlsx.StreamFile
+}
+
+func (x *xlsxExportWriter) Write(row []string) error {
+	return x.file.Write(row)
+}
+
+func (x *xlsxExportWriter) Close() error {
+	return x.file.Close()
+}
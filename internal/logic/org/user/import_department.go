@@ -0,0 +1,143 @@
+package user
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"context"
+	"strings"
+
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+)
+
+// CreatedDepartment department an import created for a path of its file
+type CreatedDepartment struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	PID  string `json:"pid"`
+	// /A/B/C
+	Path string `json:"path"`
+}
+
+// depCreator resolve the department paths of imported rows, creating the missing departments once
+type depCreator struct {
+	ctx      context.Context
+	u        *user
+	createBy string
+	// departments are only planned when previewing
+	dryRun   bool
+	deps     map[string]org.Department
+	children map[string]map[string]string
+	created  []CreatedDepartment
+}
+
+func (u *user) newDepCreator(ctx context.Context, list []org.Department, createBy string, dryRun bool) *depCreator {
+	d := &depCreator{
+		ctx:      ctx,
+		u:        u,
+		createBy: createBy,
+		dryRun:   dryRun,
+		deps:     make(map[string]org.Department, len(list)),
+		children: make(map[string]map[string]string),
+		created:  make([]CreatedDepartment, 0),
+	}
+	for k := range list {
+		d.add(list[k])
+	}
+	return d
+}
+
+func (d *depCreator) add(dep org.Department) {
+	d.deps[dep.ID] = dep
+	if d.children[dep.PID] == nil {
+		d.children[dep.PID] = make(map[string]string)
+	}
+	d.children[dep.PID][dep.Name] = dep.ID
+}
+
+// resolve id of the department of path, the missing ones along it are created,
+// empty when the path names another top department than the existing one
+func (d *depCreator) resolve(path string) (string, error) {
+	parts := make([]string, 0)
+	for _, v := range strings.Split(path, "/") {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	pid := ""
+	for k, name := range parts {
+		id, ok := d.children[pid][name]
+		if !ok {
+			if pid == "" && len(d.children[pid]) > 0 {
+				return "", nil
+			}
+			dep, err := d.create(name, pid, "/"+strings.Join(parts[:k+1], "/"))
+			if err != nil {
+				return "", err
+			}
+			id = dep.ID
+		}
+		pid = id
+	}
+	return pid, nil
+}
+
+func (d *depCreator) create(name, pid, path string) (*org.Department, error) {
+	now := time2.NowUnix()
+	dep := &org.Department{
+		ID:        id2.ShortID(0),
+		Name:      name,
+		UseStatus: consts.NormalStatus,
+		Attr:      consts.DepAttrDEP,
+		PID:       pid,
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: d.createBy,
+	}
+	if pid == "" {
+		dep.Attr = consts.DepAttrCOM
+		dep.SuperPID = dep.ID
+		dep.Grade = consts.FirsGrade
+	} else {
+		parent := d.deps[pid]
+		dep.SuperPID = parent.SuperPID
+		dep.Grade = parent.Grade + 1
+	}
+	if !d.dryRun {
+		err := d.u.depRepo.Insert(d.ctx, d.u.DB, dep)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d.add(*dep)
+	d.created = append(d.created, CreatedDepartment{
+		ID:   dep.ID,
+		Name: dep.Name,
+		PID:  dep.PID,
+		Path: path,
+	})
+	return dep, nil
+}
+
+// createdPaths paths of the created departments separated by comma
+func createdPaths(created []CreatedDepartment) string {
+	paths := make([]string, 0, len(created))
+	for k := range created {
+		paths = append(paths, created[k].Path)
+	}
+	return strings.Join(paths, ",")
+}
@@ -0,0 +1,259 @@
+package user
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+)
+
+// import file formats
+const (
+	FormatXlsx   = "xlsx"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Excel only takes a utf-8 csv for utf-8 when it starts with the bom
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// DetectImportFormat format of an uploaded file by its extension, files without one are told by their content
+func DetectImportFormat(fileName string, file []byte) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx":
+		return FormatXlsx
+	case ".csv", ".txt":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case "":
+		if bytes.HasPrefix(file, []byte("PK\x03\x04")) {
+			return FormatXlsx
+		}
+		if bytes.HasPrefix(bytes.TrimLeft(bytes.TrimPrefix(file, utf8BOM), " \t\r\n"), []byte("{")) {
+			return FormatNDJSON
+		}
+		return FormatCSV
+	}
+	return ""
+}
+
+// csvTemplate the template as a utf-8 csv, the demo row is the one of the xlsx template
+func (u *user) csvTemplate(columns []string) (*GetTemplateFileResponse, error) {
+	demo := make([]string, len(columns))
+	for k := range columns {
+		demo[k] = "demo(导入请删除此行)"
+		if columns[k] == consts.OwnerDepName {
+			demo[k] = "/部门demo1/子部门demo2"
+		}
+	}
+	buffer := bytes.NewBuffer(append([]byte(nil), utf8BOM...))
+	writer := csv.NewWriter(buffer)
+	if err := writer.WriteAll([][]string{columns, demo}); err != nil {
+		return nil, err
+	}
+	name := u.conf.TemplateName
+	return &GetTemplateFileResponse{
+		Data:     buffer.Bytes(),
+		FileName: strings.TrimSuffix(name, filepath.Ext(name)) + ".csv",
+	}, nil
+}
+
+// makeData rows of the file in the format of the request, every format yields the rows makeDataFromExcl does
+func (u *user) makeData(ctx context.Context, file []byte, r *ImportFileRequest) ([]map[string]interface{}, error) {
+	format := strings.ToLower(r.Format)
+	if format == "" {
+		format = DetectImportFormat(r.FileName, file)
+	}
+	switch format {
+	case FormatXlsx:
+		return u.makeDataFromExcl(ctx, file, r.TenantID)
+	case FormatCSV:
+		return u.makeDataFromCSV(ctx, file, r)
+	case FormatNDJSON:
+		return u.makeDataFromNDJSON(ctx, file)
+	}
+	return nil, error2.New(code.InvalidFile)
+}
+
+// makeDataFromCSV the first line holds the column names of the template
+func (u *user) makeDataFromCSV(ctx context.Context, file []byte, r *ImportFileRequest) ([]map[string]interface{}, error) {
+	comma, err := csvDelimiter(r.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+	file, err = decodeImportFile(file, r.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(file))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, error2.New(code.InvalidFile)
+	}
+	columns := u.importColumns(ctx)
+	header := make([]string, len(records[0]))
+	for k, v := range records[0] {
+		header[k] = columns[strings.TrimSpace(v)]
+	}
+	suc1 := make([]map[string]interface{}, 0, len(records)-1)
+	for k, record := range records[1:] {
+		s := make(map[string]interface{})
+		for k1, v1 := range record {
+			if k1 < len(header) && header[k1] != "" {
+				s[header[k1]] = strings.TrimSpace(v1)
+			}
+		}
+		if row := fillImportRow(s); row != nil {
+			// the header is the first line
+			row[consts.ROW] = k + 2
+			suc1 = append(suc1, row)
+		}
+	}
+	return suc1, nil
+}
+
+// makeDataFromNDJSON one user object per line, keyed by the column names of the template or the fields
+func (u *user) makeDataFromNDJSON(ctx context.Context, file []byte) ([]map[string]interface{}, error) {
+	columns := u.importColumns(ctx)
+	suc1 := make([]map[string]interface{}, 0)
+	reader := bufio.NewReader(bytes.NewReader(bytes.TrimPrefix(file, utf8BOM)))
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) > 0 {
+			one := make(map[string]interface{})
+			if e := json.Unmarshal(data, &one); e != nil {
+				return nil, error2.New(code.InvalidFile)
+			}
+			s := make(map[string]interface{})
+			for k, v := range one {
+				if field := columns[k]; field != "" && v != nil {
+					s[field] = ndjsonValue(v)
+				}
+			}
+			if row := fillImportRow(s); row != nil {
+				row[consts.ROW] = line
+				suc1 = append(suc1, row)
+			}
+		}
+		if err == io.EOF {
+			return suc1, nil
+		}
+	}
+}
+
+// importColumns field of every column name of the template, fields map to themselves
+// and the names the admin gave the text columns of the user, which exports use, map to theirs
+func (u *user) importColumns(ctx context.Context) map[string]string {
+	xlsxFields := u.columnRepo.GetXlsxField(ctx, u.DB, consts.FieldAdminStatus)
+	columns := make(map[string]string, 2*len(xlsxFields)+2)
+	for k, v := range xlsxFields {
+		if v != consts.ID {
+			columns[k] = v
+			columns[v] = v
+		}
+	}
+	fields := importableColumns()
+	list, _ := u.columnRepo.GetFilter(ctx, u.DB, consts.FieldAdminStatus, consts.AllAttr)
+	for _, v := range list {
+		if field, ok := fields[v.ColumnsName]; ok && v.Name != "" {
+			columns[v.Name] = field
+		}
+	}
+	columns[consts.OwnerDepName] = consts.DEPNAME
+	columns[consts.DEPNAME] = consts.DEPNAME
+	return columns
+}
+
+// fillImportRow blank rows are dropped, the columns screening reads are always there
+func fillImportRow(s map[string]interface{}) map[string]interface{} {
+	blank := true
+	for _, v := range s {
+		if v != "" {
+			blank = false
+			break
+		}
+	}
+	if blank {
+		return nil
+	}
+	for _, v := range []string{consts.NAME, consts.EMAIL, consts.SELFEMAIL, consts.PHONE, consts.DEPNAME} {
+		if _, ok := s[v]; !ok {
+			s[v] = ""
+		}
+	}
+	return s
+}
+
+// ndjsonValue cells are strings, numbers like phones are written without exponent
+func ndjsonValue(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func decodeImportFile(file []byte, name string) ([]byte, error) {
+	var enc encoding.Encoding
+	switch strings.ToLower(name) {
+	case "", "utf8", "utf-8":
+		return bytes.TrimPrefix(file, utf8BOM), nil
+	case "gbk":
+		enc = simplifiedchinese.GBK
+	case "gb18030":
+		enc = simplifiedchinese.GB18030
+	default:
+		return nil, error2.New(code.InvalidParams)
+	}
+	return enc.NewDecoder().Bytes(file)
+}
+
+func csvDelimiter(delimiter string) (rune, error) {
+	switch delimiter {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	comma, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || comma == '"' || comma == '\r' || comma == '\n' || comma == utf8.RuneError {
+		return 0, error2.New(code.InvalidParams)
+	}
+	return comma, nil
+}
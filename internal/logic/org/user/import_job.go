@@ -0,0 +1,352 @@
+package user
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/tealeg/xlsx"
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+const (
+	// rows handled between two progress writes and cancel checks
+	importJobBatch = 100
+	// defaultStaleTime seconds a running job may go without progress
+	defaultStaleTime = 1800
+)
+
+// ImportJob user imports running in the background, large files no longer hold the request
+type ImportJob interface {
+	Submit(c context.Context, file []byte, profile header2.Profile, r *ImportFileRequest) (*SubmitImportJobResponse, error)
+	Info(c context.Context, r *ImportJobInfoRequest) (*org.ImportJob, error)
+	Cancel(c context.Context, r *CancelImportJobRequest) (*CancelImportJobResponse, error)
+	FailFile(c context.Context, r *ImportJobFailFileRequest) (*ImportJobFailFileResponse, error)
+	PageList(c context.Context, r *ListImportJobRequest) (*page.Page, error)
+}
+
+type importJob struct {
+	DB      *gorm.DB
+	user    *user
+	jobRepo org.ImportJobRepo
+	batch   int
+}
+
+// NewImportJob new, jobs left running by a stopped worker are failed
+func NewImportJob(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient) ImportJob {
+	j := &importJob{
+		DB:      db,
+		user:    NewUser(conf, db, redisClient).(*user),
+		jobRepo: mysql2.NewImportJobRepo(),
+		batch:   importJobBatch,
+	}
+	j.failStale(conf.ImportJob.StaleTime)
+	return j
+}
+
+// failStale a job makes progress every batch, one that stopped for staleTime seconds has lost its worker
+func (j *importJob) failStale(staleTime time.Duration) {
+	if staleTime <= 0 {
+		staleTime = defaultStaleTime
+	}
+	now := time2.NowUnix()
+	num, err := j.jobRepo.FailStale(j.DB, now-int64(staleTime)*1000, now, consts.ImportJobInterrupted)
+	if err != nil {
+		logger.Logger.Error("import job fail stale ", err)
+		return
+	}
+	if num > 0 {
+		logger.Logger.Info("import job fail stale ", num)
+	}
+}
+
+// SubmitImportJobResponse the job to follow
+type SubmitImportJobResponse struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+// Submit parse the file and start importing its rows, a file that can not be read is refused at once
+func (j *importJob) Submit(c context.Context, file []byte, profile header2.Profile, r *ImportFileRequest) (*SubmitImportJobResponse, error) {
+	rows, err := j.user.makeData(c, file, r)
+	if err != nil {
+		return nil, err
+	}
+	now := time2.NowUnix()
+	job := &org.ImportJob{
+		ID:        id2.ShortID(0),
+		FileName:  r.FileName,
+		UseStatus: r.UseStatus,
+		IsUpdate:  r.IsUpdate,
+		CreateDep: r.CreateDep,
+		Status:    consts.ImportJobRunning,
+		Total:     len(rows),
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: profile.UserID,
+	}
+	err = j.jobRepo.Insert(c, j.DB, job)
+	if err != nil {
+		return nil, err
+	}
+	// the request context ends with the response, the job only keeps the tenant
+	ctx := header2.SetContext(context.Background(), TenantID, r.TenantID)
+	go j.run(ctx, job, rows, profile.UserID, r)
+	return &SubmitImportJobResponse{
+		ID:    job.ID,
+		Total: job.Total,
+	}, nil
+}
+
+// run screen, insert and update the rows batch by batch like ImportFile does at once
+func (j *importJob) run(ctx context.Context, job *org.ImportJob, rows []map[string]interface{}, createBy string, r *ImportFileRequest) {
+	fails := make([]map[string]interface{}, 0)
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Logger.Error("import job ", job.ID, err)
+			job.Status = consts.ImportJobFailed
+			job.Message = fmt.Sprint(err)
+		}
+		j.finish(ctx, job, fails)
+	}()
+
+	suc, screenFails, created := j.user.screenUserData(ctx, rows, createBy, r)
+	fails = append(fails, screenFails...)
+	job.CreatedDeps = createdPaths(created)
+	if search := GetSearch(); search != nil && len(created) > 0 {
+		search.PushDep(ctx, nil)
+	}
+	job.Processed = len(screenFails)
+	job.FailTotal = len(screenFails)
+
+	updates := make([]map[string]interface{}, 0)
+	for start := 0; start < len(suc); start += j.batch {
+		if j.canceled(ctx, job) {
+			return
+		}
+		end := start + j.batch
+		if end > len(suc) {
+			end = len(suc)
+		}
+		added, fail, update, users := j.user.insertList(ctx, suc[start:end], createBy, r)
+		updates = append(updates, update...)
+		fails = append(fails, fail...)
+		job.AddTotal += len(added)
+		job.FailTotal += len(fail)
+		// rows to update are counted once updated
+		job.Processed += len(added) + len(fail)
+		j.push(ctx, users)
+		j.progress(job)
+	}
+	for start := 0; start < len(updates); start += j.batch {
+		if j.canceled(ctx, job) {
+			return
+		}
+		end := start + j.batch
+		if end > len(updates) {
+			end = len(updates)
+		}
+		updated, fail, users := j.user.updateList(ctx, updates[start:end], r.TenantID)
+		fails = append(fails, fail...)
+		job.UpdateTotal += len(updated)
+		job.FailTotal += len(fail)
+		job.Processed += len(updated) + len(fail)
+		j.push(ctx, users)
+		j.progress(job)
+	}
+	job.Status = consts.ImportJobFinished
+}
+
+func (j *importJob) canceled(ctx context.Context, job *org.ImportJob) bool {
+	one := j.jobRepo.Get(ctx, j.DB, job.ID)
+	if one != nil && one.Status == consts.ImportJobCanceled {
+		job.Status = consts.ImportJobCanceled
+		return true
+	}
+	return false
+}
+
+func (j *importJob) progress(job *org.ImportJob) {
+	job.UpdatedAt = time2.NowUnix()
+	if err := j.jobRepo.Progress(j.DB, job); err != nil {
+		logger.Logger.Error("import job progress ", job.ID, err)
+	}
+}
+
+func (j *importJob) push(ctx context.Context, users []*org.User) {
+	if search := GetSearch(); search != nil && len(users) > 0 {
+		search.PushUser(ctx, nil, users...)
+	}
+}
+
+func (j *importJob) finish(ctx context.Context, job *org.ImportJob, fails []map[string]interface{}) {
+	if len(fails) > 0 {
+		data, err := j.failFile(ctx, fails)
+		if err != nil {
+			logger.Logger.Error("import job fail file ", job.ID, err)
+		}
+		job.FailFile = data
+	}
+	now := time2.NowUnix()
+	job.FinishedAt = now
+	job.UpdatedAt = now
+	if err := j.jobRepo.Finish(j.DB, job); err != nil {
+		logger.Logger.Error("import job finish ", job.ID, err)
+	}
+}
+
+// failFile failed rows in the layout of the template with their remark as the last column,
+// fixed rows can be imported again
+func (j *importJob) failFile(ctx context.Context, fails []map[string]interface{}) ([]byte, error) {
+	xlsxFields := j.user.columnRepo.GetXlsxField(ctx, j.DB, consts.FieldAdminStatus)
+	names := make([]string, 0, len(xlsxFields)+1)
+	for k, v := range xlsxFields {
+		if v != consts.ID {
+			names = append(names, k)
+		}
+	}
+	xlsxFields[consts.OwnerDepName] = consts.DEPNAME
+	names = append(names, consts.OwnerDepName)
+	sort.Strings(names)
+	xlsxFields[consts.RemarkName] = consts.REMARK
+	names = append(names, consts.RemarkName)
+
+	newFile := xlsx.NewFile()
+	sheet, err := newFile.AddSheet("sheet1")
+	if err != nil {
+		return nil, err
+	}
+	row := sheet.AddRow()
+	for k := range names {
+		row.AddCell().SetValue(names[k])
+	}
+	for _, fail := range fails {
+		row := sheet.AddRow()
+		for k := range names {
+			cell := row.AddCell()
+			if v, ok := fail[xlsxFields[names[k]]]; ok && v != nil {
+				cell.SetValue(fmt.Sprint(v))
+			}
+		}
+	}
+	buffer := new(bytes.Buffer)
+	err = newFile.Write(buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// ImportJobInfoRequest progress of a job
+type ImportJobInfoRequest struct {
+	ID string `json:"id" form:"id" binding:"required"`
+}
+
+// Info status and counters of a job of current tenant
+func (j *importJob) Info(c context.Context, r *ImportJobInfoRequest) (*org.ImportJob, error) {
+	one := j.jobRepo.Get(c, j.DB, r.ID)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	return one, nil
+}
+
+// CancelImportJobRequest cancel a job
+type CancelImportJobRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// CancelImportJobResponse cancel response
+type CancelImportJobResponse struct {
+}
+
+// Cancel stop a running job before its next batch, rows already imported are kept
+func (j *importJob) Cancel(c context.Context, r *CancelImportJobRequest) (*CancelImportJobResponse, error) {
+	one := j.jobRepo.Get(c, j.DB, r.ID)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	if one.Status != consts.ImportJobRunning {
+		return nil, error2.New(code.ImportJobEnded)
+	}
+	err := j.jobRepo.Cancel(j.DB, r.ID, time2.NowUnix())
+	if err != nil {
+		return nil, err
+	}
+	return &CancelImportJobResponse{}, nil
+}
+
+// ImportJobFailFileRequest failed rows of a job
+type ImportJobFailFileRequest struct {
+	ID string `json:"id" form:"id" binding:"required"`
+}
+
+// ImportJobFailFileResponse xlsx of the failed rows
+type ImportJobFailFileResponse struct {
+	Data     []byte `json:"data"`
+	FileName string `json:"fileName"`
+}
+
+// FailFile xlsx of the failed rows, it is ready once the job ended
+func (j *importJob) FailFile(c context.Context, r *ImportJobFailFileRequest) (*ImportJobFailFileResponse, error) {
+	one := j.jobRepo.Get(c, j.DB, r.ID)
+	if one == nil || len(one.FailFile) == 0 {
+		return nil, error2.New(code.DataNotExist)
+	}
+	name := strings.TrimSuffix(one.FileName, filepath.Ext(one.FileName))
+	if name == "" {
+		name = one.ID
+	}
+	return &ImportJobFailFileResponse{
+		Data:     one.FailFile,
+		FileName: name + "_fail.xlsx",
+	}, nil
+}
+
+// ListImportJobRequest list import jobs
+type ListImportJobRequest struct {
+	Page  int `json:"page" form:"page"`
+	Limit int `json:"limit" form:"limit"`
+}
+
+// PageList import jobs of current tenant, newest first
+func (j *importJob) PageList(c context.Context, r *ListImportJobRequest) (*page.Page, error) {
+	list, total := j.jobRepo.PageList(c, j.DB, r.Page, r.Limit)
+	if list == nil {
+		list = make([]org.ImportJob, 0)
+	}
+	return &page.Page{
+		Data:       list,
+		TotalCount: total,
+	}, nil
+}
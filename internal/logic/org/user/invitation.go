@@ -0,0 +1,321 @@
+package user
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+
+	"gorm.io/gorm"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/encode2"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/page"
+	"github.com/quanxiang-cloud/organizations/pkg/verification"
+)
+
+const (
+	// defaultInvitationExpire seconds
+	defaultInvitationExpire = 3 * 24 * 60 * 60
+)
+
+// Invitation pending invitations of users, admins resend or revoke them
+type Invitation interface {
+	Resend(c context.Context, r *ResendInvitationRequest) (*ResendInvitationResponse, error)
+	Revoke(c context.Context, r *RevokeInvitationRequest) (*RevokeInvitationResponse, error)
+	PageList(c context.Context, r *ListInvitationRequest) (*page.Page, error)
+}
+
+type invitation struct {
+	DB             *gorm.DB
+	conf           configs.Config
+	userRepo       org.UserRepo
+	invitationRepo org.InvitationRepo
+	message        message.Message
+}
+
+// NewInvitation new
+func NewInvitation(conf configs.Config, db *gorm.DB) Invitation {
+	return newInvitation(conf, db)
+}
+
+func newInvitation(conf configs.Config, db *gorm.DB) *invitation {
+	return &invitation{
+		DB:             db,
+		conf:           conf,
+		userRepo:       mysql2.NewUserRepo(),
+		invitationRepo: mysql2.NewInvitationRepo(),
+		message:        message.NewMessage(conf.InternalNet),
+	}
+}
+
+// InvitationEnabled invitations replace mailed passwords once a secret is set
+func InvitationEnabled(conf configs.Config) bool {
+	return conf.Invitation.Secret != "" && !conf.Invitation.SendPassword
+}
+
+// HashInvitationNonce invitations keep the hash of their nonce only
+func HashInvitationNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// expireAt milliseconds a new or resent invitation expires at
+func (i *invitation) expireAt(now int64) int64 {
+	expire := int64(i.conf.Invitation.Expire)
+	if expire <= 0 {
+		expire = defaultInvitationExpire
+	}
+	return now + expire*1000
+}
+
+// issue revoke pending invitations of the user and create a new one in tx, it returns the token to send
+func (i *invitation) issue(c context.Context, tx *gorm.DB, userID, sendTo string, sendChannel int, createdBy string) (*org.Invitation, string, error) {
+	now := time2.NowUnix()
+	err := i.invitationRepo.Revoke(tx, createdBy, now, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	nonce := id2.HexUUID(true)
+	one := &org.Invitation{
+		ID:          id2.HexUUID(true),
+		UserID:      userID,
+		SendTo:      sendTo,
+		SendChannel: sendChannel,
+		NonceHash:   HashInvitationNonce(nonce),
+		Status:      consts.InvitationPending,
+		ExpireAt:    i.expireAt(now),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   createdBy,
+		UpdatedBy:   createdBy,
+	}
+	err = i.invitationRepo.Insert(c, tx, one)
+	if err != nil {
+		return nil, "", err
+	}
+	return one, encode2.SignToken(i.conf.Invitation.Secret, one.ID, nonce), nil
+}
+
+// send mail the link to send to or the email of the user, text it when phone channel is on
+func (i *invitation) send(u *org.User, one *org.Invitation, token string) {
+	email, phone := u.SelfEmail, u.Phone
+	if email == "" {
+		email = u.Email
+	}
+	if verification.CheckPhone(one.SendTo) {
+		phone = one.SendTo
+	} else if one.SendTo != "" {
+		email = one.SendTo
+	}
+	keyAndValue := map[string]string{
+		"name":    u.Name,
+		"account": u.Email,
+		"link":    i.conf.Invitation.URL + url.QueryEscape(token),
+	}
+	reqs := make([]*message.CreateReq, 0)
+	if one.SendChannel&SENDEMAIL != 0 && email != "" {
+		mesReq := new(message.CreateReq)
+		mesReq.Email = &message.Email{
+			To: []string{email},
+			Content: &message.Content{
+				TemplateID:  i.conf.MessageTemplate.Invitation,
+				KeyAndValue: keyAndValue,
+			},
+		}
+		reqs = append(reqs, mesReq)
+	}
+	if one.SendChannel&SENDPHONE != 0 && phone != "" {
+		mesReq := new(message.CreateReq)
+		mesReq.Phone = &message.Phone{
+			To: []string{phone},
+			Content: &message.Content{
+				TemplateID:  i.conf.MessageTemplate.Invitation,
+				KeyAndValue: keyAndValue,
+			},
+		}
+		reqs = append(reqs, mesReq)
+	}
+	if len(reqs) == 0 {
+		return
+	}
+	// sent after the response, only the tenant of the invitation is kept
+	ctx := header2.SetContext(context.Background(), TenantID, one.TenantID)
+	go func() {
+		err := i.message.SendMessage(ctx, reqs)
+		if err != nil {
+			logger.Logger.Error(err)
+		}
+	}()
+}
+
+// get invitation of current tenant
+func (i *invitation) get(c context.Context, id string) *org.Invitation {
+	one := i.invitationRepo.Get(i.DB, id)
+	_, tenantID := ginheader.GetTenantID(c).Wreck()
+	if one == nil || one.TenantID != tenantID {
+		return nil
+	}
+	return one
+}
+
+// ResendInvitationRequest resend invitation request
+type ResendInvitationRequest struct {
+	ID      string `json:"id" binding:"required,max=64"`
+	Profile header2.Profile
+}
+
+// ResendInvitationResponse resend invitation response
+type ResendInvitationResponse struct {
+	ID       string `json:"id"`
+	ExpireAt int64  `json:"expireAt"`
+}
+
+// Resend send a pending invitation again with a new link and lifetime, the old link stops working
+func (i *invitation) Resend(c context.Context, r *ResendInvitationRequest) (*ResendInvitationResponse, error) {
+	one := i.get(c, r.ID)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	if one.Status != consts.InvitationPending {
+		return nil, error2.New(code.InvalidInvitation)
+	}
+	u := i.userRepo.Get(c, i.DB, one.UserID)
+	if u == nil || u.UseStatus != consts.NormalStatus {
+		return nil, error2.New(code.InvalidAccount)
+	}
+	nonce := id2.HexUUID(true)
+	now := time2.NowUnix()
+	one.NonceHash = HashInvitationNonce(nonce)
+	one.ExpireAt = i.expireAt(now)
+	one.UpdatedAt = now
+	one.UpdatedBy = r.Profile.UserID
+	err := i.invitationRepo.Update(i.DB, one)
+	if err != nil {
+		return nil, err
+	}
+	i.send(u, one, encode2.SignToken(i.conf.Invitation.Secret, one.ID, nonce))
+	return &ResendInvitationResponse{
+		ID:       one.ID,
+		ExpireAt: one.ExpireAt,
+	}, nil
+}
+
+// RevokeInvitationRequest revoke invitation request
+type RevokeInvitationRequest struct {
+	ID      string `json:"id" binding:"required,max=64"`
+	Profile header2.Profile
+}
+
+// RevokeInvitationResponse revoke invitation response
+type RevokeInvitationResponse struct {
+}
+
+// Revoke close a pending invitation
+func (i *invitation) Revoke(c context.Context, r *RevokeInvitationRequest) (*RevokeInvitationResponse, error) {
+	one := i.get(c, r.ID)
+	if one == nil {
+		return nil, error2.New(code.DataNotExist)
+	}
+	if one.Status != consts.InvitationPending {
+		return nil, error2.New(code.InvalidInvitation)
+	}
+	one.Status = consts.InvitationRevoked
+	one.UpdatedAt = time2.NowUnix()
+	one.UpdatedBy = r.Profile.UserID
+	err := i.invitationRepo.Update(i.DB, one)
+	if err != nil {
+		return nil, err
+	}
+	return &RevokeInvitationResponse{}, nil
+}
+
+// ListInvitationRequest list invitation request
+type ListInvitationRequest struct {
+	//1:pending,2:accepted,-1:revoked, default pending
+	Status int `json:"status" form:"status"`
+	Page   int `json:"page" form:"page"`
+	Limit  int `json:"limit" form:"limit"`
+}
+
+// InvitationResponse invitation with its user
+type InvitationResponse struct {
+	ID          string `json:"id"`
+	UserID      string `json:"userID"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	SendTo      string `json:"sendTo"`
+	SendChannel int    `json:"sendChannel"`
+	Status      int    `json:"status"`
+	ExpireAt    int64  `json:"expireAt"`
+	Expired     bool   `json:"expired"`
+	AcceptedAt  int64  `json:"acceptedAt"`
+	CreatedAt   int64  `json:"createdAt"`
+	CreatedBy   string `json:"createdBy"`
+}
+
+// PageList invitations of current tenant, pending ones by default
+func (i *invitation) PageList(c context.Context, r *ListInvitationRequest) (*page.Page, error) {
+	if r.Status == 0 {
+		r.Status = consts.InvitationPending
+	}
+	list, total := i.invitationRepo.PageList(c, i.DB, r.Status, r.Page, r.Limit)
+	userIDs := make([]string, 0, len(list))
+	for k := range list {
+		userIDs = append(userIDs, list[k].UserID)
+	}
+	users := make(map[string]*org.User)
+	if len(userIDs) > 0 {
+		for _, v := range i.userRepo.List(c, i.DB, userIDs...) {
+			users[v.ID] = v
+		}
+	}
+	now := time2.NowUnix()
+	res := make([]InvitationResponse, 0, len(list))
+	for k := range list {
+		one := InvitationResponse{
+			ID:          list[k].ID,
+			UserID:      list[k].UserID,
+			SendTo:      list[k].SendTo,
+			SendChannel: list[k].SendChannel,
+			Status:      list[k].Status,
+			ExpireAt:    list[k].ExpireAt,
+			Expired:     list[k].Status == consts.InvitationPending && list[k].ExpireAt <= now,
+			AcceptedAt:  list[k].AcceptedAt,
+			CreatedAt:   list[k].CreatedAt,
+			CreatedBy:   list[k].CreatedBy,
+		}
+		if u, ok := users[list[k].UserID]; ok {
+			one.Name = u.Name
+			one.Email = u.Email
+		}
+		res = append(res, one)
+	}
+	return &page.Page{
+		Data:       res,
+		TotalCount: total,
+	}, nil
+}
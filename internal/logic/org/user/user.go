@@ -21,6 +21,7 @@ import (
 	"github.com/quanxiang-cloud/organizations/internal/logic/org/department"
 	"github.com/quanxiang-cloud/organizations/pkg/goalie"
 	"github.com/tealeg/xlsx"
+	"io"
 	"net/http"
 	"sort"
 	"strings"
@@ -65,7 +66,9 @@ type User interface {
 	Register(c context.Context, r *RegisterRequest) (*RegisterResponse, error)
 	GetUsersByIDs(c context.Context, r *GetUsersByIDsRequest) (*GetUsersByIDsResponse, error)
 	ImportFile(c context.Context, file []byte, profile header2.Profile, r *ImportFileRequest) (*ImportFileResponse, error)
+	PreviewImport(c context.Context, file []byte, r *ImportFileRequest) (*PreviewImportResponse, error)
 	Template(c context.Context, r *GetTemplateFileRequest) (*GetTemplateFileResponse, error)
+	Export(c context.Context, r *ExportRequest, w io.Writer) error
 }
 
 type user struct {
@@ -83,6 +86,9 @@ type user struct {
 	userTenantRepo org.UserTenantRelationRepo
 	landlord       landlord.Landlord
 	goalie         goalie.Goalie
+	hasher         encode2.Hasher
+	verifyCode     verification.Code
+	invitation     *invitation
 }
 
 // NewUser new
@@ -105,6 +111,9 @@ func NewUser(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient
 		userTenantRepo: mysql2.NewUserTenantRelationRepo(),
 		landlord:       landlord.NewLandlord(conf.InternalNet),
 		goalie:         goalie.NewGoalie(conf.InternalNet),
+		hasher:         encode2.NewHasher(conf.PasswordHash),
+		verifyCode:     verification.NewCode(conf.VerificationCode, redisClient),
+		invitation:     newInvitation(conf, db),
 	}
 }
 
@@ -278,7 +287,12 @@ func (u *user) Add(c context.Context, r *AddUserRequest) (res *AddUserResponse,
 	account := org.Account{}
 	account.Account = r.Email
 	account.ID = id2.ShortID(0)
-	account.Password = encode2.MD5Encode(r.Password)
+	account.Password, err = u.hasher.Hash(r.Password)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	account.PasswordChangedAt = nowUnix
 	account.UserID = id
 
 	account.CreatedBy = r.Profile.UserID
@@ -304,12 +318,28 @@ func (u *user) Add(c context.Context, r *AddUserRequest) (res *AddUserResponse,
 	if err != nil {
 		logger.Logger.Error(err)
 	}
+	// the invitee sets the password, the generated one is never sent
+	var invite *org.Invitation
+	token := ""
+	if r.SendMessage.SendChannel != NO && InvitationEnabled(u.conf) {
+		invite, token, err = u.invitation.issue(c, tx, id, r.SendMessage.SendTo, r.SendMessage.SendChannel, r.Profile.UserID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
 	tx.Commit()
 	//send message
-	if r.SendMessage.SendChannel != NO {
+	if invite != nil {
+		u.invitation.send(addData, invite, token)
+	} else if r.SendMessage.SendChannel != NO {
 		m := make(map[string]string)
 		m[id] = r.Password
-		SendAccountAndPWDOrCode(c, u.message, "", r.SendMessage.SendTo, u.conf.MessageTemplate.NewPWD, r.Password, r.SendMessage.SendChannel)
+		phone := addData.Phone
+		if verification.CheckPhone(r.SendMessage.SendTo) {
+			phone = r.SendMessage.SendTo
+		}
+		SendAccountAndPWDOrCode(c, u.message, "", r.SendMessage.SendTo, phone, u.conf.MessageTemplate.NewPWD, r.Password, r.SendMessage.SendChannel)
 	}
 	adminUser.Users = append(adminUser.Users, addData)
 	return &adminUser, err
@@ -610,6 +640,14 @@ func (u *user) PageList(c context.Context, r *SearchListUserRequest) (*page.Page
 }
 
 func (u *user) getUsersPageList(c context.Context, r *SearchListUserRequest) ([]*org.User, int64) {
+	userIDs, _ := u.filterUserIDs(c, r)
+	list, total := u.userRepo.PageList(c, u.DB, consts.NormalStatus, r.Page, r.Limit, userIDs)
+
+	return list, total
+}
+
+// filterUserIDs users of the departments of the request, filtered is false when it asks for no department
+func (u *user) filterUserIDs(c context.Context, r *SearchListUserRequest) (userIDs []string, filtered bool) {
 	depIDs := make([]string, 0)
 	if len(r.DepIDs) > 0 {
 		depIDs = append(depIDs, r.DepIDs...)
@@ -622,17 +660,14 @@ func (u *user) getUsersPageList(c context.Context, r *SearchListUserRequest) ([]
 			}
 		}
 	}
-	var userIDs = make([]string, 0)
+	userIDs = make([]string, 0)
 	if len(depIDs) > 0 {
 		relations := u.userDepRepo.SelectByDEPID(u.DB, depIDs...)
 		for k := range relations {
 			userIDs = append(userIDs, relations[k].UserID)
 		}
 	}
-
-	list, total := u.userRepo.PageList(c, u.DB, consts.NormalStatus, r.Page, r.Limit, userIDs)
-
-	return list, total
+	return userIDs, len(depIDs) > 0
 }
 
 // DepOneResponse response
@@ -867,7 +902,9 @@ type StatusRequest struct {
 	//1:normal，-2:invalid，-1:del，2:active,-3:no word
 	UseStatus int    `json:"useStatus" binding:"required,max=64"`
 	UpdatedBy string `json:"updatedBy"`
-	Profile   header2.Profile
+	//channel of the activation message, 1:email,2:phone,3:both, default email
+	SendChannel int `json:"sendChannel"`
+	Profile     header2.Profile
 }
 
 // StatusResponse response
@@ -909,7 +946,12 @@ func (u *user) UpdateUserStatus(c context.Context, r *StatusRequest) (*StatusRes
 	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
 	if r.UseStatus == consts.ActiveStatus {
 		pwd = random2.RandomString(int(info.PwdMinLen), info.PwdType)
-		account.Password = encode2.MD5Encode(pwd)
+		account.Password, err = u.hasher.Hash(pwd)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		account.PasswordChangedAt = nowUnix
 	}
 
 	err = u.accountReo.Update(u.DB, &account)
@@ -917,6 +959,14 @@ func (u *user) UpdateUserStatus(c context.Context, r *StatusRequest) (*StatusRes
 		err = u.userDepRepo.DeleteByUserIDs(tx, r.ID)
 		err = u.userRepo.UpdateByID(c, tx, old)
 		err = u.accountReo.DeleteByUserID(tx, r.ID)
+		if err == nil {
+			err = u.invitation.invitationRepo.Revoke(tx, r.Profile.UserID, nowUnix, r.ID)
+		}
+	}
+	var invite *org.Invitation
+	token := ""
+	if err == nil && r.UseStatus == consts.ActiveStatus && InvitationEnabled(u.conf) {
+		invite, token, err = u.invitation.issue(c, tx, r.ID, "", activeSendChannel(r.SendChannel), r.Profile.UserID)
 	}
 
 	if err != nil {
@@ -924,6 +974,11 @@ func (u *user) UpdateUserStatus(c context.Context, r *StatusRequest) (*StatusRes
 		return nil, err
 	}
 	tx.Commit()
+	if invite != nil {
+		u.invitation.send(old, invite, token)
+	} else if pwd != "" {
+		SendAccountAndPWDOrCode(c, u.message, "", old.SelfEmail, old.Phone, u.conf.MessageTemplate.NewPWD, pwd, activeSendChannel(r.SendChannel))
+	}
 	if r.UseStatus == consts.DelStatus {
 		delRequest := &goalie.OthDelRequest{
 			IDs:   []string{r.ID},
@@ -944,6 +999,8 @@ type ListStatusRequest struct {
 	//1:normal，-2:invalid，-1:del，2:active,-3:no word
 	UseStatus int    `json:"useStatus" binding:"required"`
 	UpdatedBy string `json:"updatedBy"`
+	//channel of the activation message, 1:email,2:phone,3:both, default email
+	SendChannel int `json:"sendChannel"`
 }
 
 // ListStatusResponse update list user status response
@@ -958,6 +1015,8 @@ func (u *user) UpdateUsersStatus(c context.Context, r *ListStatusRequest) (*List
 	}
 	info := systems.GetSecurityInfo(c, u.conf, u.redisClient)
 	pwds := make(map[string]string)
+	invites := make(map[string]*org.Invitation)
+	tokens := make(map[string]string)
 	users := make([]*org.User, 0)
 	ids := make([]string, 0)
 	for _, v := range r.IDS {
@@ -992,10 +1051,18 @@ func (u *user) UpdateUsersStatus(c context.Context, r *ListStatusRequest) (*List
 		account.UpdatedAt = nowUnix
 		if r.UseStatus == consts.ActiveStatus {
 			pwd := random2.RandomString(int(info.PwdMinLen), info.PwdType)
-			account.Password = encode2.MD5Encode(pwd)
+			account.Password, err = u.hasher.Hash(pwd)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			account.PasswordChangedAt = nowUnix
 			pwds[account.ID] = pwd
 		}
 		err = u.accountReo.Update(u.DB, &account)
+		if err == nil && r.UseStatus == consts.ActiveStatus && InvitationEnabled(u.conf) {
+			invites[v], tokens[v], err = u.invitation.issue(c, tx, v, "", activeSendChannel(r.SendChannel), r.UpdatedBy)
+		}
 
 		if err != nil {
 			tx.Rollback()
@@ -1024,8 +1091,13 @@ func (u *user) UpdateUsersStatus(c context.Context, r *ListStatusRequest) (*List
 		if r.UseStatus == consts.ActiveStatus {
 			users := u.userRepo.List(c, u.DB, r.IDS...)
 			for k := range users {
+				if invite, ok := invites[users[k].ID]; ok {
+					u.invitation.send(users[k], invite, tokens[users[k].ID])
+					continue
+				}
 				pwd := pwds[users[k].ID]
-				SendAccountAndPWDOrCode(c, u.message, "", users[k].SelfEmail, u.conf.MessageTemplate.NewPWD, pwd, 1)
+				sendType := activeSendChannel(r.SendChannel)
+				SendAccountAndPWDOrCode(c, u.message, "", users[k].SelfEmail, users[k].Phone, u.conf.MessageTemplate.NewPWD, pwd, sendType)
 			}
 		}
 	}()
@@ -1033,6 +1105,14 @@ func (u *user) UpdateUsersStatus(c context.Context, r *ListStatusRequest) (*List
 	return response, nil
 }
 
+// activeSendChannel channel the admin chose for activation, email when none
+func activeSendChannel(sendChannel int) int {
+	if sendChannel&(SENDEMAIL|SENDPHONE) == 0 {
+		return SENDEMAIL
+	}
+	return sendChannel & (SENDEMAIL | SENDPHONE)
+}
+
 // ChangeUsersDEPRequest change user dep request
 type ChangeUsersDEPRequest struct {
 	UsersID  []string `json:"usersID"  binding:"required"`
@@ -1268,6 +1348,8 @@ func FindDepToTop(AllDep map[string]*org.Department, depID string, res []DepOneR
 
 // GetTemplateFileRequest temp file
 type GetTemplateFileRequest struct {
+	//xlsx or csv, default xlsx
+	Format string `json:"format" form:"format"`
 }
 
 // GetTemplateFileResponse temp file
@@ -1283,12 +1365,6 @@ func (u *user) Template(c context.Context, r *GetTemplateFileRequest) (*GetTempl
 	if xlsxFields == nil || len(xlsxFields) == 0 {
 		return nil, error2.New(code.FieldNameIsNull)
 	}
-	newFile := xlsx.NewFile()
-	sheet, err := newFile.AddSheet("sheet1")
-	if err != nil {
-		return nil, err
-	}
-	row := sheet.AddRow()
 	s := make([]string, 0)
 	for k, v := range xlsxFields {
 		if v != consts.ID {
@@ -1297,6 +1373,15 @@ func (u *user) Template(c context.Context, r *GetTemplateFileRequest) (*GetTempl
 	}
 	s = append(s, consts.OwnerDepName)
 	sort.Strings(s)
+	if r.Format == FormatCSV {
+		return u.csvTemplate(s)
+	}
+	newFile := xlsx.NewFile()
+	sheet, err := newFile.AddSheet("sheet1")
+	if err != nil {
+		return nil, err
+	}
+	row := sheet.AddRow()
 	for k := range s {
 		cell := row.AddCell()
 		cell.SetValue(s[k])
@@ -1341,7 +1426,7 @@ func (u *user) IndexCount(c context.Context, r *IndexCountRequest) (*IndexCountR
 }
 
 // SendAccountAndPWDOrCode sendType 第一位发邮件，第二位发手机
-func SendAccountAndPWDOrCode(c context.Context, messageClient message.Message, email, selfEmail, messageTemple, data string, sendType int) {
+func SendAccountAndPWDOrCode(c context.Context, messageClient message.Message, email, selfEmail, phone, messageTemple, data string, sendType int) {
 	emailReq := make([]*message.CreateReq, 0)
 	//send email
 	if sendType&1 == 1 {
@@ -1372,9 +1457,25 @@ func SendAccountAndPWDOrCode(c context.Context, messageClient message.Message, e
 		emailReq = append(emailReq, mesReq)
 	}
 	//send phone
-	if sendType>>1&1 == 1 {
+	if sendType>>1&1 == 1 && phone != "" {
 		mesReq := new(message.CreateReq)
-		mesReq.Phone = &message.Phone{}
+		keyAndValue := map[string]string{
+			"code": data,
+		}
+		if email != "" {
+			keyAndValue["account"] = email
+		}
+		mesReq.Phone = &message.Phone{
+			To: []string{phone},
+			Content: &message.Content{
+				TemplateID:  messageTemple,
+				KeyAndValue: keyAndValue,
+			},
+		}
+		emailReq = append(emailReq, mesReq)
+	}
+	if len(emailReq) == 0 {
+		return
 	}
 	go func() {
 		err := messageClient.SendMessage(c, emailReq)
@@ -1418,12 +1519,9 @@ type RegisterResponse struct {
 
 // Register register
 func (u *user) Register(c context.Context, r *RegisterRequest) (*RegisterResponse, error) {
-	val := u.redisClient.Get(c, u.conf.VerificationCode.RegisterCode+":"+r.Email).Val()
-	if val == "" {
-		return nil, error2.New(code.ExpireVerificationCode)
-	}
-	if val != r.Code {
-		return nil, error2.New(code.InvalidVerificationCode)
+	err := u.verifyCode.Verify(c, u.conf.VerificationCode.RegisterCode, r.Email, r.Code)
+	if err != nil {
+		return nil, err
 	}
 	id := id2.HexUUID(true)
 	nowUnix := time2.NowUnix()
@@ -1472,7 +1570,12 @@ func (u *user) Register(c context.Context, r *RegisterRequest) (*RegisterRespons
 	account.Account = r.Email
 	account.ID = id2.ShortID(0)
 	account.UserID = id
-	account.Password = encode2.MD5Encode(r.Password)
+	account.Password, err = u.hasher.Hash(r.Password)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	account.PasswordChangedAt = nowUnix
 	account.CreatedBy = id
 	account.CreatedAt = nowUnix
 	account.UpdatedAt = nowUnix
@@ -1490,7 +1593,7 @@ func (u *user) Register(c context.Context, r *RegisterRequest) (*RegisterRespons
 	}
 
 	tx.Commit()
-
+	u.verifyCode.Del(c, u.conf.VerificationCode.RegisterCode, r.Email)
 	return &RegisterResponse{User: addData}, nil
 }
 
@@ -1569,7 +1672,13 @@ func (u *user) GetUsersByIDs(c context.Context, r *GetUsersByIDsRequest) (*GetUs
 type ImportFileRequest struct {
 	UseStatus int    `json:"useStatus" form:"useStatus" binding:"required,max=64"` //状态：1正常，-2禁用，-1删除，2激活==1 （与账号库相同）
 	IsUpdate  int    `json:"isUpdate" form:"isUpdate" `                            //1更新旧数据，-1不更新只插入新数据
+	Preview   bool   `json:"preview" form:"preview"`                               //只预览导入结果，不写入数据
+	Format    string `json:"format" form:"format"`                                 //xlsx、csv、ndjson，为空时按文件识别
+	Encoding  string `json:"encoding" form:"encoding"`                             //csv编码：utf-8、gbk、gb18030
+	Delimiter string `json:"delimiter" form:"delimiter"`                           //csv分隔符，默认逗号，tab为制表符
+	CreateDep int    `json:"createDep" form:"createDep"`                           //1按所在部门路径创建不存在的部门
 	TenantID  string `json:"tenantID"`
+	FileName  string `json:"-" form:"-"`
 }
 
 // ImportFileResponse 文件导入结果
@@ -1580,6 +1689,7 @@ type ImportFileResponse struct {
 	UpdateData         []map[string]interface{} `json:"updateData"`
 	FailTotal          int                      `json:"failTotal"`
 	FailUsers          []map[string]interface{} `json:"failUsers"`
+	CreatedDepartments []CreatedDepartment      `json:"createdDepartments"`
 	Users              []*org.User              `json:"-"`
 }
 
@@ -1587,12 +1697,12 @@ type ImportFileResponse struct {
 func (u *user) ImportFile(c context.Context, file []byte, profile header2.Profile, r *ImportFileRequest) (*ImportFileResponse, error) {
 	fail := make([]map[string]interface{}, 0)
 	//1、开始解析excel文件
-	suc1, err := u.makeDataFromExcl(c, file, r.TenantID)
+	suc1, err := u.makeData(c, file, r)
 	if err != nil {
 		return nil, err
 	}
 	//2、第二次遍历suc1中的数据，找出邮箱或者手机重复的，剩下的才能进行插入操作
-	suc2, fails := u.screenUserData(c, suc1, r)
+	suc2, fails, created := u.screenUserData(c, suc1, profile.UserID, r)
 	fail = append(fail, fails...)
 	//3、开始执行插入操作
 	suc, fail2, updates, userList := u.insertList(c, suc2, profile.UserID, r)
@@ -1615,12 +1725,98 @@ func (u *user) ImportFile(c context.Context, file []byte, profile header2.Profil
 		UpdateData:         updateSucs,
 		FailTotal:          len(fail),
 		FailUsers:          fail,
+		CreatedDepartments: created,
 		Users:              userList,
 	}
 
 	return &result, nil
 }
 
+// planned actions of a previewed row
+const (
+	ImportInsert = "insert"
+	ImportUpdate = "update"
+	ImportSkip   = "skip"
+	ImportFail   = "fail"
+)
+
+// PreviewImportRow planned action of a row
+type PreviewImportRow struct {
+	//row number in the sheet
+	Row    int                    `json:"row"`
+	Action string                 `json:"action"`
+	Reason string                 `json:"reason,omitempty"`
+	Data   map[string]interface{} `json:"data"`
+}
+
+// PreviewImportResponse what an import of the file would do
+type PreviewImportResponse struct {
+	InsertTotal int                `json:"insertTotal"`
+	UpdateTotal int                `json:"updateTotal"`
+	SkipTotal   int                `json:"skipTotal"`
+	FailTotal   int                `json:"failTotal"`
+	Rows        []PreviewImportRow `json:"rows"`
+	//departments the import would create
+	CreatedDepartments []CreatedDepartment `json:"createdDepartments"`
+}
+
+// PreviewImport parse, screen and classify the rows like ImportFile without writing anything,
+// existing users are skipped unless the import updates them
+func (u *user) PreviewImport(c context.Context, file []byte, r *ImportFileRequest) (*PreviewImportResponse, error) {
+	suc1, err := u.makeData(c, file, r)
+	if err != nil {
+		return nil, err
+	}
+	suc2, fails, created := u.screenUserData(c, suc1, "", r)
+	res := &PreviewImportResponse{
+		Rows:               make([]PreviewImportRow, 0, len(suc1)),
+		CreatedDepartments: created,
+	}
+	for k := range fails {
+		res.Rows = append(res.Rows, newPreviewImportRow(fails[k], ImportFail))
+	}
+	for k := range suc2 {
+		one := u.userRepo.SelectByEmailOrPhone(c, u.DB, suc2[k][consts.EMAIL].(string))
+		switch {
+		case one == nil:
+			res.Rows = append(res.Rows, newPreviewImportRow(suc2[k], ImportInsert))
+		case r.IsUpdate == isUpdate:
+			suc2[k][consts.ID] = one.ID
+			res.Rows = append(res.Rows, newPreviewImportRow(suc2[k], ImportUpdate))
+		default:
+			suc2[k][consts.REMARK] = consts.EmailPhoneExist
+			res.Rows = append(res.Rows, newPreviewImportRow(suc2[k], ImportSkip))
+		}
+	}
+	sort.SliceStable(res.Rows, func(i, j int) bool {
+		return res.Rows[i].Row < res.Rows[j].Row
+	})
+	for k := range res.Rows {
+		switch res.Rows[k].Action {
+		case ImportInsert:
+			res.InsertTotal++
+		case ImportUpdate:
+			res.UpdateTotal++
+		case ImportSkip:
+			res.SkipTotal++
+		case ImportFail:
+			res.FailTotal++
+		}
+	}
+	return res, nil
+}
+
+func newPreviewImportRow(data map[string]interface{}, action string) PreviewImportRow {
+	row, _ := data[consts.ROW].(int)
+	reason, _ := data[consts.REMARK].(string)
+	return PreviewImportRow{
+		Row:    row,
+		Action: action,
+		Reason: reason,
+		Data:   data,
+	}
+}
+
 // 1、从excl组装数据
 func (u *user) makeDataFromExcl(ctx context.Context, file []byte, tenantID string) ([]map[string]interface{}, error) {
 	xlFile, _ := xlsx.OpenBinary(file)
@@ -1628,7 +1824,7 @@ func (u *user) makeDataFromExcl(ctx context.Context, file []byte, tenantID strin
 		return nil, error2.New(code.InvalidFile)
 	}
 	suc1 := make([]map[string]interface{}, 0)
-	xlsxFields := u.columnRepo.GetXlsxField(ctx, u.DB, consts.FieldAdminStatus)
+	columns := u.importColumns(ctx)
 	departments, _ := u.depRepo.PageList(ctx, u.DB, consts.NormalStatus, 1, 10000)
 	depMap := make(map[string]string)
 	for _, v := range departments {
@@ -1642,13 +1838,10 @@ func (u *user) makeDataFromExcl(ctx context.Context, file []byte, tenantID strin
 			s := make(map[string]interface{})
 			for k1, v1 := range row.Cells {
 				fmt.Println("k1===", k1)
-				if cells0[k1].Value == consts.OwnerDepName {
-					s[consts.DEPNAME] = v1.Value
-				} else {
-					s[xlsxFields[cells0[k1].Value]] = v1.Value
-				}
+				s[columns[cells0[k1].Value]] = v1.Value
 			}
 			if len(s) > 0 {
+				s[consts.ROW] = k + 1
 				suc1 = append(suc1, s)
 			}
 
@@ -1657,8 +1850,8 @@ func (u *user) makeDataFromExcl(ctx context.Context, file []byte, tenantID strin
 	return suc1, nil
 }
 
-// 2、对数据进行组装，判断出需新增和更新的数据
-func (u *user) screenUserData(ctx context.Context, suc1 []map[string]interface{}, r *ImportFileRequest) (suc, fails []map[string]interface{}) {
+// 2、对数据进行组装，判断出需新增和更新的数据，需要时创建不存在的部门
+func (u *user) screenUserData(ctx context.Context, suc1 []map[string]interface{}, createBy string, r *ImportFileRequest) (suc, fails []map[string]interface{}, created []CreatedDepartment) {
 	m := make(map[string]int)
 	fail := make([]map[string]interface{}, 0)
 	suc2 := make([]map[string]interface{}, 0)
@@ -1678,6 +1871,10 @@ func (u *user) screenUserData(ctx context.Context, suc1 []map[string]interface{}
 	depRouter := department.NewDepartmentRouter()
 	list, _ := u.depRepo.PageList(ctx, u.DB, consts.NormalStatus, 1, 10000)
 	depRouter.AddRoute(list)
+	var creator *depCreator
+	if r.CreateDep == createDep {
+		creator = u.newDepCreator(ctx, list, createBy, r.Preview)
+	}
 A:
 	for k := range suc2 {
 		if m[suc2[k][consts.EMAIL].(string)] <= 1 && m[suc2[k][consts.PHONE].(string)] <= 1 {
@@ -1705,25 +1902,46 @@ A:
 					}
 				case consts.DEPNAME:
 					node := depRouter.GetRoute(suc2[k][k1].(string))
-					if node == nil {
+					if node != nil {
+						suc2[k][consts.DEPID] = node.DepID
+					} else if creator == nil {
 						suc2[k][consts.REMARK] = consts.NotDepartment
 						fail = append(fail, suc2[k])
 						continue A
 					}
-					suc2[k][consts.DEPID] = node.DepID
 				}
 			}
+			// departments are created for valid rows only
+			if path, ok := suc2[k][consts.DEPNAME].(string); ok && creator != nil && suc2[k][consts.DEPID] == nil {
+				depID, err := creator.resolve(path)
+				if err != nil {
+					logger.Logger.Error("import create department ", path, err)
+					suc2[k][consts.REMARK] = consts.CreateDepartmentFail
+					fail = append(fail, suc2[k])
+					continue
+				}
+				if depID == "" {
+					suc2[k][consts.REMARK] = consts.NotDepartment
+					fail = append(fail, suc2[k])
+					continue
+				}
+				suc2[k][consts.DEPID] = depID
+			}
 			suc3 = append(suc3, suc2[k])
 			continue
 		}
 		suc2[k][consts.REMARK] = consts.EmailPhoneRepeat
 		fail = append(fail, suc2[k])
 	}
-	return suc3, fail
+	if creator != nil {
+		created = creator.created
+	}
+	return suc3, fail, created
 }
 
 const (
-	isUpdate = 1
+	isUpdate  = 1
+	createDep = 1
 )
 
 func (u *user) insertList(ctx context.Context, suc2 []map[string]interface{}, createBy string, r *ImportFileRequest) (suc, fails, updates []map[string]interface{}, userList []*org.User) {
@@ -1782,14 +2000,22 @@ func (u *user) insertList(ctx context.Context, suc2 []map[string]interface{}, cr
 			continue
 		}
 		password := CreatePassword(ctx, u.conf, u.redisClient)
+		hashed, err := u.hasher.Hash(password)
+		if err != nil {
+			delete(suc2[k], consts.ID)
+			fail = append(fail, suc2[k])
+			tx.Rollback()
+			continue
+		}
 		account := org.Account{
-			ID:        id2.HexUUID(true),
-			Password:  encode2.MD5Encode(password),
-			Account:   u2.Email,
-			CreatedAt: nowUnix,
-			UpdatedAt: nowUnix,
-			CreatedBy: createBy,
-			UserID:    u2.ID,
+			ID:                id2.HexUUID(true),
+			Password:          hashed,
+			PasswordChangedAt: nowUnix,
+			Account:           u2.Email,
+			CreatedAt:         nowUnix,
+			UpdatedAt:         nowUnix,
+			CreatedBy:         createBy,
+			UserID:            u2.ID,
 		}
 		err = u.accountReo.Insert(tx, &account)
 		if err != nil {
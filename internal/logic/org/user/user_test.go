@@ -14,20 +14,31 @@ limitations under the License.
 package user
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/elliotchance/redismock/v8"
 	"github.com/go-redis/redis/v8"
 	"github.com/golang/mock/gomock"
 	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
 	"github.com/quanxiang-cloud/organizations/mock"
 	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/encode2"
 	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/verification"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"github.com/tealeg/xlsx"
+	"golang.org/x/text/encoding/simplifiedchinese"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
@@ -114,6 +125,7 @@ func (suite *UserSuite) TestAddUser() {
 		}},
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		accountReo:     accountRepo,
@@ -165,6 +177,7 @@ func (suite *UserSuite) TestUpdateUser() {
 		}},
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		accountReo:     accountRepo,
@@ -192,6 +205,7 @@ func (suite *UserSuite) TestUpdateAvatar() {
 		Avatar: "avatar",
 	}
 	suite.user = &user{
+		hasher:   encode2.NewHasher(configs.PasswordHash{}),
 		DB:       suite.db,
 		userRepo: userRepo,
 	}
@@ -219,6 +233,7 @@ func (suite *UserSuite) TestPageList() {
 		Limit: 100,
 	}
 	suite.user = &user{
+		hasher:      encode2.NewHasher(configs.PasswordHash{}),
 		DB:          suite.db,
 		userRepo:    userRepo,
 		userDepRepo: userDepRepo,
@@ -252,6 +267,7 @@ func (suite *UserSuite) TestAdminSelectByID() {
 		ID: "1",
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -287,6 +303,7 @@ func (suite *UserSuite) TestUserSelectByID() {
 		ID: "1",
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -321,6 +338,7 @@ func (suite *UserSuite) TestUpdateUserStatus() {
 		UseStatus: 1,
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -357,6 +375,7 @@ func (suite *UserSuite) TestUpdateUsersStatus() {
 		UseStatus: 1,
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -394,6 +413,7 @@ func (suite *UserSuite) TestAdminChangeUsersDEP() {
 		NewDepID: "2",
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -432,6 +452,7 @@ func (suite *UserSuite) TestOthGetOneUser() {
 		ID: "1",
 	}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -463,6 +484,7 @@ func (suite *UserSuite) TestTemplate() {
 
 	rq := &GetTemplateFileRequest{}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -495,6 +517,7 @@ func (suite *UserSuite) TestIndexCount() {
 
 	rq := &IndexCountRequest{}
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -537,6 +560,7 @@ func (suite *UserSuite) TestRegister() {
 	}
 
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -548,6 +572,7 @@ func (suite *UserSuite) TestRegister() {
 		landlord:       mockLandlord,
 		userTenantRepo: userTenantRepo,
 		conf:           suite.conf,
+		verifyCode:     verification.NewCode(suite.conf.VerificationCode, suite.redisClient),
 	}
 	suite.redisClient.SetEX(suite.Ctx, suite.conf.VerificationCode.RegisterCode+":"+rq.Email, "123456", suite.conf.VerificationCode.ExpireTime*time.Second)
 	res, err := suite.user.Register(suite.Ctx, rq)
@@ -578,6 +603,7 @@ func (suite *UserSuite) TestGetUsersByIDs() {
 	}
 
 	suite.user = &user{
+		hasher:         encode2.NewHasher(configs.PasswordHash{}),
 		DB:             suite.db,
 		userRepo:       userRepo,
 		userDepRepo:    userDepRepo,
@@ -593,3 +619,653 @@ func (suite *UserSuite) TestGetUsersByIDs() {
 	assert.Nil(suite.T(), err)
 	assert.NotNil(suite.T(), res)
 }
+
+func TestSendAccountAndPWDOrCode(t *testing.T) {
+	sink := message.NewSink()
+	SendAccountAndPWDOrCode(context.Background(), sink, "test1@test.com", "self@test.com", "13800000000", "newPWD", "654321Aa..", SENDEMAIL|SENDPHONE)
+	assert.Eventually(t, func() bool {
+		return len(sink.Messages()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	emails := sink.Emails("self@test.com")
+	assert.Len(t, emails, 1)
+	assert.Equal(t, "654321Aa..", emails[0].Content.KeyAndValue["code"])
+	phones := sink.Phones("13800000000")
+	assert.Len(t, phones, 1)
+	assert.Equal(t, "newPWD", phones[0].Content.TemplateID)
+	assert.Equal(t, "654321Aa..", phones[0].Content.KeyAndValue["code"])
+	assert.Equal(t, "test1@test.com", phones[0].Content.KeyAndValue["account"])
+
+	// phone channel without a number sends nothing
+	SendAccountAndPWDOrCode(context.Background(), sink, "", "", "", "newPWD", "654321Aa..", SENDPHONE)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, sink.Messages(), 2)
+}
+
+func TestInvitation(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	invitationRepo := mock.NewMockInvitationRepo(ctl)
+	userRepo := mock.NewMockUserRepo(ctl)
+	var saved org.Invitation
+	invitationRepo.EXPECT().Revoke(gomock.Any(), "admin", gomock.Any(), "1")
+	invitationRepo.EXPECT().Insert(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, tx *gorm.DB, req *org.Invitation) error {
+		saved = *req
+		return nil
+	})
+	invitationRepo.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(db *gorm.DB, id string) *org.Invitation {
+		one := saved
+		return &one
+	}).Times(2)
+	invitationRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(tx *gorm.DB, req *org.Invitation) error {
+		saved = *req
+		return nil
+	}).Times(2)
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), "1")
+
+	conf := configs.Config{}
+	conf.Invitation.Secret = "secret"
+	conf.Invitation.URL = "http://home/invitation?token="
+	sink := message.NewSink()
+	i := &invitation{
+		conf:           conf,
+		userRepo:       userRepo,
+		invitationRepo: invitationRepo,
+		message:        sink,
+	}
+	ctx := context.Background()
+	one, token, err := i.issue(ctx, nil, "1", "self@test.com", SENDEMAIL, "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, consts.InvitationPending, one.Status)
+	id, nonce, ok := encode2.ParseToken("secret", token)
+	assert.True(t, ok)
+	assert.Equal(t, one.ID, id)
+	assert.Equal(t, HashInvitationNonce(nonce), saved.NonceHash)
+
+	// resend replaces the nonce, the old link stops working
+	_, err = i.Resend(ctx, &ResendInvitationRequest{ID: one.ID})
+	assert.Nil(t, err)
+	assert.NotEqual(t, HashInvitationNonce(nonce), saved.NonceHash)
+	var link string
+	assert.Eventually(t, func() bool {
+		emails := sink.Emails("self@test.com")
+		if len(emails) == 1 {
+			link = emails[0].Content.KeyAndValue["link"]
+		}
+		return link != ""
+	}, time.Second, 10*time.Millisecond)
+	resent, err := url.QueryUnescape(strings.TrimPrefix(link, conf.Invitation.URL))
+	assert.Nil(t, err)
+	_, nonce, ok = encode2.ParseToken("secret", resent)
+	assert.True(t, ok)
+	assert.Equal(t, HashInvitationNonce(nonce), saved.NonceHash)
+
+	_, err = i.Revoke(ctx, &RevokeInvitationRequest{ID: one.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, consts.InvitationRevoked, saved.Status)
+}
+
+func TestImportJob(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	conn, _, err := sqlmock.New()
+	assert.Nil(t, err)
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		SkipInitializeWithVersion: true,
+		Conn:                      conn,
+	}), &gorm.Config{})
+	assert.Nil(t, err)
+	mr, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer mr.Close()
+
+	userRepo := mock.NewMockUserRepo(ctl)
+	userRepo.EXPECT().SelectByEmailOrPhone(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, db *gorm.DB, info string) *org.User {
+		if info == "test1@test.com" {
+			return &org.User{ID: "1"}
+		}
+		return nil
+	}).AnyTimes()
+	userRepo.EXPECT().Insert(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().UpdateByID(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	accountRepo.EXPECT().Insert(gomock.Any(), gomock.Any()).AnyTimes()
+	userDepRepo := mock.NewMockUserDepartmentRelationRepo(ctl)
+	userDepRepo.EXPECT().DeleteByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	userDepRepo.EXPECT().Add(gomock.Any(), gomock.Any()).AnyTimes()
+	depRepo := mock.NewMockDepartmentRepo(ctl)
+	depRepo.EXPECT().PageList(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	columnRepo := mock.NewMockUserTableColumnsRepo(ctl)
+	columnRepo.EXPECT().GetXlsxField(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	var saved org.ImportJob
+	progress := 0
+	jobRepo := mock.NewMockImportJobRepo(ctl)
+	jobRepo.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, db *gorm.DB, id string) *org.ImportJob {
+		one := saved
+		return &one
+	}).AnyTimes()
+	jobRepo.EXPECT().Progress(gomock.Any(), gomock.Any()).DoAndReturn(func(db *gorm.DB, req *org.ImportJob) error {
+		progress++
+		saved.Processed = req.Processed
+		return nil
+	}).AnyTimes()
+	jobRepo.EXPECT().Finish(gomock.Any(), gomock.Any()).DoAndReturn(func(db *gorm.DB, req *org.ImportJob) error {
+		saved = *req
+		return nil
+	}).AnyTimes()
+
+	conf := configs.Config{}
+	conf.Model = "debug"
+	j := &importJob{
+		DB: db,
+		user: &user{
+			DB:          db,
+			conf:        conf,
+			redisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+			hasher:      encode2.NewHasher(configs.PasswordHash{}),
+			userRepo:    userRepo,
+			accountReo:  accountRepo,
+			userDepRepo: userDepRepo,
+			depRepo:     depRepo,
+			columnRepo:  columnRepo,
+		},
+		jobRepo: jobRepo,
+		batch:   2,
+	}
+	rows := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{consts.NAME: "a", consts.EMAIL: "a@test.com", consts.SELFEMAIL: "a@self.com", consts.PHONE: "13600000001", consts.DEPNAME: "/test"},
+			{consts.NAME: "b", consts.EMAIL: "b", consts.SELFEMAIL: "b@self.com", consts.PHONE: "13600000002", consts.DEPNAME: "/test"},
+			{consts.NAME: "test1", consts.EMAIL: "test1@test.com", consts.SELFEMAIL: "test1@self.com", consts.PHONE: "13600000003", consts.DEPNAME: "/test"},
+			{consts.NAME: "c", consts.EMAIL: "c@test.com", consts.SELFEMAIL: "c@self.com", consts.PHONE: "13600000004", consts.DEPNAME: "/nope"},
+			{consts.NAME: "d", consts.EMAIL: "d@test.com", consts.SELFEMAIL: "d@self.com", consts.PHONE: "13600000005", consts.DEPNAME: "/test"},
+		}
+	}
+	r := &ImportFileRequest{UseStatus: consts.NormalStatus, IsUpdate: isUpdate}
+	ctx := context.Background()
+
+	saved = org.ImportJob{ID: "job", FileName: "users.xlsx", Status: consts.ImportJobRunning, Total: 5}
+	job := saved
+	j.run(ctx, &job, rows(), "admin", r)
+	assert.Equal(t, consts.ImportJobFinished, saved.Status)
+	assert.Equal(t, 5, saved.Processed)
+	assert.Equal(t, 2, saved.AddTotal)
+	assert.Equal(t, 1, saved.UpdateTotal)
+	assert.Equal(t, 2, saved.FailTotal)
+	// two insert batches and one update batch
+	assert.Equal(t, 3, progress)
+	res, err := j.FailFile(ctx, &ImportJobFailFileRequest{ID: "job"})
+	assert.Nil(t, err)
+	assert.Equal(t, "users_fail.xlsx", res.FileName)
+	failFile, err := xlsx.OpenBinary(res.Data)
+	assert.Nil(t, err)
+	failRows := failFile.Sheets[0].Rows
+	assert.Len(t, failRows, 3)
+	header := failRows[0].Cells
+	assert.Equal(t, consts.RemarkName, header[len(header)-1].Value)
+	remarks := []string{failRows[1].Cells[len(header)-1].Value, failRows[2].Cells[len(header)-1].Value}
+	assert.ElementsMatch(t, []string{consts.NotEmail, consts.NotDepartment}, remarks)
+
+	_, err = j.Cancel(ctx, &CancelImportJobRequest{ID: "job"})
+	assert.NotNil(t, err)
+
+	// a canceled job stops before its next batch
+	saved = org.ImportJob{ID: "job", Status: consts.ImportJobCanceled, Total: 5}
+	job = org.ImportJob{ID: "job", Status: consts.ImportJobRunning, Total: 5}
+	j.run(ctx, &job, rows(), "admin", r)
+	assert.Equal(t, consts.ImportJobCanceled, saved.Status)
+	assert.Equal(t, 0, saved.AddTotal)
+	assert.Equal(t, 2, saved.FailTotal)
+}
+
+func TestImportJobFailStale(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	var before, updatedAt int64
+	jobRepo := mock.NewMockImportJobRepo(ctl)
+	jobRepo.EXPECT().FailStale(gomock.Any(), gomock.Any(), gomock.Any(), consts.ImportJobInterrupted).DoAndReturn(func(db *gorm.DB, b, u int64, message string) (int64, error) {
+		before, updatedAt = b, u
+		return 0, nil
+	}).Times(2)
+	j := &importJob{jobRepo: jobRepo}
+
+	j.failStale(600)
+	assert.Equal(t, int64(600*1000), updatedAt-before)
+	j.failStale(0)
+	assert.Equal(t, int64(defaultStaleTime*1000), updatedAt-before)
+}
+
+type xlsxColumns struct {
+	org.UserTableColumnsRepo
+	configured []org.UserTableColumns
+}
+
+func (x xlsxColumns) GetFilter(ctx context.Context, db *gorm.DB, status, attr int) ([]org.UserTableColumns, map[string]string) {
+	return x.configured, nil
+}
+
+func (xlsxColumns) GetXlsxField(ctx context.Context, db *gorm.DB, status int) map[string]string {
+	return map[string]string{
+		"姓名":   consts.NAME,
+		"手机号":  consts.PHONE,
+		"邮箱":   consts.EMAIL,
+		"私人邮箱": consts.SELFEMAIL,
+	}
+}
+
+func TestPreviewImport(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	userRepo := mock.NewMockUserRepo(ctl)
+	userRepo.EXPECT().SelectByEmailOrPhone(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, db *gorm.DB, info string) *org.User {
+		if info == "test1@test.com" {
+			return &org.User{ID: "1"}
+		}
+		return nil
+	}).AnyTimes()
+	depRepo := mock.NewMockDepartmentRepo(ctl)
+	depRepo.EXPECT().PageList(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	u := &user{
+		userRepo:   userRepo,
+		depRepo:    depRepo,
+		columnRepo: xlsxColumns{},
+	}
+
+	file := xlsx.NewFile()
+	sheet, err := file.AddSheet("sheet1")
+	assert.Nil(t, err)
+	for _, values := range [][]string{
+		{"姓名", "邮箱", "私人邮箱", "手机号", consts.OwnerDepName},
+		{"a", "a@test.com", "a@self.com", "13600000001", "/test"},
+		{"b", "b", "b@self.com", "13600000002", "/test"},
+		{"test1", "test1@test.com", "test1@self.com", "13600000003", "/test"},
+		{"c", "c@test.com", "c@self.com", "13600000004", "/nope"},
+	} {
+		row := sheet.AddRow()
+		for _, v := range values {
+			row.AddCell().SetValue(v)
+		}
+	}
+	buffer := new(bytes.Buffer)
+	assert.Nil(t, file.Write(buffer))
+	ctx := context.Background()
+
+	res, err := u.PreviewImport(ctx, buffer.Bytes(), &ImportFileRequest{UseStatus: consts.NormalStatus, IsUpdate: isUpdate, FileName: "users.xlsx"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res.InsertTotal)
+	assert.Equal(t, 1, res.UpdateTotal)
+	assert.Equal(t, 0, res.SkipTotal)
+	assert.Equal(t, 2, res.FailTotal)
+	if assert.Len(t, res.Rows, 4) {
+		assert.Equal(t, 2, res.Rows[0].Row)
+		assert.Equal(t, ImportInsert, res.Rows[0].Action)
+		assert.Equal(t, consts.NotEmail, res.Rows[1].Reason)
+		assert.Equal(t, ImportUpdate, res.Rows[2].Action)
+		assert.Equal(t, "1", res.Rows[2].Data[consts.ID])
+		assert.Equal(t, 5, res.Rows[3].Row)
+		assert.Equal(t, consts.NotDepartment, res.Rows[3].Reason)
+	}
+
+	// without updating, existing users are left alone
+	res, err = u.PreviewImport(ctx, buffer.Bytes(), &ImportFileRequest{UseStatus: consts.NormalStatus, IsUpdate: -1, FileName: "users.xlsx"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res.SkipTotal)
+	assert.Equal(t, consts.EmailPhoneExist, res.Rows[2].Reason)
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	assert.Equal(t, FormatXlsx, DetectImportFormat("users.XLSX", nil))
+	assert.Equal(t, FormatCSV, DetectImportFormat("users.csv", nil))
+	assert.Equal(t, FormatNDJSON, DetectImportFormat("users.jsonl", nil))
+	assert.Equal(t, "", DetectImportFormat("users.xls", nil))
+	assert.Equal(t, FormatXlsx, DetectImportFormat("", []byte("PK\x03\x04...")))
+	assert.Equal(t, FormatNDJSON, DetectImportFormat("", []byte("\n {\"name\":\"a\"}")))
+	assert.Equal(t, FormatCSV, DetectImportFormat("", []byte("姓名,邮箱")))
+}
+
+func TestImportCSVAndNDJSON(t *testing.T) {
+	u := &user{
+		columnRepo: xlsxColumns{},
+	}
+	ctx := context.Background()
+
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("姓名;邮箱;私人邮箱;手机号;所在部门名称;备注\n张三;a@test.com;a@self.com;13600000001;/研发部\n;;;;\n")
+	assert.Nil(t, err)
+	rows, err := u.makeData(ctx, []byte(gbk), &ImportFileRequest{FileName: "users.csv", Encoding: "gbk", Delimiter: ";"})
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, map[string]interface{}{
+			consts.NAME:      "张三",
+			consts.EMAIL:     "a@test.com",
+			consts.SELFEMAIL: "a@self.com",
+			consts.PHONE:     "13600000001",
+			consts.DEPNAME:   "/研发部",
+			consts.ROW:       2,
+		}, rows[0])
+	}
+	_, err = u.makeData(ctx, []byte(gbk), &ImportFileRequest{FileName: "users.csv", Encoding: "big5"})
+	assert.NotNil(t, err)
+
+	ndjson := "{\"name\":\"李四\",\"email\":\"b@test.com\",\"私人邮箱\":\"b@self.com\",\"phone\":13600000002}\n\n{\"name\":\"王五\",\"所在部门名称\":\"/研发部\"}"
+	rows, err = u.makeData(ctx, []byte(ndjson), &ImportFileRequest{})
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "13600000002", rows[0][consts.PHONE])
+		assert.Equal(t, "b@self.com", rows[0][consts.SELFEMAIL])
+		assert.Equal(t, "", rows[0][consts.DEPNAME])
+		assert.Equal(t, 3, rows[1][consts.ROW])
+		assert.Equal(t, "/研发部", rows[1][consts.DEPNAME])
+	}
+	_, err = u.makeData(ctx, []byte("{\"name\":"), &ImportFileRequest{Format: FormatNDJSON})
+	assert.NotNil(t, err)
+
+	// the csv template reads back as a file to import
+	template, err := u.Template(ctx, &GetTemplateFileRequest{Format: FormatCSV})
+	assert.Nil(t, err)
+	rows, err = u.makeData(ctx, template.Data, &ImportFileRequest{FileName: template.FileName})
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "/部门demo1/子部门demo2", rows[0][consts.DEPNAME])
+		assert.Equal(t, "demo(导入请删除此行)", rows[0][consts.EMAIL])
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	userRepo := mock.NewMockUserRepo(ctl)
+	userRepo.EXPECT().SelectAfterID(gomock.Any(), gomock.Any(), consts.NormalStatus, "", exportBatch, gomock.Any()).
+		Return([]*org.User{{ID: "1"}, {ID: "2"}, {ID: "0"}}).AnyTimes()
+	userRepo.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().SelectColumns(gomock.Any(), gomock.Any(), []string{"name", "phone", "native_place"}, "1", "2", "0").Return([]map[string]interface{}{
+		{"id": "1", "name": "test1", "phone": "13600000001", "native_place": []byte("北京")},
+		{"id": "2", "name": "test2", "phone": "13600000002", "native_place": nil},
+		{"id": "0", "name": "test0", "phone": "13600000000", "native_place": "上海"},
+	}).AnyTimes()
+	userDepRepo := mock.NewMockUserDepartmentRelationRepo(ctl)
+	userDepRepo.EXPECT().SelectByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	userLeaderRepo := mock.NewMockUserLeaderRelationRepo(ctl)
+	userLeaderRepo.EXPECT().SelectByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	depRepo := mock.NewMockDepartmentRepo(ctl)
+	depRepo.EXPECT().PageList(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	u := &user{
+		userRepo:       userRepo,
+		userDepRepo:    userDepRepo,
+		userLeaderRepo: userLeaderRepo,
+		depRepo:        depRepo,
+		columnRepo: xlsxColumns{configured: []org.UserTableColumns{
+			{Name: "籍贯", ColumnsName: "native_place", Attr: consts.AliasAttr},
+			{Name: "员工姓名", ColumnsName: "name", Attr: consts.SystemAttr},
+			{Name: "ID", ColumnsName: "id", Attr: consts.SystemAttr},
+			{Name: "手机号码", ColumnsName: "phone", Attr: consts.SystemAttr},
+		}},
+	}
+	ctx := context.Background()
+
+	buffer := new(bytes.Buffer)
+	err := u.Export(ctx, &ExportRequest{Format: FormatCSV}, buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, "\xef\xbb\xbf"+
+		"员工姓名,手机号码,籍贯,所在部门名称,直属上级\n"+
+		"test1,13600000001,北京,/test,\n"+
+		"test2,13600000002,,,test1\n"+
+		"test0,13600000000,上海,,\n", buffer.String())
+
+	// the export imports again under the configured names
+	rows, err := u.makeData(ctx, buffer.Bytes(), &ImportFileRequest{FileName: ExportFileName(FormatCSV)})
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, "test1", rows[0][consts.NAME])
+		assert.Equal(t, "13600000001", rows[0][consts.PHONE])
+		assert.Equal(t, "/test", rows[0][consts.DEPNAME])
+	}
+
+	buffer.Reset()
+	err = u.Export(ctx, &ExportRequest{}, buffer)
+	assert.Nil(t, err)
+	rows, err = u.makeData(ctx, buffer.Bytes(), &ImportFileRequest{FileName: ExportFileName("")})
+	assert.Nil(t, err)
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, "test2", rows[1][consts.NAME])
+		assert.Equal(t, "13600000002", rows[1][consts.PHONE])
+	}
+}
+
+func TestExportPages(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	first := make([]*org.User, 0, exportBatch)
+	for k := 0; k < exportBatch; k++ {
+		first = append(first, &org.User{ID: fmt.Sprintf("u%03d", k)})
+	}
+	userRepo := mock.NewMockUserRepo(ctl)
+	gomock.InOrder(
+		userRepo.EXPECT().SelectAfterID(gomock.Any(), gomock.Any(), consts.NormalStatus, "", exportBatch, gomock.Any()).Return(first),
+		// the next page starts after the last id of the full one
+		userRepo.EXPECT().SelectAfterID(gomock.Any(), gomock.Any(), consts.NormalStatus, "u499", exportBatch, gomock.Any()).Return([]*org.User{{ID: "u500"}}),
+	)
+	userRepo.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	userRepo.EXPECT().SelectColumns(gomock.Any(), gomock.Any(), []string{"name"}, gomock.Any()).DoAndReturn(func(ctx context.Context, db *gorm.DB, columns []string, id ...string) []map[string]interface{} {
+		res := make([]map[string]interface{}, 0, len(id))
+		for _, v := range id {
+			res = append(res, map[string]interface{}{"id": v, "name": v})
+		}
+		return res
+	}).Times(2)
+	userDepRepo := mock.NewMockUserDepartmentRelationRepo(ctl)
+	userDepRepo.EXPECT().SelectByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	userLeaderRepo := mock.NewMockUserLeaderRelationRepo(ctl)
+	userLeaderRepo.EXPECT().SelectByUserIDs(gomock.Any(), gomock.Any()).AnyTimes()
+	u := &user{
+		userRepo:       userRepo,
+		userDepRepo:    userDepRepo,
+		userLeaderRepo: userLeaderRepo,
+		depRepo:        emptyDepartments{},
+		columnRepo: xlsxColumns{configured: []org.UserTableColumns{
+			{Name: "员工姓名", ColumnsName: "name", Attr: consts.SystemAttr},
+		}},
+	}
+
+	buffer := new(bytes.Buffer)
+	err := u.Export(context.Background(), &ExportRequest{Format: FormatCSV}, buffer)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if assert.Len(t, lines, exportBatch+2) {
+		assert.Equal(t, "u000,,", lines[1])
+		assert.Equal(t, "u500,,", lines[exportBatch+1])
+	}
+}
+
+func TestImportCreateDepartments(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	inserted := make([]org.Department, 0)
+	depRepo := mock.NewMockDepartmentRepo(ctl)
+	depRepo.EXPECT().PageList(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	depRepo.EXPECT().Insert(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, tx *gorm.DB, req *org.Department) error {
+		inserted = append(inserted, *req)
+		return nil
+	}).Times(2)
+	u := &user{
+		depRepo: depRepo,
+	}
+	rows := func() []map[string]interface{} {
+		list := make([]map[string]interface{}, 0)
+		for k, v := range [][]string{
+			{"a", "a@test.com", "/test/研发部/后端"},
+			{"b", "b@test.com", "/test/研发部"},
+			{"c", "c@test.com", "/other/研发部"},
+			{"d", "d@test.com", "/test/test1"},
+			{"e", "e", "/test/市场部"},
+		} {
+			list = append(list, map[string]interface{}{
+				consts.NAME:      v[0],
+				consts.EMAIL:     v[1],
+				consts.SELFEMAIL: v[0] + "@self.com",
+				consts.PHONE:     fmt.Sprintf("1360000000%d", k),
+				consts.DEPNAME:   v[2],
+				consts.ROW:       k + 2,
+			})
+		}
+		return list
+	}
+	ctx := context.Background()
+
+	// without the option the missing departments fail their rows
+	suc, fails, created := u.screenUserData(ctx, rows(), "admin", &ImportFileRequest{})
+	assert.Len(t, suc, 1)
+	assert.Len(t, fails, 4)
+	assert.Empty(t, created)
+
+	// previewing plans the departments without writing them
+	_, _, created = u.screenUserData(ctx, rows(), "admin", &ImportFileRequest{CreateDep: createDep, Preview: true})
+	assert.Len(t, created, 2)
+	assert.Empty(t, inserted)
+
+	suc, fails, created = u.screenUserData(ctx, rows(), "admin", &ImportFileRequest{CreateDep: createDep})
+	assert.Len(t, suc, 3)
+	if assert.Len(t, fails, 2) {
+		assert.ElementsMatch(t, []string{consts.NotDepartment, consts.NotEmail},
+			[]interface{}{fails[0][consts.REMARK], fails[1][consts.REMARK]})
+	}
+	if assert.Len(t, created, 2) && assert.Len(t, inserted, 2) {
+		assert.Equal(t, "/test/研发部", created[0].Path)
+		assert.Equal(t, "/test/研发部/后端", created[1].Path)
+		assert.Equal(t, org.Department{
+			ID:        created[0].ID,
+			Name:      "研发部",
+			UseStatus: consts.NormalStatus,
+			Attr:      consts.DepAttrDEP,
+			PID:       "1",
+			Grade:     1,
+			CreatedAt: inserted[0].CreatedAt,
+			UpdatedAt: inserted[0].UpdatedAt,
+			CreatedBy: "admin",
+		}, inserted[0])
+		assert.Equal(t, created[0].ID, inserted[1].PID)
+		assert.Equal(t, 2, inserted[1].Grade)
+	}
+	depIDs := make(map[interface{}]interface{})
+	for k := range suc {
+		depIDs[suc[k][consts.NAME]] = suc[k][consts.DEPID]
+	}
+	assert.Equal(t, map[interface{}]interface{}{
+		"a": created[1].ID,
+		"b": created[0].ID,
+		"d": "2",
+	}, depIDs)
+	assert.Equal(t, "/test/研发部,/test/研发部/后端", createdPaths(created))
+
+	// an organization without departments gets its top one as a company
+	u.depRepo = emptyDepartments{DepartmentRepo: depRepo}
+	_, _, created = u.screenUserData(ctx, rows()[:1], "admin", &ImportFileRequest{CreateDep: createDep, Preview: true})
+	if assert.Len(t, created, 3) {
+		assert.Equal(t, "", created[0].PID)
+		assert.Equal(t, "/test", created[0].Path)
+	}
+}
+
+type emptyDepartments struct {
+	org.DepartmentRepo
+}
+
+func (emptyDepartments) PageList(ctx context.Context, db *gorm.DB, status, page, limit int) ([]org.Department, int64) {
+	return nil, 0
+}
+
+func TestActiveSendChannel(t *testing.T) {
+	assert.Equal(t, SENDEMAIL, activeSendChannel(NO))
+	assert.Equal(t, SENDPHONE, activeSendChannel(SENDPHONE))
+	assert.Equal(t, SENDEMAIL|SENDPHONE, activeSendChannel(SENDEMAIL|SENDPHONE))
+}
+
+// pendingUserRepo a user waiting for activation
+type pendingUserRepo struct {
+	org.UserRepo
+	one org.User
+}
+
+func (r pendingUserRepo) Get(ctx context.Context, db *gorm.DB, id string) *org.User {
+	one := r.one
+	return &one
+}
+
+func TestUpdateUserStatusSendPassword(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	conn, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		SkipInitializeWithVersion: true,
+		Conn:                      conn,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := mock.NewMockUserRepo(ctl)
+	userRepo.EXPECT().UpdateByID(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	var saved org.Account
+	accountRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(tx *gorm.DB, req *org.Account) error {
+		saved = *req
+		return nil
+	}).Times(2)
+	conf := configs.Config{}
+	conf.MessageTemplate.NewPWD = "newPWD"
+	sink := message.NewSink()
+	hasher := encode2.NewHasher(configs.PasswordHash{})
+	u := &user{
+		hasher: hasher,
+		DB:     db,
+		conf:   conf,
+		userRepo: pendingUserRepo{UserRepo: userRepo, one: org.User{
+			ID:        "1",
+			SelfEmail: "self@test.com",
+			Phone:     "13688886666",
+			UseStatus: consts.ActiveStatus,
+		}},
+		accountReo:  accountRepo,
+		redisClient: redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		message:     sink,
+	}
+	ctx := header2.SetContext(context.Background(), TenantID, "")
+
+	// invitations are off, the generated password goes to the chosen channel
+	_, err = u.UpdateUserStatus(ctx, &StatusRequest{ID: "1", UseStatus: consts.ActiveStatus, SendChannel: SENDPHONE})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return len(sink.Phones("13688886666")) == 1
+	}, time.Second, 10*time.Millisecond)
+	phone := sink.Phones("13688886666")[0]
+	assert.Equal(t, "newPWD", phone.Content.TemplateID)
+	ok, _ := hasher.Verify(phone.Content.KeyAndValue["code"], saved.Password)
+	assert.True(t, ok)
+	assert.Empty(t, sink.Emails("self@test.com"))
+
+	// email by default
+	_, err = u.UpdateUserStatus(ctx, &StatusRequest{ID: "1", UseStatus: consts.ActiveStatus})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return len(sink.Emails("self@test.com")) == 1
+	}, time.Second, 10*time.Millisecond)
+	ok, _ = hasher.Verify(sink.Emails("self@test.com")[0].Content.KeyAndValue["code"], saved.Password)
+	assert.True(t, ok)
+	assert.Len(t, sink.Phones("13688886666"), 1)
+}
@@ -21,7 +21,9 @@ type Account struct {
 	ID       string `gorm:"column:id;type:varchar(100);primaryKey ;" json:"id"`            //userID
 	Account  string `gorm:"column:account;type:varchar(100);index:account" json:"account"` //多形态:邮箱、手机、其它
 	UserID   string `gorm:"column:user_id;type:varchar(64);" json:"userID"`
-	Password string `gorm:"column:password;type:varchar(100);" json:"password"`
+	Password string `gorm:"column:password;type:varchar(255);" json:"password"`
+	//last time the password was set
+	PasswordChangedAt int64 `gorm:"column:password_changed_at;type:bigint;" json:"passwordChangedAt,omitempty"`
 
 	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
 	UpdatedAt int64  `gorm:"column:updated_at;type:bigint; " json:"updatedAt,omitempty" comment:"更新时间"`
@@ -46,4 +48,5 @@ type AccountRepo interface {
 	DeleteByUserID(db *gorm.DB, id ...string) error
 	Update(tx *gorm.DB, res *Account) error
 	UpdatePasswordByUserID(tx *gorm.DB, res *Account) error
+	SelectByPasswordChangedAt(db *gorm.DB, begin, end int64) []Account
 }
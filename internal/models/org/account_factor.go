@@ -0,0 +1,47 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+)
+
+// AccountFactor account second factor
+type AccountFactor struct {
+	ID     string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	UserID string `gorm:"column:user_id;type:varchar(64);uniqueIndex:user_id" json:"userID"`
+	Secret string `gorm:"column:secret;type:varchar(64);" json:"-"`
+	//sha256 of unused recovery codes, separated by comma
+	RecoveryCodes string `gorm:"column:recovery_codes;type:text;" json:"-"`
+	//0:pending confirm,1:enabled
+	Status int `gorm:"column:status;type:int;" json:"status"`
+
+	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
+	UpdatedAt int64  `gorm:"column:updated_at;type:bigint; " json:"updatedAt,omitempty" comment:"更新时间"`
+	UpdatedBy string `gorm:"column:updated_by;type:varchar(64); " json:"updatedBy,omitempty" comment:"修改者"`
+}
+
+// TableName table name
+func (AccountFactor) TableName() string {
+	return "org_user_account_factor"
+}
+
+// AccountFactorRepo interface
+type AccountFactorRepo interface {
+	Insert(tx *gorm.DB, req *AccountFactor) error
+	Update(tx *gorm.DB, req *AccountFactor) error
+	// UpdateRecoveryCodes update recovery codes only if they are still old, it returns rows affected
+	UpdateRecoveryCodes(tx *gorm.DB, req *AccountFactor, old string) (int64, error)
+	SelectByUserID(db *gorm.DB, userID string) *AccountFactor
+	DeleteByUserID(tx *gorm.DB, userID ...string) error
+}
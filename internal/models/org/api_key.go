@@ -0,0 +1,52 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+)
+
+// APIKey api key of a service account, only its hash is kept
+type APIKey struct {
+	ID               string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	ServiceAccountID string `gorm:"column:service_account_id;type:varchar(64);index:service_account_id" json:"serviceAccountID"`
+	//first characters of the key, to tell keys apart
+	Prefix  string `gorm:"column:prefix;type:varchar(16);" json:"prefix"`
+	KeyHash string `gorm:"column:key_hash;type:varchar(64);uniqueIndex:key_hash" json:"-"`
+	//milliseconds, 0 never expires
+	ExpireAt   int64  `gorm:"column:expire_at;type:bigint;" json:"expireAt"`
+	LastUsedAt int64  `gorm:"column:last_used_at;type:bigint;" json:"lastUsedAt"`
+	LastUsedIP string `gorm:"column:last_used_ip;type:varchar(64);" json:"lastUsedIP"`
+	TenantID   string `gorm:"column:tenant_id;type:varchar(64);" json:"tenantID"`
+
+	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
+	CreatedBy string `gorm:"column:created_by;type:varchar(64); " json:"createdBy,omitempty" comment:"创建者"`
+}
+
+// TableName table name
+func (APIKey) TableName() string {
+	return "org_api_key"
+}
+
+// APIKeyRepo interface
+type APIKeyRepo interface {
+	Insert(tx *gorm.DB, req *APIKey) error
+	Get(db *gorm.DB, id string) *APIKey
+	SelectByHash(db *gorm.DB, keyHash string) *APIKey
+	SelectByServiceAccountID(db *gorm.DB, serviceAccountID string) []APIKey
+	UpdateExpireAt(tx *gorm.DB, id string, expireAt int64) error
+	// UpdateLastUsed only touches keys last used before the given time
+	UpdateLastUsed(tx *gorm.DB, id, ip string, usedAt, before int64) error
+	Delete(tx *gorm.DB, id ...string) error
+	DeleteByServiceAccountID(tx *gorm.DB, serviceAccountID ...string) error
+}
@@ -56,4 +56,6 @@ type DepartmentRepo interface {
 	SelectSupper(ctx context.Context, db *gorm.DB) *Department
 	Count(ctx context.Context, db *gorm.DB, status int) int64
 	GetMaxGrade(ctx context.Context, db *gorm.DB) int64
+	// SelectByOffset departments in creation order, offset rows skipped, name matched when not empty
+	SelectByOffset(ctx context.Context, db *gorm.DB, status, offset, limit int, name string) (list []Department, total int64)
 }
@@ -0,0 +1,49 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ImpersonationEvent start, stop and every write of an admin acting as a user
+type ImpersonationEvent struct {
+	ID        string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	SessionID string `gorm:"column:session_id;type:varchar(64);index:session_id" json:"sessionID"`
+	//admin acting as the user
+	ActorID string `gorm:"column:actor_id;type:varchar(64);index:actor_id" json:"actorID"`
+	UserID  string `gorm:"column:user_id;type:varchar(64);" json:"userID"`
+	//start,stop,write
+	Action string `gorm:"column:action;type:varchar(16);" json:"action"`
+	Reason string `gorm:"column:reason;type:varchar(255);" json:"reason,omitempty"`
+	Method string `gorm:"column:method;type:varchar(16);" json:"method,omitempty"`
+	Path   string `gorm:"column:path;type:varchar(255);" json:"path,omitempty"`
+	//http status of the write
+	Status    int    `gorm:"column:status;type:int;" json:"status,omitempty"`
+	IP        string `gorm:"column:ip;type:varchar(64);" json:"ip"`
+	TenantID  string `gorm:"column:tenant_id;type:varchar(64);" json:"tenantID"`
+	CreatedAt int64  `gorm:"column:created_at;type:bigint;" json:"createdAt"`
+}
+
+// TableName table name
+func (ImpersonationEvent) TableName() string {
+	return "org_impersonation_event"
+}
+
+// ImpersonationEventRepo interface
+type ImpersonationEventRepo interface {
+	Insert(db *gorm.DB, req *ImpersonationEvent) error
+	PageList(ctx context.Context, db *gorm.DB, sessionID, actorID, userID string, page, limit int) ([]ImpersonationEvent, int64)
+}
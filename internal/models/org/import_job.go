@@ -0,0 +1,69 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ImportJob user import running in the background, its counters grow as rows are handled
+type ImportJob struct {
+	ID       string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	FileName string `gorm:"column:file_name;type:varchar(255);" json:"fileName"`
+	//status of imported users
+	UseStatus int `gorm:"column:use_status;type:int;" json:"useStatus"`
+	//1:update existing users,-1:only insert
+	IsUpdate int `gorm:"column:is_update;type:int;" json:"isUpdate"`
+	//1:create the missing departments of the paths
+	CreateDep int `gorm:"column:create_dep;type:int;" json:"createDep"`
+	//1:running,2:finished,-1:failed,-2:canceled
+	Status      int    `gorm:"column:status;type:int;" json:"status"`
+	Total       int    `gorm:"column:total;type:int;" json:"total"`
+	Processed   int    `gorm:"column:processed;type:int;" json:"processed"`
+	AddTotal    int    `gorm:"column:add_total;type:int;" json:"addTotal"`
+	UpdateTotal int    `gorm:"column:update_total;type:int;" json:"updateTotal"`
+	FailTotal   int    `gorm:"column:fail_total;type:int;" json:"failTotal"`
+	Message     string `gorm:"column:message;type:varchar(255);" json:"message,omitempty"`
+	//paths of the departments the job created, separated by comma
+	CreatedDeps string `gorm:"column:created_deps;type:text;" json:"createdDeps,omitempty"`
+	//xlsx of the failed rows with their remark
+	FailFile   []byte `gorm:"column:fail_file;type:longblob;" json:"-"`
+	TenantID   string `gorm:"column:tenant_id;type:varchar(64);" json:"tenantID"`
+	FinishedAt int64  `gorm:"column:finished_at;type:bigint;" json:"finishedAt,omitempty"`
+
+	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
+	UpdatedAt int64  `gorm:"column:updated_at;type:bigint; " json:"updatedAt,omitempty" comment:"更新时间"`
+	CreatedBy string `gorm:"column:created_by;type:varchar(64); " json:"createdBy,omitempty" comment:"创建者"`
+}
+
+// TableName table name
+func (ImportJob) TableName() string {
+	return "org_import_job"
+}
+
+// ImportJobRepo interface
+type ImportJobRepo interface {
+	Insert(ctx context.Context, db *gorm.DB, req *ImportJob) error
+	// Progress writes the counters only, a cancel made meanwhile is kept
+	Progress(db *gorm.DB, req *ImportJob) error
+	// Finish writes the final status, counters and the file of failed rows
+	Finish(db *gorm.DB, req *ImportJob) error
+	// Cancel marks a running job canceled, the worker stops before its next batch
+	Cancel(db *gorm.DB, id string, updatedAt int64) error
+	// FailStale marks failed the running jobs of every tenant not updated since before
+	FailStale(db *gorm.DB, before, updatedAt int64, message string) (int64, error)
+	Get(ctx context.Context, db *gorm.DB, id string) *ImportJob
+	PageList(ctx context.Context, db *gorm.DB, page, limit int) ([]ImportJob, int64)
+}
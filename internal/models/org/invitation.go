@@ -0,0 +1,55 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Invitation link a user follows to set the password, only the hash of its nonce is kept
+type Invitation struct {
+	ID     string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	UserID string `gorm:"column:user_id;type:varchar(64);index:user_id" json:"userID"`
+	//email or phone the invitation was sent to, empty for the email of the user
+	SendTo      string `gorm:"column:send_to;type:varchar(64);" json:"sendTo"`
+	SendChannel int    `gorm:"column:send_channel;type:int;" json:"sendChannel"`
+	NonceHash   string `gorm:"column:nonce_hash;type:varchar(64);" json:"-"`
+	//1:pending,2:accepted,-1:revoked
+	Status int `gorm:"column:status;type:int;" json:"status"`
+	//milliseconds
+	ExpireAt   int64  `gorm:"column:expire_at;type:bigint;" json:"expireAt"`
+	AcceptedAt int64  `gorm:"column:accepted_at;type:bigint;" json:"acceptedAt"`
+	TenantID   string `gorm:"column:tenant_id;type:varchar(64);" json:"tenantID"`
+
+	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
+	UpdatedAt int64  `gorm:"column:updated_at;type:bigint; " json:"updatedAt,omitempty" comment:"更新时间"`
+	CreatedBy string `gorm:"column:created_by;type:varchar(64); " json:"createdBy,omitempty" comment:"创建者"`
+	UpdatedBy string `gorm:"column:updated_by;type:varchar(64); " json:"updatedBy,omitempty" comment:"修改者"`
+}
+
+// TableName table name
+func (Invitation) TableName() string {
+	return "org_invitation"
+}
+
+// InvitationRepo interface
+type InvitationRepo interface {
+	Insert(ctx context.Context, tx *gorm.DB, req *Invitation) error
+	Update(tx *gorm.DB, req *Invitation) error
+	Get(db *gorm.DB, id string) *Invitation
+	// Revoke closes the pending invitations of the users
+	Revoke(tx *gorm.DB, updatedBy string, updatedAt int64, userID ...string) error
+	PageList(ctx context.Context, db *gorm.DB, status, page, limit int) ([]Invitation, int64)
+}
@@ -0,0 +1,47 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// LoginEvent login attempt
+type LoginEvent struct {
+	ID       string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	UserID   string `gorm:"column:user_id;type:varchar(64);index:user_id" json:"userID"`
+	Account  string `gorm:"column:account;type:varchar(100);" json:"account"`
+	TenantID string `gorm:"column:tenant_id;type:varchar(64);" json:"tenantID"`
+	//pwd,ldapClient,code,factor
+	Types string `gorm:"column:types;type:varchar(16);" json:"types"`
+	//1:success,-1:fail
+	Result    int    `gorm:"column:result;type:int;" json:"result"`
+	ErrCode   int64  `gorm:"column:err_code;type:bigint;" json:"errCode,omitempty"`
+	IP        string `gorm:"column:ip;type:varchar(64);" json:"ip"`
+	UserAgent string `gorm:"column:user_agent;type:varchar(512);" json:"userAgent"`
+	CreatedAt int64  `gorm:"column:created_at;type:bigint;index:created_at" json:"createdAt"`
+}
+
+// TableName table name
+func (LoginEvent) TableName() string {
+	return "org_login_event"
+}
+
+// LoginEventRepo interface
+type LoginEventRepo interface {
+	Insert(db *gorm.DB, req *LoginEvent) error
+	PageList(ctx context.Context, db *gorm.DB, userID string, result int, begin, end int64, page, limit int) ([]LoginEvent, int64)
+	DeleteBefore(db *gorm.DB, createdAt int64) (int64, error)
+}
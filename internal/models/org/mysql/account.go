@@ -82,9 +82,24 @@ func (u *accountRepo) Update(tx *gorm.DB, res *org.Account) error {
 }
 
 func (u *accountRepo) UpdatePasswordByUserID(tx *gorm.DB, res *org.Account) error {
-	err := tx.Table(res.TableName()).Where("user_id=?", res.UserID).Update("password", res.Password).Error
+	// password_changed_at is kept when it is zero, such as rehash on login
+	err := tx.Table(res.TableName()).Where("user_id=?", res.UserID).Updates(&org.Account{
+		Password:          res.Password,
+		PasswordChangedAt: res.PasswordChangedAt,
+	}).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (u *accountRepo) SelectByPasswordChangedAt(db *gorm.DB, begin, end int64) []org.Account {
+	res := make([]org.Account, 0)
+	db = db.Where("password_changed_at between ? and ?", begin, end)
+	affected := db.Find(&res).
+		RowsAffected
+	if affected > 0 {
+		return res
+	}
+	return nil
+}
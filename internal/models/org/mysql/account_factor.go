@@ -0,0 +1,57 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+)
+
+type accountFactorRepo struct {
+}
+
+// NewAccountFactorRepo new
+func NewAccountFactorRepo() org.AccountFactorRepo {
+	return new(accountFactorRepo)
+}
+
+func (a *accountFactorRepo) Insert(tx *gorm.DB, req *org.AccountFactor) error {
+	return tx.Create(req).Error
+}
+
+func (a *accountFactorRepo) Update(tx *gorm.DB, req *org.AccountFactor) error {
+	return tx.Model(req).Select("secret", "recovery_codes", "status", "updated_at", "updated_by").Updates(req).Error
+}
+
+func (a *accountFactorRepo) UpdateRecoveryCodes(tx *gorm.DB, req *org.AccountFactor, old string) (int64, error) {
+	res := tx.Model(&org.AccountFactor{}).Where("id=? and recovery_codes=?", req.ID, old).
+		Updates(map[string]interface{}{
+			"recovery_codes": req.RecoveryCodes,
+			"updated_at":     req.UpdatedAt,
+		})
+	return res.RowsAffected, res.Error
+}
+
+func (a *accountFactorRepo) SelectByUserID(db *gorm.DB, userID string) *org.AccountFactor {
+	res := new(org.AccountFactor)
+	affected := db.Where("user_id=?", userID).Find(res).RowsAffected
+	if affected == 1 {
+		return res
+	}
+	return nil
+}
+
+func (a *accountFactorRepo) DeleteByUserID(tx *gorm.DB, userID ...string) error {
+	return tx.Where("user_id in (?)", userID).Delete(&org.AccountFactor{}).Error
+}
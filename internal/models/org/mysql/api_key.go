@@ -0,0 +1,73 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+)
+
+type apiKeyRepo struct {
+}
+
+// NewAPIKeyRepo new
+func NewAPIKeyRepo() org.APIKeyRepo {
+	return new(apiKeyRepo)
+}
+
+func (a *apiKeyRepo) Insert(tx *gorm.DB, req *org.APIKey) error {
+	return tx.Create(req).Error
+}
+
+func (a *apiKeyRepo) Get(db *gorm.DB, id string) *org.APIKey {
+	res := new(org.APIKey)
+	affected := db.Where("id=?", id).Find(res).RowsAffected
+	if affected == 1 {
+		return res
+	}
+	return nil
+}
+
+func (a *apiKeyRepo) SelectByHash(db *gorm.DB, keyHash string) *org.APIKey {
+	res := new(org.APIKey)
+	affected := db.Where("key_hash=?", keyHash).Find(res).RowsAffected
+	if affected == 1 {
+		return res
+	}
+	return nil
+}
+
+func (a *apiKeyRepo) SelectByServiceAccountID(db *gorm.DB, serviceAccountID string) []org.APIKey {
+	list := make([]org.APIKey, 0)
+	db.Where("service_account_id=?", serviceAccountID).Order("created_at desc").Find(&list)
+	return list
+}
+
+func (a *apiKeyRepo) UpdateExpireAt(tx *gorm.DB, id string, expireAt int64) error {
+	return tx.Model(&org.APIKey{}).Where("id=?", id).Update("expire_at", expireAt).Error
+}
+
+func (a *apiKeyRepo) UpdateLastUsed(tx *gorm.DB, id, ip string, usedAt, before int64) error {
+	return tx.Model(&org.APIKey{}).
+		Where("id=? and (last_used_at<? or last_used_at is null)", id, before).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).Error
+}
+
+func (a *apiKeyRepo) Delete(tx *gorm.DB, id ...string) error {
+	return tx.Where("id in (?)", id).Delete(&org.APIKey{}).Error
+}
+
+func (a *apiKeyRepo) DeleteByServiceAccountID(tx *gorm.DB, serviceAccountID ...string) error {
+	return tx.Where("service_account_id in (?)", serviceAccountID).Delete(&org.APIKey{}).Error
+}
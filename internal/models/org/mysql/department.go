@@ -223,3 +223,26 @@ func (d *departmentRepo) GetMaxGrade(ctx context.Context, db *gorm.DB) int64 {
 func NewDepartmentRepo() org.DepartmentRepo {
 	return new(departmentRepo)
 }
+
+func (d *departmentRepo) SelectByOffset(ctx context.Context, db *gorm.DB, status, offset, limit int, name string) (list []org.Department, total int64) {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	if status != 0 {
+		db = db.Where("use_status=?", status)
+	}
+	if name != "" {
+		db = db.Where("name=?", name)
+	}
+	var num int64
+	db.Model(&org.Department{}).Count(&num)
+	if limit <= 0 || int64(offset) >= num {
+		return nil, num
+	}
+	departments := make([]org.Department, 0)
+	db.Order("created_at, id").Limit(limit).Offset(offset).Find(&departments)
+	return departments, num
+}
@@ -0,0 +1,64 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	page2 "github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+type impersonationEventRepo struct {
+}
+
+// NewImpersonationEventRepo new
+func NewImpersonationEventRepo() org.ImpersonationEventRepo {
+	return new(impersonationEventRepo)
+}
+
+func (i *impersonationEventRepo) Insert(db *gorm.DB, req *org.ImpersonationEvent) error {
+	return db.Create(req).Error
+}
+
+func (i *impersonationEventRepo) PageList(ctx context.Context, db *gorm.DB, sessionID, actorID, userID string, page, limit int) ([]org.ImpersonationEvent, int64) {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	if sessionID != "" {
+		db = db.Where("session_id=?", sessionID)
+	}
+	if actorID != "" {
+		db = db.Where("actor_id=?", actorID)
+	}
+	if userID != "" {
+		db = db.Where("user_id=?", userID)
+	}
+	var num int64
+	db.Model(&org.ImpersonationEvent{}).Count(&num)
+	newPage := page2.NewPage(page, limit, num)
+
+	db = db.Order("created_at desc").Limit(newPage.PageSize).Offset(newPage.StartIndex)
+	list := make([]org.ImpersonationEvent, 0)
+	affected := db.Find(&list).RowsAffected
+	if affected > 0 {
+		return list, num
+	}
+	return nil, 0
+}
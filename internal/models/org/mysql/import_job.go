@@ -0,0 +1,103 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	page2 "github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+type importJobRepo struct {
+}
+
+// NewImportJobRepo new
+func NewImportJobRepo() org.ImportJobRepo {
+	return new(importJobRepo)
+}
+
+func (i *importJobRepo) Insert(ctx context.Context, db *gorm.DB, req *org.ImportJob) error {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	req.TenantID = tenantID
+	return db.Create(req).Error
+}
+
+func (i *importJobRepo) Progress(db *gorm.DB, req *org.ImportJob) error {
+	return db.Model(req).Select("total", "processed", "add_total", "update_total", "fail_total", "created_deps", "updated_at").Updates(req).Error
+}
+
+func (i *importJobRepo) Finish(db *gorm.DB, req *org.ImportJob) error {
+	return db.Model(req).Select("status", "total", "processed", "add_total", "update_total", "fail_total",
+		"message", "created_deps", "fail_file", "finished_at", "updated_at").Updates(req).Error
+}
+
+func (i *importJobRepo) Cancel(db *gorm.DB, id string, updatedAt int64) error {
+	return db.Model(&org.ImportJob{}).
+		Where("id=? and status=?", id, consts.ImportJobRunning).
+		Updates(map[string]interface{}{
+			"status":     consts.ImportJobCanceled,
+			"updated_at": updatedAt,
+		}).Error
+}
+
+func (i *importJobRepo) FailStale(db *gorm.DB, before, updatedAt int64, message string) (int64, error) {
+	res := db.Model(&org.ImportJob{}).
+		Where("status=? and updated_at<?", consts.ImportJobRunning, before).
+		Updates(map[string]interface{}{
+			"status":      consts.ImportJobFailed,
+			"message":     message,
+			"finished_at": updatedAt,
+			"updated_at":  updatedAt,
+		})
+	return res.RowsAffected, res.Error
+}
+
+func (i *importJobRepo) Get(ctx context.Context, db *gorm.DB, id string) *org.ImportJob {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	res := new(org.ImportJob)
+	affected := db.Where("id=?", id).Find(res).RowsAffected
+	if affected == 1 {
+		return res
+	}
+	return nil
+}
+
+func (i *importJobRepo) PageList(ctx context.Context, db *gorm.DB, page, limit int) ([]org.ImportJob, int64) {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	var num int64
+	db.Model(&org.ImportJob{}).Count(&num)
+	newPage := page2.NewPage(page, limit, num)
+
+	db = db.Omit("fail_file").Order("created_at desc").Limit(newPage.PageSize).Offset(newPage.StartIndex)
+	list := make([]org.ImportJob, 0)
+	affected := db.Find(&list).RowsAffected
+	if affected > 0 {
+		return list, num
+	}
+	return nil, 0
+}
@@ -0,0 +1,84 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	page2 "github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+type invitationRepo struct {
+}
+
+// NewInvitationRepo new
+func NewInvitationRepo() org.InvitationRepo {
+	return new(invitationRepo)
+}
+
+func (i *invitationRepo) Insert(ctx context.Context, tx *gorm.DB, req *org.Invitation) error {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	req.TenantID = tenantID
+	return tx.Create(req).Error
+}
+
+func (i *invitationRepo) Update(tx *gorm.DB, req *org.Invitation) error {
+	return tx.Model(req).Select("nonce_hash", "status", "expire_at", "accepted_at", "updated_at", "updated_by").Updates(req).Error
+}
+
+func (i *invitationRepo) Get(db *gorm.DB, id string) *org.Invitation {
+	res := new(org.Invitation)
+	affected := db.Where("id=?", id).Find(res).RowsAffected
+	if affected == 1 {
+		return res
+	}
+	return nil
+}
+
+func (i *invitationRepo) Revoke(tx *gorm.DB, updatedBy string, updatedAt int64, userID ...string) error {
+	return tx.Model(&org.Invitation{}).
+		Where("user_id in (?) and status=?", userID, consts.InvitationPending).
+		Updates(map[string]interface{}{
+			"status":     consts.InvitationRevoked,
+			"updated_at": updatedAt,
+			"updated_by": updatedBy,
+		}).Error
+}
+
+func (i *invitationRepo) PageList(ctx context.Context, db *gorm.DB, status, page, limit int) ([]org.Invitation, int64) {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	if status != 0 {
+		db = db.Where("status=?", status)
+	}
+	var num int64
+	db.Model(&org.Invitation{}).Count(&num)
+	newPage := page2.NewPage(page, limit, num)
+
+	db = db.Order("created_at desc").Limit(newPage.PageSize).Offset(newPage.StartIndex)
+	list := make([]org.Invitation, 0)
+	affected := db.Find(&list).RowsAffected
+	if affected > 0 {
+		return list, num
+	}
+	return nil, 0
+}
@@ -0,0 +1,72 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	page2 "github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+type loginEventRepo struct {
+}
+
+// NewLoginEventRepo new
+func NewLoginEventRepo() org.LoginEventRepo {
+	return new(loginEventRepo)
+}
+
+func (l *loginEventRepo) Insert(db *gorm.DB, req *org.LoginEvent) error {
+	return db.Create(req).Error
+}
+
+func (l *loginEventRepo) PageList(ctx context.Context, db *gorm.DB, userID string, result int, begin, end int64, page, limit int) ([]org.LoginEvent, int64) {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	if userID != "" {
+		db = db.Where("user_id=?", userID)
+	}
+	if result != 0 {
+		db = db.Where("result=?", result)
+	}
+	if begin != 0 {
+		db = db.Where("created_at>=?", begin)
+	}
+	if end != 0 {
+		db = db.Where("created_at<=?", end)
+	}
+	var num int64
+	db.Model(&org.LoginEvent{}).Count(&num)
+	newPage := page2.NewPage(page, limit, num)
+
+	db = db.Order("created_at desc").Limit(newPage.PageSize).Offset(newPage.StartIndex)
+	list := make([]org.LoginEvent, 0)
+	affected := db.Find(&list).RowsAffected
+	if affected > 0 {
+		return list, num
+	}
+	return nil, 0
+}
+
+func (l *loginEventRepo) DeleteBefore(db *gorm.DB, createdAt int64) (int64, error) {
+	tx := db.Where("created_at<?", createdAt).Delete(&org.LoginEvent{})
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,56 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+)
+
+type passwordHistoryRepo struct {
+}
+
+// NewPasswordHistoryRepo new
+func NewPasswordHistoryRepo() org.PasswordHistoryRepo {
+	return new(passwordHistoryRepo)
+}
+
+func (p *passwordHistoryRepo) Insert(tx *gorm.DB, req *org.PasswordHistory) error {
+	return tx.Create(req).Error
+}
+
+func (p *passwordHistoryRepo) SelectByUserID(db *gorm.DB, userID string, limit int) []org.PasswordHistory {
+	res := make([]org.PasswordHistory, 0)
+	db = db.Where("user_id=?", userID).Order("created_at desc")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	affected := db.Find(&res).RowsAffected
+	if affected > 0 {
+		return res
+	}
+	return nil
+}
+
+func (p *passwordHistoryRepo) Prune(tx *gorm.DB, userID string, keep int) error {
+	ids := make([]string, 0)
+	err := tx.Model(&org.PasswordHistory{}).Where("user_id=?", userID).Order("created_at desc").Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) <= keep {
+		return nil
+	}
+	return tx.Where("id in (?)", ids[keep:]).Delete(&org.PasswordHistory{}).Error
+}
@@ -0,0 +1,80 @@
+package mysql
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	page2 "github.com/quanxiang-cloud/organizations/pkg/page"
+)
+
+type serviceAccountRepo struct {
+}
+
+// NewServiceAccountRepo new
+func NewServiceAccountRepo() org.ServiceAccountRepo {
+	return new(serviceAccountRepo)
+}
+
+func (s *serviceAccountRepo) Insert(ctx context.Context, tx *gorm.DB, req *org.ServiceAccount) error {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	req.TenantID = tenantID
+	return tx.Create(req).Error
+}
+
+func (s *serviceAccountRepo) Update(tx *gorm.DB, req *org.ServiceAccount) error {
+	return tx.Model(req).Select("name", "description", "use_status", "updated_at", "updated_by").Updates(req).Error
+}
+
+func (s *serviceAccountRepo) Delete(ctx context.Context, tx *gorm.DB, id ...string) error {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		tx = tx.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		tx = tx.Where("tenant_id=?", tenantID)
+	}
+	return tx.Where("id in (?)", id).Delete(&org.ServiceAccount{}).Error
+}
+
+func (s *serviceAccountRepo) Get(db *gorm.DB, id string) *org.ServiceAccount {
+	res := new(org.ServiceAccount)
+	affected := db.Where("id=?", id).Find(res).RowsAffected
+	if affected == 1 {
+		return res
+	}
+	return nil
+}
+
+func (s *serviceAccountRepo) PageList(ctx context.Context, db *gorm.DB, page, limit int) ([]org.ServiceAccount, int64) {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	var num int64
+	db.Model(&org.ServiceAccount{}).Count(&num)
+	newPage := page2.NewPage(page, limit, num)
+
+	db = db.Order("created_at desc").Limit(newPage.PageSize).Offset(newPage.StartIndex)
+	list := make([]org.ServiceAccount, 0)
+	affected := db.Find(&list).RowsAffected
+	if affected > 0 {
+		return list, num
+	}
+	return nil, 0
+}
@@ -109,6 +109,18 @@ func (u *userRepo) List(ctx context.Context, db *gorm.DB, id ...string) (res []*
 	return nil
 }
 
+func (u *userRepo) SelectColumns(ctx context.Context, db *gorm.DB, columns []string, id ...string) []map[string]interface{} {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	list := make([]map[string]interface{}, 0)
+	db.Model(&org.User{}).Select(append([]string{"id"}, columns...)).Where("id in (?)", id).Find(&list)
+	return list
+}
+
 func (u *userRepo) SelectByEmailOrPhone(ctx context.Context, db *gorm.DB, info string) (res *org.User) {
 	user := org.User{}
 	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
@@ -166,3 +178,39 @@ func (u *userRepo) Count(ctx context.Context, db *gorm.DB, status, activeStatus
 	}
 	return num1, num2
 }
+
+func (u *userRepo) SelectByOffset(ctx context.Context, db *gorm.DB, notStatus, offset, limit int) (list []*org.User, total int64) {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	db = db.Where("use_status<>?", notStatus)
+	var num int64
+	db.Model(&org.User{}).Count(&num)
+	if limit <= 0 || int64(offset) >= num {
+		return nil, num
+	}
+	users := make([]*org.User, 0)
+	db.Order("created_at, id").Limit(limit).Offset(offset).Find(&users)
+	return users, num
+}
+
+func (u *userRepo) SelectAfterID(ctx context.Context, db *gorm.DB, status int, afterID string, limit int, userIDs []string) []*org.User {
+	if len(userIDs) > 0 {
+		db = db.Where("id in (?)", userIDs)
+	}
+	if status != 0 {
+		db = db.Where("use_status=?", status)
+	}
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		db = db.Where("tenant_id=? or tenant_id is null", tenantID)
+	} else {
+		db = db.Where("tenant_id=?", tenantID)
+	}
+	users := make([]*org.User, 0)
+	db.Where("id>?", afterID).Order("id").Limit(limit).Find(&users)
+	return users
+}
@@ -0,0 +1,39 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+)
+
+// PasswordHistory passwords user has used
+type PasswordHistory struct {
+	ID        string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	UserID    string `gorm:"column:user_id;type:varchar(64);index:user_id" json:"userID"`
+	Password  string `gorm:"column:password;type:varchar(255);" json:"-"`
+	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
+}
+
+// TableName table name
+func (PasswordHistory) TableName() string {
+	return "org_user_password_history"
+}
+
+// PasswordHistoryRepo interface
+type PasswordHistoryRepo interface {
+	Insert(tx *gorm.DB, req *PasswordHistory) error
+	// SelectByUserID newest first
+	SelectByUserID(db *gorm.DB, userID string, limit int) []PasswordHistory
+	// Prune keep the newest ones
+	Prune(tx *gorm.DB, userID string, keep int) error
+}
@@ -0,0 +1,48 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ServiceAccount non-human principal of a tenant, it calls other services with api keys
+type ServiceAccount struct {
+	ID          string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	Name        string `gorm:"column:name;type:varchar(64);" json:"name"`
+	Description string `gorm:"column:description;type:varchar(255);" json:"description"`
+	//1:normal,-2:disabled
+	UseStatus int    `gorm:"column:use_status;type:int;" json:"useStatus"`
+	TenantID  string `gorm:"column:tenant_id;type:varchar(64);" json:"tenantID"`
+
+	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
+	UpdatedAt int64  `gorm:"column:updated_at;type:bigint; " json:"updatedAt,omitempty" comment:"更新时间"`
+	CreatedBy string `gorm:"column:created_by;type:varchar(64); " json:"createdBy,omitempty" comment:"创建者"`
+	UpdatedBy string `gorm:"column:updated_by;type:varchar(64); " json:"updatedBy,omitempty" comment:"修改者"`
+}
+
+// TableName table name
+func (ServiceAccount) TableName() string {
+	return "org_service_account"
+}
+
+// ServiceAccountRepo interface
+type ServiceAccountRepo interface {
+	Insert(ctx context.Context, tx *gorm.DB, req *ServiceAccount) error
+	Update(tx *gorm.DB, req *ServiceAccount) error
+	Delete(ctx context.Context, tx *gorm.DB, id ...string) error
+	Get(db *gorm.DB, id string) *ServiceAccount
+	PageList(ctx context.Context, db *gorm.DB, page, limit int) ([]ServiceAccount, int64)
+}
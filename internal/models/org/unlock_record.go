@@ -0,0 +1,40 @@
+package org
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+)
+
+// UnlockRecord admin unlock ip or account
+type UnlockRecord struct {
+	ID string `gorm:"column:id;type:varchar(64);primaryKey" json:"id"`
+	//ip or account
+	Types string `gorm:"column:types;type:varchar(16);" json:"types"`
+	//ip or user id
+	Target    string `gorm:"column:target;type:varchar(64);" json:"target"`
+	FailCount int64  `gorm:"column:fail_count;type:int;" json:"failCount"`
+	TenantID  string `gorm:"column:tenant_id;type:varchar(64);" json:"tenantID"`
+	CreatedAt int64  `gorm:"column:created_at;type:bigint; " json:"createdAt,omitempty" comment:"创建时间"`
+	CreatedBy string `gorm:"column:created_by;type:varchar(64); " json:"createdBy,omitempty" comment:"创建者"`
+}
+
+// TableName table name
+func (UnlockRecord) TableName() string {
+	return "org_account_unlock_record"
+}
+
+// UnlockRecordRepo interface
+type UnlockRecordRepo interface {
+	InsertBranch(tx *gorm.DB, req ...UnlockRecord) error
+}
@@ -62,6 +62,12 @@ type UserRepo interface {
 	SelectByEmailOrPhone(ctx context.Context, db *gorm.DB, info string) (res *User)
 	GetColumns(ctx context.Context, db *gorm.DB, user *User, schema string) []Columns
 	Count(ctx context.Context, db *gorm.DB, status, activeStatus int) (totalUser, activeUserNum int64)
+	// SelectColumns raw values of the columns, extension columns of the table included
+	SelectColumns(ctx context.Context, db *gorm.DB, columns []string, id ...string) []map[string]interface{}
+	// SelectByOffset users whose use_status is not notStatus, in creation order, offset rows skipped
+	SelectByOffset(ctx context.Context, db *gorm.DB, notStatus, offset, limit int) (list []*User, total int64)
+	// SelectAfterID users whose id is greater than afterID in id order, pages stay stable while users change
+	SelectAfterID(ctx context.Context, db *gorm.DB, status int, afterID string, limit int, userIDs []string) []*User
 }
 
 // Columns db column interface
@@ -165,3 +165,15 @@ func (m *MockAccountRepo) UpdatePasswordByUserID(tx *gorm.DB, res *org.Account)
 func (mr *MockAccountRepoMockRecorder) UpdatePasswordByUserID(tx, res interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordByUserID", reflect.TypeOf((*MockAccountRepo)(nil).UpdatePasswordByUserID), tx, res)
 }
+
+// SelectByPasswordChangedAt mocks base method.
+func (m *MockAccountRepo) SelectByPasswordChangedAt(db *gorm.DB, begin, end int64) []org.Account {
+	ret := m.ctrl.Call(m, "SelectByPasswordChangedAt", db, begin, end)
+	ret0, _ := ret[0].([]org.Account)
+	return ret0
+}
+
+// SelectByPasswordChangedAt indicates an expected call of SelectByPasswordChangedAt.
+func (mr *MockAccountRepoMockRecorder) SelectByPasswordChangedAt(db, begin, end interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByPasswordChangedAt", reflect.TypeOf((*MockAccountRepo)(nil).SelectByPasswordChangedAt), db, begin, end)
+}
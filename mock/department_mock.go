@@ -292,3 +292,18 @@ func (mr *MockDepartmentRepoMockRecorder) Update(ctx, tx, req interface{}) *gomo
 
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockDepartmentRepo)(nil).Update), ctx, tx, req)
 }
+
+// SelectByOffset mocks base method.
+func (m *MockDepartmentRepo) SelectByOffset(ctx context.Context, db *gorm.DB, status, offset, limit int, name string) ([]org.Department, int64) {
+
+	ret := m.ctrl.Call(m, "SelectByOffset", ctx, db, status, offset, limit, name)
+	ret0, _ := ret[0].([]org.Department)
+	ret1, _ := ret[1].(int64)
+	return ret0, ret1
+}
+
+// SelectByOffset indicates an expected call of SelectByOffset.
+func (mr *MockDepartmentRepoMockRecorder) SelectByOffset(ctx, db, status, offset, limit, name interface{}) *gomock.Call {
+
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByOffset", reflect.TypeOf((*MockDepartmentRepo)(nil).SelectByOffset), ctx, db, status, offset, limit, name)
+}
@@ -162,6 +162,23 @@ func (mr *MockUserRepoMockRecorder) PageList(ctx, db, status, page, limit, userI
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PageList", reflect.TypeOf((*MockUserRepo)(nil).PageList), ctx, db, status, page, limit, userIDs)
 }
 
+// SelectColumns mocks base method.
+func (m *MockUserRepo) SelectColumns(ctx context.Context, db *gorm.DB, columns []string, id ...string) []map[string]interface{} {
+	varargs := []interface{}{ctx, db, columns}
+	for _, a := range id {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SelectColumns", varargs...)
+	ret0, _ := ret[0].([]map[string]interface{})
+	return ret0
+}
+
+// SelectColumns indicates an expected call of SelectColumns.
+func (mr *MockUserRepoMockRecorder) SelectColumns(ctx, db, columns interface{}, id ...interface{}) *gomock.Call {
+	varargs := append([]interface{}{ctx, db, columns}, id...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectColumns", reflect.TypeOf((*MockUserRepo)(nil).SelectColumns), varargs...)
+}
+
 // SelectByEmailOrPhone mocks base method.
 func (m *MockUserRepo) SelectByEmailOrPhone(ctx context.Context, db *gorm.DB, info string) *org.User {
 
@@ -296,3 +313,32 @@ func (mr *MockColumnsMockRecorder) New() *gomock.Call {
 
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "New", reflect.TypeOf((*MockColumns)(nil).New))
 }
+
+// SelectByOffset mocks base method.
+func (m *MockUserRepo) SelectByOffset(ctx context.Context, db *gorm.DB, notStatus, offset, limit int) ([]*org.User, int64) {
+
+	ret := m.ctrl.Call(m, "SelectByOffset", ctx, db, notStatus, offset, limit)
+	ret0, _ := ret[0].([]*org.User)
+	ret1, _ := ret[1].(int64)
+	return ret0, ret1
+}
+
+// SelectByOffset indicates an expected call of SelectByOffset.
+func (mr *MockUserRepoMockRecorder) SelectByOffset(ctx, db, notStatus, offset, limit interface{}) *gomock.Call {
+
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectByOffset", reflect.TypeOf((*MockUserRepo)(nil).SelectByOffset), ctx, db, notStatus, offset, limit)
+}
+
+// SelectAfterID mocks base method.
+func (m *MockUserRepo) SelectAfterID(ctx context.Context, db *gorm.DB, status int, afterID string, limit int, userIDs []string) []*org.User {
+
+	ret := m.ctrl.Call(m, "SelectAfterID", ctx, db, status, afterID, limit, userIDs)
+	ret0, _ := ret[0].([]*org.User)
+	return ret0
+}
+
+// SelectAfterID indicates an expected call of SelectAfterID.
+func (mr *MockUserRepoMockRecorder) SelectAfterID(ctx, db, status, afterID, limit, userIDs interface{}) *gomock.Call {
+
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectAfterID", reflect.TypeOf((*MockUserRepo)(nil).SelectAfterID), ctx, db, status, afterID, limit, userIDs)
+}
@@ -30,6 +30,8 @@ const (
 	depByIDsURI     = "/o/dep/ids"
 	usersByDepIDURI = "/o/user/dep/id"
 	depMaxGradeURI  = "/o/dep/max/grade"
+
+	apiKeyHeader = "X-Api-Key"
 )
 
 // User interface api
@@ -53,6 +55,32 @@ func NewUser(conf client.Config) User {
 	}
 }
 
+// NewUserWithAPIKey new, requests carry the api key of a service account
+func NewUserWithAPIKey(conf client.Config, apiKey string) User {
+	c := client.New(conf)
+	c.Transport = &apiKeyTransport{key: apiKey, next: c.Transport}
+	return &user{
+		client: c,
+	}
+}
+
+// apiKeyTransport set the api key header of every request
+type apiKeyTransport struct {
+	key  string
+	next http.RoundTripper
+}
+
+// RoundTrip round trip
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(apiKeyHeader, t.key)
+	return next.RoundTrip(req)
+}
+
 //AddUsersRequest other server add user request
 type AddUsersRequest struct {
 	Users []AddUser `json:"users"`
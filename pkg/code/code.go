@@ -97,6 +97,52 @@ const (
 	ErrFieldColumnUsed = 50034000038
 	// ErrCircleData make a circle data
 	ErrCircleData = 50034000039
+	// ErrFactorEnabled second factor has been enabled
+	ErrFactorEnabled = 50034000040
+	// ErrFactorNotEnroll second factor not enroll
+	ErrFactorNotEnroll = 50034000041
+	// InvalidFactorCode invalid second factor code
+	InvalidFactorCode = 50034000042
+	// ExpireFactorTicket login ticket was expired
+	ExpireFactorTicket = 50034000043
+	// ErrPasswordUsed password was used recently
+	ErrPasswordUsed = 50034000044
+	// LockedIP too many failed login from the ip
+	LockedIP = 50034000045
+	// LimitVerificationCode too many codes sent to the recipient or from the ip
+	LimitVerificationCode = 50034000046
+	// ForbiddenLoginType login type is not allowed by tenant
+	ForbiddenLoginType = 50034000047
+	// InvalidLoginLink login link is broken, used or expired
+	InvalidLoginLink = 50034000048
+	// InvalidFilter scim filter can not be parsed
+	InvalidFilter = 50034000049
+	// InvalidPatch scim patch operation can not be applied
+	InvalidPatch = 50034000050
+	// InvalidClient oidc client is unknown or its secret is wrong
+	InvalidClient = 50034000051
+	// InvalidRedirectURI redirect uri is not registered by the client
+	InvalidRedirectURI = 50034000052
+	// InvalidGrant authorization code is invalid, used or expired
+	InvalidGrant = 50034000053
+	// UnsupportedGrantType grant type is not supported
+	UnsupportedGrantType = 50034000054
+	// InvalidAccessToken access token is invalid or expired
+	InvalidAccessToken = 50034000055
+	// InvalidAPIKey api key is unknown, expired or its service account is disabled
+	InvalidAPIKey = 50034000056
+	// InvalidInvitation invitation is unknown, expired, revoked or accepted
+	InvalidInvitation = 50034000057
+	// ForbiddenImpersonation admins and oneself can not be impersonated
+	ForbiddenImpersonation = 50034000058
+	// InvalidImpersonation impersonation is unknown, expired or of another admin
+	InvalidImpersonation = 50034000059
+	// ImportJobEnded import job is not running any more
+	ImportJobEnded = 50034000060
+	// ImpersonatedCredential credentials of the user can not be changed while impersonating
+	ImpersonatedCredential = 50034000061
+	// TooManyResults scim filter is matched against more resources than allowed
+	TooManyResults = 50034000062
 )
 
 // CodeTable 码表
@@ -140,4 +186,27 @@ var CodeTable = error2.Table{
 	ErrHasBeActive:          "数据中包含已被激活数据，请选择正确数据再操作！",
 	ErrFieldColumnUsed:      "扩展字段功能已被开启，请不要重复操作！",
 	ErrCircleData:           "数据关系成环，请检查后提交！",
+	ErrFactorEnabled:        "二次验证已开启，请勿重复绑定！",
+	ErrFactorNotEnroll:      "未绑定二次验证，请先完成绑定！",
+	InvalidFactorCode:       "动态验证码错误，请检查后重试！",
+	ExpireFactorTicket:      "登录已超时，请重新登录！",
+	ErrPasswordUsed:         "新密码不能与最近使用过的%d个密码相同！",
+	LockedIP:                "当前IP登录失败次数过多，请于%d分钟后重试！",
+	LimitVerificationCode:   "验证码获取过于频繁，请稍后再试！",
+	ForbiddenLoginType:      "当前登录方式未开启！",
+	InvalidLoginLink:        "登录链接已失效，请重新获取！",
+	InvalidFilter:           "过滤条件格式错误！",
+	InvalidPatch:            "修改操作无效！",
+	InvalidClient:           "客户端认证失败！",
+	InvalidRedirectURI:      "回调地址无效！",
+	InvalidGrant:            "授权码无效或已过期！",
+	UnsupportedGrantType:    "不支持的授权类型！",
+	InvalidAccessToken:      "访问令牌无效或已过期！",
+	InvalidAPIKey:           "API密钥无效或已过期！",
+	InvalidInvitation:       "邀请链接无效或已过期！",
+	ForbiddenImpersonation:  "不能模拟管理员或自己！",
+	InvalidImpersonation:    "模拟会话无效或已过期！",
+	ImportJobEnded:          "导入任务已结束！",
+	ImpersonatedCredential:  "模拟用户时不能修改账号凭据！",
+	TooManyResults:          "匹配的数据过多，请使用更精确的过滤条件！",
 }
@@ -34,6 +34,7 @@ var DefaultOctopusPath = "./configs/octopus.yml"
 // Config config
 type Config struct {
 	MaxLoginErrNum   int              `yaml:"maxLoginErrNum"`
+	PasswordHistory  int              `yaml:"passwordHistory"`
 	LockAccountTime  time.Duration    `yaml:"lockAccountTime"`
 	InternalNet      client.Config    `yaml:"internalNet"`
 	ProcessPort      string           `yaml:"processPort"`
@@ -50,6 +51,17 @@ type Config struct {
 	MessageTemplate  MessageTemplate  `yaml:"messageTemplate"`
 	Elastic          elastic.Config   `yaml:"elastic"`
 	Ldap             Ldap             `yaml:"ldap"`
+	PasswordHash     PasswordHash     `yaml:"passwordHash"`
+	TwoFactor        TwoFactor        `yaml:"twoFactor"`
+	LoginAudit       LoginAudit       `yaml:"loginAudit"`
+	LoginLink        LoginLink        `yaml:"loginLink"`
+	LdapSync         LdapSync         `yaml:"ldapSync"`
+	Scim             Scim             `yaml:"scim"`
+	Oidc             Oidc             `yaml:"oidc"`
+	ServiceAccount   ServiceAccount   `yaml:"serviceAccount"`
+	Invitation       Invitation       `yaml:"invitation"`
+	Impersonation    Impersonation    `yaml:"impersonation"`
+	ImportJob        ImportJob        `yaml:"importJob"`
 }
 
 // Service service config
@@ -63,7 +75,18 @@ type VerificationCode struct {
 	ResetCode    string        `yaml:"resetCode"`
 	ForgetCode   string        `yaml:"forgetCode"`
 	RegisterCode string        `yaml:"registerCode"`
+	LoginLink    string        `yaml:"loginLink"`
+	ChangeCode   string        `yaml:"changeCode"`
 	ExpireTime   time.Duration `yaml:"expireTime"`
+	// send quotas per recipient and per ip, 0 for unlimited
+	MinuteLimit   int64 `yaml:"minuteLimit"`
+	DayLimit      int64 `yaml:"dayLimit"`
+	IPMinuteLimit int64 `yaml:"ipMinuteLimit"`
+	IPDayLimit    int64 `yaml:"ipDayLimit"`
+	// MaxAttempts wrong values allowed before the code is dropped, 0 for unlimited
+	MaxAttempts int64 `yaml:"maxAttempts"`
+	// Echo return the code in response, it works only with poc or debug model
+	Echo bool `yaml:"echo"`
 }
 
 // MessageTemplate message
@@ -74,12 +97,157 @@ type MessageTemplate struct {
 	RegisterCode string `yaml:"registerCode"`
 	ResetPWD     string `yaml:"resetPWD"`
 	NewPWD       string `yaml:"newPWD"`
+	PwdExpire    string `yaml:"pwdExpire"`
+	Unlock       string `yaml:"unlock"`
+	LoginLink    string `yaml:"loginLink"`
+	Invitation   string `yaml:"invitation"`
+	ChangeCode   string `yaml:"changeCode"`
 }
 
 // Ldap ldap
 type Ldap struct {
 	Open  bool   `yaml:"open"`
 	Regex string `yaml:"regex"`
+	// Directories are bound directly, users of other domains go through the ldap service
+	Directories []LdapDirectory `yaml:"directories"`
+}
+
+// LdapDirectory ldap or active directory server serving some mail domains
+type LdapDirectory struct {
+	// TenantID empty serves every tenant, a tenant's own directory wins
+	TenantID string   `yaml:"tenantID"`
+	Domains  []string `yaml:"domains"`
+	// URL ldap://host:389 or ldaps://host:636
+	URL                string `yaml:"url"`
+	StartTLS           bool   `yaml:"startTLS"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+	// BindDN searches the user entry, anonymous when empty
+	BindDN       string `yaml:"bindDN"`
+	BindPassword string `yaml:"bindPassword"`
+	BaseDN       string `yaml:"baseDN"`
+	// Filter %s is the escaped login name, default (mail=%s)
+	Filter string `yaml:"filter"`
+	// Timeout seconds
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// PasswordHash password hash
+type PasswordHash struct {
+	// Algorithm bcrypt or argon2id, default bcrypt
+	Algorithm     string `yaml:"algorithm"`
+	BcryptCost    int    `yaml:"bcryptCost"`
+	Argon2Time    uint32 `yaml:"argon2Time"`
+	Argon2Memory  uint32 `yaml:"argon2Memory"`
+	Argon2Threads uint8  `yaml:"argon2Threads"`
+}
+
+// TwoFactor totp second factor
+type TwoFactor struct {
+	Issuer string `yaml:"issuer"`
+	// TicketExpire seconds between password checked and second factor verified
+	TicketExpire time.Duration `yaml:"ticketExpire"`
+}
+
+// LoginAudit login audit
+type LoginAudit struct {
+	// RetentionDays login events older than it are pruned, 0 keeps all
+	RetentionDays int64 `yaml:"retentionDays"`
+}
+
+// LoginLink magic link login
+type LoginLink struct {
+	// URL login page, the token is appended to it
+	URL string `yaml:"url"`
+	// Secret signs the token, link login is off when it is empty
+	Secret string `yaml:"secret"`
+}
+
+// Scim scim provisioning
+type Scim struct {
+	// Tokens bearer tokens of provisioning clients, scim is off when empty
+	Tokens []ScimToken `yaml:"tokens"`
+}
+
+// ScimToken bearer token bound to a tenant
+type ScimToken struct {
+	TenantID string `yaml:"tenantID"`
+	Token    string `yaml:"token"`
+}
+
+// Oidc openid connect provider, it is off when issuer is empty
+type Oidc struct {
+	// Issuer public url of /api/v1/org/oidc
+	Issuer string `yaml:"issuer"`
+	// KeyFile pem rsa private key signing tokens, a key is generated on start when empty
+	KeyFile string `yaml:"keyFile"`
+	// LoginURL login page, authorize requests without a login user go to it with their query
+	LoginURL string `yaml:"loginURL"`
+	// CodeExpire authorization code lifetime in seconds, default 60
+	CodeExpire time.Duration `yaml:"codeExpire"`
+	// TokenExpire access token and id token lifetime in seconds, default 3600
+	TokenExpire time.Duration `yaml:"tokenExpire"`
+}
+
+// ServiceAccount api keys of service accounts calling /o
+type ServiceAccount struct {
+	// Required reject /o requests without an api key, callers relying on the network keep working when off
+	Required bool `yaml:"required"`
+	// RotateGrace seconds a rotated key keeps working, default 86400
+	RotateGrace time.Duration `yaml:"rotateGrace"`
+}
+
+// Invitation invitation links of new and activated users, they set their own password
+type Invitation struct {
+	// URL accept page, the token is appended to it
+	URL string `yaml:"url"`
+	// Secret signs the token, generated passwords are mailed as before when it is empty
+	Secret string `yaml:"secret"`
+	// Expire seconds an invitation works, default 259200
+	Expire time.Duration `yaml:"expire"`
+	// SendPassword mail a generated password instead of an invitation
+	SendPassword bool `yaml:"sendPassword"`
+}
+
+// Impersonation admins acting as users to see what they see
+type Impersonation struct {
+	// Expire seconds an impersonation lasts, default 1800
+	Expire time.Duration `yaml:"expire"`
+	// AdminRoles tags of roles whose owners can not be impersonated, default super
+	AdminRoles []string `yaml:"adminRoles"`
+}
+
+// ImportJob user imports running in the background
+type ImportJob struct {
+	// StaleTime seconds a running job may go without progress, it is failed at startup afterwards, default 1800
+	StaleTime time.Duration `yaml:"staleTime"`
+}
+
+// LdapSync directory sync job, it reads users and organizational units
+type LdapSync struct {
+	// Directory read by the job, its domains are not used
+	Directory LdapDirectory `yaml:"directory"`
+	// UserFilter default (&(objectClass=person)(!(objectClass=computer)))
+	UserFilter string `yaml:"userFilter"`
+	// OUFilter default (objectClass=organizationalUnit)
+	OUFilter string `yaml:"ouFilter"`
+	// RootName name of the company department created when the tenant has none
+	RootName   string         `yaml:"rootName"`
+	PageSize   uint32         `yaml:"pageSize"`
+	Attributes LdapAttributes `yaml:"attributes"`
+}
+
+// LdapAttributes directory attributes read into users and departments
+type LdapAttributes struct {
+	// ID objectGUID or entryUUID, ids are derived from the dn when empty
+	ID        string `yaml:"id"`
+	Name      string `yaml:"name"`
+	Email     string `yaml:"email"`
+	Phone     string `yaml:"phone"`
+	JobNumber string `yaml:"jobNumber"`
+	Position  string `yaml:"position"`
+	// Manager holds the dn of the leader
+	Manager string `yaml:"manager"`
+	OUName  string `yaml:"ouName"`
 }
 
 // NewConfig new
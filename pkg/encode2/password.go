@@ -0,0 +1,156 @@
+package encode2
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+)
+
+const (
+	// Bcrypt bcrypt algorithm
+	Bcrypt = "bcrypt"
+	// Argon2id argon2id algorithm
+	Argon2id = "argon2id"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+	md5HexLen     = 32
+)
+
+// ErrInvalidHash encoded password can not be parsed
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Hasher password hasher
+type Hasher interface {
+	// Hash encode the plain password with the configured algorithm
+	Hash(password string) (string, error)
+	// Verify compare the plain password with the stored one,
+	// rehash is true when the stored one should be upgraded to the configured algorithm
+	Verify(password, encoded string) (ok, rehash bool)
+}
+
+type hasher struct {
+	conf configs.PasswordHash
+}
+
+// NewHasher new
+func NewHasher(conf configs.PasswordHash) Hasher {
+	if conf.Algorithm != Argon2id {
+		conf.Algorithm = Bcrypt
+	}
+	if conf.BcryptCost < bcrypt.MinCost || conf.BcryptCost > bcrypt.MaxCost {
+		conf.BcryptCost = bcrypt.DefaultCost
+	}
+	if conf.Argon2Time == 0 {
+		conf.Argon2Time = 1
+	}
+	if conf.Argon2Memory == 0 {
+		conf.Argon2Memory = 64 * 1024
+	}
+	if conf.Argon2Threads == 0 {
+		conf.Argon2Threads = 4
+	}
+	return &hasher{
+		conf: conf,
+	}
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	if h.conf.Algorithm == Argon2id {
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.conf.Argon2Time, h.conf.Argon2Memory, h.conf.Argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, h.conf.Argon2Memory, h.conf.Argon2Time, h.conf.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.conf.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (h *hasher) Verify(password, encoded string) (bool, bool) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, err := parseArgon2(encoded)
+		if err != nil {
+			return false, false
+		}
+		key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+		if subtle.ConstantTimeCompare(key, p.key) != 1 {
+			return false, false
+		}
+		return true, h.conf.Algorithm != Argon2id ||
+			p.time != h.conf.Argon2Time || p.memory != h.conf.Argon2Memory || p.threads != h.conf.Argon2Threads
+	case strings.HasPrefix(encoded, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return true, h.conf.Algorithm != Bcrypt || err != nil || cost != h.conf.BcryptCost
+	case len(encoded) == md5HexLen:
+		// legacy unsalted md5, always upgraded after a successful login
+		if subtle.ConstantTimeCompare([]byte(MD5Encode(password)), []byte(strings.ToLower(encoded))) != 1 {
+			return false, false
+		}
+		return true, true
+	}
+	return false, false
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2(encoded string) (*argon2Params, error) {
+	// $argon2id$v=19$m=65536,t=1,p=4$salt$key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, ErrInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrInvalidHash
+	}
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, ErrInvalidHash
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return nil, ErrInvalidHash
+	}
+	return p, nil
+}
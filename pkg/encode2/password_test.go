@@ -0,0 +1,79 @@
+package encode2
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"strings"
+	"testing"
+
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+)
+
+func TestHasher(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   configs.PasswordHash
+		prefix string
+	}{
+		{name: "bcrypt", conf: configs.PasswordHash{Algorithm: Bcrypt, BcryptCost: 4}, prefix: "$2a$04$"},
+		{name: "argon2id", conf: configs.PasswordHash{Algorithm: Argon2id, Argon2Memory: 1024}, prefix: "$argon2id$v=19$m=1024,t=1,p=4$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHasher(tt.conf)
+			encoded, err := h.Hash("654321a..")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(encoded, tt.prefix) {
+				t.Fatalf("unexpected hash %s", encoded)
+			}
+			if ok, rehash := h.Verify("654321a..", encoded); !ok || rehash {
+				t.Fatalf("verify got ok=%v rehash=%v", ok, rehash)
+			}
+			if ok, _ := h.Verify("654321a.", encoded); ok {
+				t.Fatal("wrong password verified")
+			}
+		})
+	}
+}
+
+func TestHasherUpgrade(t *testing.T) {
+	bc := NewHasher(configs.PasswordHash{Algorithm: Bcrypt, BcryptCost: 4})
+	ar := NewHasher(configs.PasswordHash{Algorithm: Argon2id, Argon2Memory: 1024})
+
+	legacy := MD5Encode("654321a..")
+	if ok, rehash := bc.Verify("654321a..", legacy); !ok || !rehash {
+		t.Fatalf("legacy md5 got ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, _ := bc.Verify("654321a.", legacy); ok {
+		t.Fatal("wrong password verified against md5")
+	}
+
+	encoded, _ := bc.Hash("654321a..")
+	if ok, rehash := ar.Verify("654321a..", encoded); !ok || !rehash {
+		t.Fatalf("bcrypt under argon2id got ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, rehash := NewHasher(configs.PasswordHash{Algorithm: Bcrypt, BcryptCost: 5}).Verify("654321a..", encoded); !ok || !rehash {
+		t.Fatalf("bcrypt cost change got ok=%v rehash=%v", ok, rehash)
+	}
+
+	encoded, _ = ar.Hash("654321a..")
+	if ok, rehash := bc.Verify("654321a..", encoded); !ok || !rehash {
+		t.Fatalf("argon2id under bcrypt got ok=%v rehash=%v", ok, rehash)
+	}
+
+	if ok, _ := bc.Verify("654321a..", "$argon2id$broken"); ok {
+		t.Fatal("broken hash verified")
+	}
+}
@@ -0,0 +1,57 @@
+package encode2
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+const tokenSeparator = "|"
+
+// SignToken token is subject|nonce and its hmac, both base64 url encoded,
+// subject must not contain the separator
+func SignToken(secret, subject, nonce string) string {
+	payload := []byte(subject + tokenSeparator + nonce)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ParseToken check signature of token and return subject and nonce
+func ParseToken(secret, token string) (string, string, bool) {
+	parts := strings.Split(token, ".")
+	if secret == "" || len(parts) != 2 {
+		return "", "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", false
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return "", "", false
+	}
+	values := strings.SplitN(string(payload), tokenSeparator, 2)
+	if len(values) != 2 {
+		return "", "", false
+	}
+	return values[0], values[1], true
+}
@@ -0,0 +1,41 @@
+package encode2
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"testing"
+)
+
+func TestParseToken(t *testing.T) {
+	token := SignToken("secret", "test1@test.com", "nonce")
+	subject, nonce, ok := ParseToken("secret", token)
+	if !ok || subject != "test1@test.com" || nonce != "nonce" {
+		t.Fatalf("unexpected %s %s %v", subject, nonce, ok)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		secret string
+		token  string
+	}{
+		{name: "other secret", secret: "other", token: token},
+		{name: "no secret", secret: "", token: token},
+		{name: "tampered", secret: "secret", token: token + "x"},
+		{name: "signed by other", secret: "secret", token: SignToken("other", "test2@test.com", "nonce")},
+		{name: "malformed", secret: "secret", token: "token"},
+	} {
+		if _, _, ok := ParseToken(tt.secret, tt.token); ok {
+			t.Fatalf("%s: token accepted", tt.name)
+		}
+	}
+}
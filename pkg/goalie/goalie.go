@@ -23,12 +23,14 @@ import (
 const (
 	host = "http://goalie/api/v1/goalie/role"
 
-	delOwner = "/del/owner"
+	delOwner  = "/del/owner"
+	userRoles = "/user/roles"
 )
 
 // Goalie interface api
 type Goalie interface {
 	DelOwner(ctx context.Context, r *OthDelRequest) (*OthDelResponse, error)
+	UserRoles(ctx context.Context, r *UserRolesRequest) (*UserRolesResponse, error)
 }
 type goalie struct {
 	client http.Client
@@ -60,3 +62,30 @@ func (u *goalie) DelOwner(ctx context.Context, r *OthDelRequest) (*OthDelRespons
 	}
 	return response, err
 }
+
+// UserRolesRequest roles of a user
+type UserRolesRequest struct {
+	UserID string `json:"userID"`
+}
+
+// Role role of a user
+type Role struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Tag  string `json:"tag"`
+}
+
+// UserRolesResponse roles of a user
+type UserRolesResponse struct {
+	Roles []Role `json:"roles"`
+}
+
+// UserRoles roles the user owns
+func (u *goalie) UserRoles(ctx context.Context, r *UserRolesRequest) (*UserRolesResponse, error) {
+	response := &UserRolesResponse{}
+	err := client.POST(ctx, &u.client, host+userRoles, r, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, err
+}
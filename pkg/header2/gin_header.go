@@ -25,6 +25,8 @@ const (
 	_userName     = "User-Name"
 	_departmentID = "Department-Id"
 	_tenantID     = "Tenant-Id"
+	// _impersonatorID admin acting as the user of the profile
+	_impersonatorID = "Impersonator-Id"
 )
 
 // Profile proile
@@ -54,6 +56,24 @@ func getProfileFromGIN(c *gin.Context) Profile {
 	}
 }
 
+// SetProfile replace the profile headers of request
+func SetProfile(c *gin.Context, profile Profile) {
+	c.Request.Header.Set(_userID, profile.UserID)
+	c.Request.Header.Set(_userName, profile.UserName)
+	c.Request.Header.Set(_departmentID, profile.DepartmentID)
+	c.Request.Header.Set(_tenantID, profile.TenantID)
+}
+
+// GetImpersonator admin acting as the user of profile, empty when nobody impersonates
+func GetImpersonator(c *gin.Context) string {
+	return c.GetHeader(_impersonatorID)
+}
+
+// SetImpersonator mark the request as made by an admin acting as the user of profile
+func SetImpersonator(c *gin.Context, actorID string) {
+	c.Request.Header.Set(_impersonatorID, actorID)
+}
+
 // GetDepartments get departments
 func GetDepartments(c *gin.Context) [][]string {
 	departmentID := c.GetHeader(_departmentID)
@@ -0,0 +1,387 @@
+package logic
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/other"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	ldap "github.com/quanxiang-cloud/organizations/pkg/ladp"
+)
+
+const (
+	source = "ldap"
+
+	defaultUserFilter = "(&(objectClass=person)(!(objectClass=computer)))"
+	defaultOUFilter   = "(objectClass=organizationalUnit)"
+	defaultPageSize   = 500
+)
+
+// Sync ldap sync job
+type Sync interface {
+	SyncData(ctx context.Context, req *SyncRequest) (*SyncResponse, error)
+}
+
+type sync struct {
+	DB          *gorm.DB
+	conf        configs.LdapSync
+	Oth         other.OthServer
+	accountRepo org.AccountRepo
+	depRepo     org.DepartmentRepo
+}
+
+// NewSync new
+func NewSync(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient) Sync {
+	user.NewSearch(db)
+	return &sync{
+		DB:          db,
+		conf:        withDefaults(conf.LdapSync),
+		Oth:         other.NewOtherServer(conf, db, redisClient),
+		accountRepo: mysql.NewAccountRepo(),
+		depRepo:     mysql.NewDepartmentRepo(),
+	}
+}
+
+// SyncRequest sync
+type SyncRequest struct {
+	SyncDEP  int
+	IsUpdate int
+	TenantID string
+}
+
+// SyncResponse sync
+type SyncResponse struct {
+	Users int
+	Deps  int
+}
+
+// SyncData sync data
+func (s *sync) SyncData(ctx context.Context, req *SyncRequest) (*SyncResponse, error) {
+	//1、read the directory
+	ous, people, err := Read(s.conf)
+	if err != nil {
+		logger.Logger.Error(err)
+		return nil, err
+	}
+	//2、map entries to departments and users
+	var rootID string
+	if supper := s.depRepo.SelectSupper(ctx, s.DB); supper != nil {
+		rootID = supper.ID
+	}
+	deps, users := Convert(s.conf, ous, people, rootID, func(email string) string {
+		if acc := s.accountRepo.SelectByAccount(s.DB, email); acc != nil {
+			return acc.UserID
+		}
+		return ""
+	})
+	//3、save through the other server
+	if req.SyncDEP == 1 {
+		ad := &other.AddDepartmentRequest{}
+		ad.Deps = deps
+		ad.SyncDEP = req.SyncDEP
+		ad.IsUpdate = req.IsUpdate
+		ad.SyncSource = source
+		_, err := s.Oth.AddDepartments(ctx, ad)
+		if err != nil {
+			logger.Logger.Error(err)
+			return nil, err
+		}
+	} else {
+		for k := range users {
+			users[k].DepsID = nil
+		}
+	}
+	au := &other.AddUsersRequest{}
+	au.Users = users
+	au.SyncDEP = req.SyncDEP
+	au.IsUpdate = req.IsUpdate
+	au.SyncSource = source
+	res, err := s.Oth.AddUsers(ctx, au)
+	if err != nil {
+		logger.Logger.Error(err)
+		return nil, err
+	}
+	for k, v := range res.Result {
+		if v.Remark != "" {
+			logger.Logger.Warnf("sync %s: %s", users[k].Email, v.Remark)
+		}
+	}
+
+	sig1 := make(chan int, 1)
+	sig2 := make(chan int, 1)
+	to := make(chan int)
+
+	go s.Oth.PushUserToSearch(ctx, sig2, to)
+	go s.Oth.PushDepToSearch(ctx, sig1)
+
+	var i = 0
+	var total = 0
+	for {
+		if total != 0 && i >= (total) {
+			return &SyncResponse{Users: len(users), Deps: len(deps)}, nil
+		}
+		select {
+		case total = <-to:
+		case n := <-sig1:
+			i = i + n
+		case n := <-sig2:
+			i = i + n
+
+		}
+	}
+}
+
+// Read organizational units and people under the base dn
+func Read(conf configs.LdapSync) (ous, people []*goldap.Entry, err error) {
+	conn, err := ldap.Dial(&conf.Directory)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	attrs := conf.Attributes
+	ous, err = search(conn, conf, conf.OUFilter, attrs.ID, attrs.OUName)
+	if err != nil {
+		return nil, nil, err
+	}
+	people, err = search(conn, conf, conf.UserFilter,
+		attrs.ID, attrs.Name, attrs.Email, attrs.Phone, attrs.JobNumber, attrs.Position, attrs.Manager)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ous, people, nil
+}
+
+func search(conn *goldap.Conn, conf configs.LdapSync, filter string, attributes ...string) ([]*goldap.Entry, error) {
+	fields := make([]string, 0, len(attributes))
+	for _, v := range attributes {
+		if v != "" {
+			fields = append(fields, v)
+		}
+	}
+	res, err := conn.SearchWithPaging(goldap.NewSearchRequest(
+		conf.Directory.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+		filter, fields, nil,
+	), conf.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return res.Entries, nil
+}
+
+// Convert map organizational units to departments and people to users,
+// rootID is the company department, a new one is added when it is empty,
+// existing returns the user id of an email already in the org
+func Convert(conf configs.LdapSync, ous, people []*goldap.Entry, rootID string, existing func(email string) string) ([]other.AddDep, []other.AddUser) {
+	attrs := conf.Attributes
+	deps := make([]other.AddDep, 0, len(ous)+1)
+	if rootID == "" {
+		rootID = dnID(conf.Directory.BaseDN)
+		name := conf.RootName
+		if name == "" {
+			name = rdnValue(conf.Directory.BaseDN)
+		}
+		deps = append(deps, other.AddDep{
+			ID:        rootID,
+			Name:      name,
+			UseStatus: consts.NormalStatus,
+			Attr:      consts.DepAttrCOM,
+		})
+	}
+
+	base := normalizeDN(conf.Directory.BaseDN)
+	depIDs := make(map[string]string, len(ous))
+	for _, v := range ous {
+		dn := normalizeDN(v.DN)
+		if dn == base {
+			// the base itself is the company
+			depIDs[dn] = rootID
+			continue
+		}
+		depIDs[dn] = entryID(attrs.ID, v)
+	}
+	parentID := func(dn string) string {
+		if id, ok := depIDs[parentDN(dn)]; ok {
+			return id
+		}
+		return rootID
+	}
+	for _, v := range ous {
+		if normalizeDN(v.DN) == base {
+			continue
+		}
+		name := v.GetAttributeValue(attrs.OUName)
+		if name == "" {
+			name = rdnValue(v.DN)
+		}
+		deps = append(deps, other.AddDep{
+			ID:        depIDs[normalizeDN(v.DN)],
+			Name:      name,
+			UseStatus: consts.NormalStatus,
+			Attr:      consts.DepAttrDEP,
+			PID:       parentID(v.DN),
+		})
+	}
+
+	userIDs := make(map[string]string, len(people))
+	for _, v := range people {
+		id := ""
+		if email := v.GetAttributeValue(attrs.Email); email != "" && existing != nil {
+			id = existing(email)
+		}
+		if id == "" {
+			id = entryID(attrs.ID, v)
+		}
+		userIDs[normalizeDN(v.DN)] = id
+	}
+	users := make([]other.AddUser, 0, len(people))
+	for _, v := range people {
+		name := v.GetAttributeValue(attrs.Name)
+		if name == "" {
+			name = rdnValue(v.DN)
+		}
+		addUser := other.AddUser{
+			ID:        userIDs[normalizeDN(v.DN)],
+			Name:      name,
+			Email:     v.GetAttributeValue(attrs.Email),
+			Phone:     v.GetAttributeValue(attrs.Phone),
+			JobNumber: v.GetAttributeValue(attrs.JobNumber),
+			Position:  v.GetAttributeValue(attrs.Position),
+			UseStatus: consts.NormalStatus,
+			DepsID:    []string{parentID(v.DN)},
+			Source:    source,
+		}
+		if manager := v.GetAttributeValue(attrs.Manager); manager != "" {
+			// managers out of the synced subtree are dropped
+			if leaderID, ok := userIDs[normalizeDN(manager)]; ok {
+				addUser.LeadersID = []string{leaderID}
+			}
+		}
+		users = append(users, addUser)
+	}
+	return deps, users
+}
+
+func withDefaults(conf configs.LdapSync) configs.LdapSync {
+	if conf.UserFilter == "" {
+		conf.UserFilter = defaultUserFilter
+	}
+	if conf.OUFilter == "" {
+		conf.OUFilter = defaultOUFilter
+	}
+	if conf.PageSize == 0 {
+		conf.PageSize = defaultPageSize
+	}
+	attrs := &conf.Attributes
+	for _, v := range []struct {
+		field *string
+		value string
+	}{
+		{&attrs.Name, "cn"},
+		{&attrs.Email, "mail"},
+		{&attrs.Phone, "mobile"},
+		{&attrs.JobNumber, "employeeNumber"},
+		{&attrs.Position, "title"},
+		{&attrs.Manager, "manager"},
+		{&attrs.OUName, "ou"},
+	} {
+		if *v.field == "" {
+			*v.field = v.value
+		}
+	}
+	return conf
+}
+
+// entryID id kept across syncs, from the id attribute or else the dn
+func entryID(attr string, entry *goldap.Entry) string {
+	if attr != "" {
+		if raw := entry.GetRawAttributeValue(attr); len(raw) > 0 {
+			// objectGUID is binary
+			if !printable(raw) {
+				return hex.EncodeToString(raw)
+			}
+			if len(raw) <= 64 {
+				return string(raw)
+			}
+		}
+	}
+	return dnID(entry.DN)
+}
+
+func dnID(dn string) string {
+	sum := md5.Sum([]byte(normalizeDN(dn)))
+	return hex.EncodeToString(sum[:])
+}
+
+func printable(raw []byte) bool {
+	if !utf8.Valid(raw) {
+		return false
+	}
+	for _, r := range string(raw) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeDN(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	return joinRDNs(parsed.RDNs)
+}
+
+func parentDN(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+	return joinRDNs(parsed.RDNs[1:])
+}
+
+func joinRDNs(list []*goldap.RelativeDN) string {
+	rdns := make([]string, 0, len(list))
+	for _, rdn := range list {
+		attrs := make([]string, 0, len(rdn.Attributes))
+		for _, v := range rdn.Attributes {
+			attrs = append(attrs, strings.ToLower(v.Type)+"="+strings.ToLower(v.Value))
+		}
+		rdns = append(rdns, strings.Join(attrs, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
+
+func rdnValue(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return dn
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
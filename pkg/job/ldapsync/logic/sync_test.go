@@ -0,0 +1,116 @@
+package logic
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/ladp/ldaptest"
+)
+
+func TestReadAndConvert(t *testing.T) {
+	server, err := ldaptest.NewServer(
+		&ldaptest.Entry{DN: "cn=reader,dc=test,dc=com", Password: "reader"},
+		&ldaptest.Entry{DN: "ou=staff,dc=test,dc=com", Attributes: map[string][]string{
+			"objectClass": {"organizationalUnit"}, "ou": {"staff"},
+		}},
+		&ldaptest.Entry{DN: "ou=dev,ou=staff,dc=test,dc=com", Attributes: map[string][]string{
+			"objectClass": {"organizationalUnit"}, "ou": {"研发部"},
+		}},
+		&ldaptest.Entry{DN: "cn=boss,ou=staff,dc=test,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person"}, "cn": {"boss"}, "mail": {"boss@test.com"}, "displayName": {"老板"},
+		}},
+		&ldaptest.Entry{DN: "cn=zhangsan,ou=dev,ou=staff,dc=test,dc=com", Attributes: map[string][]string{
+			"objectClass":    {"person"},
+			"cn":             {"zhangsan"},
+			"mail":           {"zhangsan@test.com"},
+			"mobile":         {"13688886666"},
+			"employeeNumber": {"007"},
+			"title":          {"engineer"},
+			"manager":        {"CN=boss,OU=staff,DC=test,DC=com"},
+		}},
+		&ldaptest.Entry{DN: "cn=pc1,ou=dev,ou=staff,dc=test,dc=com", Attributes: map[string][]string{
+			"objectClass": {"person", "computer"}, "cn": {"pc1"},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	conf := withDefaults(configs.LdapSync{
+		Directory: configs.LdapDirectory{
+			URL:          server.URL,
+			BindDN:       "cn=reader,dc=test,dc=com",
+			BindPassword: "reader",
+			BaseDN:       "ou=staff,dc=test,dc=com",
+		},
+		RootName: "test",
+		PageSize: 1,
+	})
+	conf.Attributes.Name = "displayName"
+	ous, people, err := Read(conf)
+	assert.Nil(t, err)
+	assert.Len(t, ous, 2)
+	assert.Len(t, people, 2)
+
+	deps, users := Convert(conf, ous, people, "", func(email string) string {
+		if email == "boss@test.com" {
+			return "boss-id"
+		}
+		return ""
+	})
+	rootID := dnID("ou=staff,dc=test,dc=com")
+	devID := dnID("ou=dev,ou=staff,dc=test,dc=com")
+	if assert.Len(t, deps, 2) {
+		assert.Equal(t, rootID, deps[0].ID)
+		assert.Equal(t, "test", deps[0].Name)
+		assert.Equal(t, "", deps[0].PID)
+		assert.Equal(t, devID, deps[1].ID)
+		assert.Equal(t, "研发部", deps[1].Name)
+		assert.Equal(t, rootID, deps[1].PID)
+	}
+	userMap := make(map[string]int)
+	for k := range users {
+		userMap[users[k].Email] = k
+	}
+	boss := users[userMap["boss@test.com"]]
+	assert.Equal(t, "boss-id", boss.ID)
+	assert.Equal(t, "老板", boss.Name)
+	assert.Equal(t, []string{rootID}, boss.DepsID)
+	assert.Empty(t, boss.LeadersID)
+	zhangsan := users[userMap["zhangsan@test.com"]]
+	assert.Equal(t, dnID("cn=zhangsan,ou=dev,ou=staff,dc=test,dc=com"), zhangsan.ID)
+	assert.Equal(t, "zhangsan", zhangsan.Name)
+	assert.Equal(t, "13688886666", zhangsan.Phone)
+	assert.Equal(t, "007", zhangsan.JobNumber)
+	assert.Equal(t, "engineer", zhangsan.Position)
+	assert.Equal(t, []string{devID}, zhangsan.DepsID)
+	assert.Equal(t, []string{"boss-id"}, zhangsan.LeadersID)
+
+	// an existing company adopts the top organizational units
+	deps, users = Convert(conf, ous, people, "company", nil)
+	if assert.Len(t, deps, 1) {
+		assert.Equal(t, "company", deps[0].PID)
+	}
+	assert.Equal(t, []string{"company"}, users[userMap["boss@test.com"]].DepsID)
+}
+
+func TestEntryID(t *testing.T) {
+	assert.Equal(t, dnID("CN=a, OU=b"), dnID("cn=a,ou=b"))
+	assert.Equal(t, "ou=b", parentDN("CN=a,OU=b"))
+	assert.Equal(t, "a,b", rdnValue(`cn=a\,b,ou=c`))
+}
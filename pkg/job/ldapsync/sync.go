@@ -0,0 +1,64 @@
+package main
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"flag"
+
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/db/mysql"
+	redis2 "github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/es"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/job/ldapsync/logic"
+)
+
+var (
+	configPATH = flag.String("config", "configs/config.yml", "-config=配置文件地址")
+	isUpdate   = flag.Int("isUpdate", 1, "-isUpdate=是否跟新已存在数据，1更新")
+	syncDep    = flag.Int("syncDep", 1, "-syncDep=是否同步组织数据,1同步")
+	tenantID   = flag.String("tenantID", "", "-tenantID=租户id")
+)
+
+func main() {
+	flag.Parse()
+	conf, err := configs.NewConfig(*configPATH)
+	if err != nil {
+		panic(err)
+	}
+	db, err := mysql.New(conf.Mysql, logger.Logger)
+	if err != nil {
+		logger.Logger.Error(err)
+		panic(err)
+	}
+	client, err := redis2.NewClient(conf.Redis)
+	if err != nil {
+		logger.Logger.Error(err)
+		panic(err)
+	}
+	es.New(&conf.Elastic, logger.Logger)
+	sync := &logic.SyncRequest{
+		IsUpdate: *isUpdate,
+		SyncDEP:  *syncDep,
+		TenantID: *tenantID,
+	}
+	ctx := header2.SetContext(context.Background(), user.TenantID, sync.TenantID)
+	res, err := logic.NewSync(*conf, db, client).SyncData(ctx, sync)
+	if err != nil {
+		panic(err)
+	}
+	logger.Logger.Infof("ldap synced, users: %d, departments: %d", res.Users, res.Deps)
+}
@@ -0,0 +1,70 @@
+package logic
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"gorm.io/gorm"
+
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+)
+
+const (
+	dayMillis = 24 * 60 * 60 * 1000
+)
+
+// Prune login audit retention job
+type Prune interface {
+	Prune(req *PruneRequest) (*PruneResponse, error)
+}
+
+type prune struct {
+	DB        *gorm.DB
+	eventRepo org.LoginEventRepo
+	conf      configs.Config
+}
+
+// NewPrune new
+func NewPrune(conf configs.Config, db *gorm.DB) Prune {
+	return &prune{
+		DB:        db,
+		eventRepo: mysql2.NewLoginEventRepo(),
+		conf:      conf,
+	}
+}
+
+// PruneRequest prune request
+type PruneRequest struct {
+}
+
+// PruneResponse prune response
+type PruneResponse struct {
+	Total int64 `json:"total"`
+}
+
+// Prune delete login events older than RetentionDays
+func (p *prune) Prune(req *PruneRequest) (*PruneResponse, error) {
+	res := &PruneResponse{}
+	if p.conf.LoginAudit.RetentionDays <= 0 {
+		return res, nil
+	}
+	before := time2.NowUnix() - p.conf.LoginAudit.RetentionDays*dayMillis
+	total, err := p.eventRepo.DeleteBefore(p.DB, before)
+	if err != nil {
+		return nil, err
+	}
+	res.Total = total
+	return res, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"flag"
+
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/db/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/job/loginaudit/logic"
+)
+
+var (
+	configPATH = flag.String("config", "configs/config.yml", "-config=配置文件地址")
+)
+
+func main() {
+	flag.Parse()
+	conf, err := configs.NewConfig(*configPATH)
+	if err != nil {
+		panic(err)
+	}
+	db, err := mysql.New(conf.Mysql, logger.Logger)
+	if err != nil {
+		logger.Logger.Error(err)
+		panic(err)
+	}
+	res, err := logic.NewPrune(*conf, db).Prune(&logic.PruneRequest{})
+	if err != nil {
+		panic(err)
+	}
+	logger.Logger.Infof("login events pruned: %d", res.Total)
+}
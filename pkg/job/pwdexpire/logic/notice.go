@@ -0,0 +1,132 @@
+package logic
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/account"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/consts"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	mysql2 "github.com/quanxiang-cloud/organizations/internal/models/org/mysql"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+)
+
+const (
+	dayMillis   = 24 * 60 * 60 * 1000
+	redisNotice = "organizations:pwdExpireNotice:"
+)
+
+// Notice password expire notice job
+type Notice interface {
+	SendNotice(ctx context.Context, req *NoticeRequest) (*NoticeResponse, error)
+}
+
+type notice struct {
+	DB          *gorm.DB
+	accountRepo org.AccountRepo
+	userRepo    org.UserRepo
+	message     message.Message
+	redisClient redis.UniversalClient
+	conf        configs.Config
+}
+
+// NewNotice new
+func NewNotice(conf configs.Config, db *gorm.DB, redisClient redis.UniversalClient) Notice {
+	return &notice{
+		DB:          db,
+		accountRepo: mysql2.NewAccountRepo(),
+		userRepo:    mysql2.NewUserRepo(),
+		message:     message.NewMessage(conf.InternalNet),
+		redisClient: redisClient,
+		conf:        conf,
+	}
+}
+
+// NoticeRequest notice request
+type NoticeRequest struct {
+	TenantID string
+}
+
+// NoticeResponse notice response
+type NoticeResponse struct {
+	Total int `json:"total"`
+}
+
+// SendNotice notice users whose password expires within PwdNoticeDays, at most once a day
+func (n *notice) SendNotice(ctx context.Context, req *NoticeRequest) (*NoticeResponse, error) {
+	res := &NoticeResponse{}
+	info := systems.GetSecurityInfo(ctx, n.conf, n.redisClient)
+	if info.PwdExpireDays <= 0 || info.PwdNoticeDays <= 0 {
+		return res, nil
+	}
+	now := time2.NowUnix()
+	begin := now - info.PwdExpireDays*dayMillis
+	end := begin + info.PwdNoticeDays*dayMillis
+	accounts := n.accountRepo.SelectByPasswordChangedAt(n.DB, begin, end)
+	if len(accounts) == 0 {
+		return res, nil
+	}
+	userAccount := make(map[string]*org.Account)
+	ids := make([]string, 0, len(accounts))
+	for k := range accounts {
+		if _, ok := userAccount[accounts[k].UserID]; !ok {
+			userAccount[accounts[k].UserID] = &accounts[k]
+			ids = append(ids, accounts[k].UserID)
+		}
+	}
+	reqs := make([]*message.CreateReq, 0)
+	marks := make([]string, 0)
+	for _, u := range n.userRepo.List(ctx, n.DB, ids...) {
+		if u.UseStatus != consts.NormalStatus || u.Email == "" {
+			continue
+		}
+		expireAt := account.PasswordExpireAt(userAccount[u.ID], info.PwdExpireDays)
+		days := (expireAt - now + dayMillis - 1) / dayMillis
+		if !n.redisClient.SetNX(ctx, redisNotice+u.ID, days, 23*time.Hour).Val() {
+			continue
+		}
+		marks = append(marks, redisNotice+u.ID)
+		mesReq := new(message.CreateReq)
+		mesReq.Email = &message.Email{
+			To: []string{u.Email},
+			Content: &message.Content{
+				TemplateID: n.conf.MessageTemplate.PwdExpire,
+				KeyAndValue: map[string]string{
+					"name": u.Name,
+					"days": strconv.FormatInt(days, 10),
+				},
+			},
+		}
+		reqs = append(reqs, mesReq)
+	}
+	if len(reqs) == 0 {
+		return res, nil
+	}
+	err := n.message.SendMessage(ctx, reqs)
+	if err != nil {
+		// nothing was sent, the next run notices them again
+		n.redisClient.Del(ctx, marks...)
+		return nil, err
+	}
+	res.Total = len(reqs)
+	return res, nil
+}
@@ -0,0 +1,96 @@
+package logic
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/organizations/internal/models/org"
+	"github.com/quanxiang-cloud/organizations/mock"
+	"github.com/quanxiang-cloud/organizations/pkg/message"
+	"github.com/quanxiang-cloud/organizations/pkg/systems"
+)
+
+// failMessage message server is down
+type failMessage struct{}
+
+func (failMessage) SendMessage(ctx context.Context, req []*message.CreateReq) error {
+	return errors.New("message server is down")
+}
+
+func TestSendNotice(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+	info, _ := json.Marshal(systems.SecurityInfo{PwdExpireDays: 90, PwdNoticeDays: 7})
+	redisClient.Set(ctx, "orgs:systems:secret", info, time.Minute)
+
+	// the passwords of the fixture users expire in 3 and 5 days
+	now := time2.NowUnix()
+	accountRepo := mock.NewMockAccountRepo(ctl)
+	accountRepo.EXPECT().SelectByPasswordChangedAt(gomock.Any(), gomock.Any(), gomock.Any()).Return([]org.Account{
+		{ID: "1", UserID: "1", PasswordChangedAt: now - 87*dayMillis},
+		{ID: "2", UserID: "2", PasswordChangedAt: now - 85*dayMillis},
+		{ID: "0", UserID: "0", PasswordChangedAt: now - 85*dayMillis},
+	}).AnyTimes()
+	userRepo := mock.NewMockUserRepo(ctl)
+	userRepo.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	n := &notice{
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		message:     failMessage{},
+		redisClient: redisClient,
+	}
+
+	// a failed send leaves no marker
+	_, err = n.SendNotice(ctx, &NoticeRequest{})
+	assert.NotNil(t, err)
+	assert.False(t, mr.Exists(redisNotice+"1"))
+	assert.False(t, mr.Exists(redisNotice+"2"))
+
+	sink := message.NewSink()
+	n.message = sink
+	res, err := n.SendNotice(ctx, &NoticeRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, res.Total)
+	emails := sink.Emails("test1@test.com")
+	if assert.Len(t, emails, 1) {
+		assert.Equal(t, "3", emails[0].Content.KeyAndValue["days"])
+	}
+	emails = sink.Emails("test2@test.com")
+	if assert.Len(t, emails, 1) {
+		assert.Equal(t, "5", emails[0].Content.KeyAndValue["days"])
+	}
+
+	// at most once a day
+	res, err = n.SendNotice(ctx, &NoticeRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, res.Total)
+	assert.Len(t, sink.Messages(), 3)
+}
@@ -0,0 +1,58 @@
+package main
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"flag"
+
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/db/mysql"
+	redis2 "github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/organizations/internal/logic/org/user"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/header2"
+	"github.com/quanxiang-cloud/organizations/pkg/job/pwdexpire/logic"
+)
+
+var (
+	configPATH = flag.String("config", "configs/config.yml", "-config=配置文件地址")
+	tenantID   = flag.String("tenantID", "", "-tenantID=租户id")
+)
+
+func main() {
+	flag.Parse()
+	conf, err := configs.NewConfig(*configPATH)
+	if err != nil {
+		panic(err)
+	}
+	db, err := mysql.New(conf.Mysql, logger.Logger)
+	if err != nil {
+		logger.Logger.Error(err)
+		panic(err)
+	}
+	client, err := redis2.NewClient(conf.Redis)
+	if err != nil {
+		logger.Logger.Error(err)
+		panic(err)
+	}
+	req := &logic.NoticeRequest{
+		TenantID: *tenantID,
+	}
+	ctx := header2.SetContext(context.Background(), user.TenantID, req.TenantID)
+	res, err := logic.NewNotice(*conf, db, client).SendNotice(ctx, req)
+	if err != nil {
+		panic(err)
+	}
+	logger.Logger.Infof("password expire notice sent: %d", res.Total)
+}
@@ -0,0 +1,202 @@
+package jws
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+const (
+	// AlgRS256 the only algorithm signed and accepted
+	AlgRS256 = "RS256"
+
+	keyBits = 2048
+)
+
+// ErrInvalidToken token is malformed or its signature is wrong
+var ErrInvalidToken = errors.New("invalid token")
+
+// Header jose header
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// JWK public rsa key
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS key set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Signer sign and verify compact json web signatures with rs256
+type Signer struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+// NewSigner load the pem private key of keyFile, a key is generated when keyFile is empty
+func NewSigner(keyFile string) (*Signer, error) {
+	if keyFile == "" {
+		key, err := rsa.GenerateKey(rand.Reader, keyBits)
+		if err != nil {
+			return nil, err
+		}
+		return NewSignerWithKey(key), nil
+	}
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewSignerWithKey(key), nil
+}
+
+// NewSignerWithKey new
+func NewSignerWithKey(key *rsa.PrivateKey) *Signer {
+	s := &Signer{key: key}
+	s.kid = thumbprint(&key.PublicKey)
+	return s
+}
+
+// ParsePrivateKey parse pkcs1 or pkcs8 pem rsa private key
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no pem block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not a rsa private key")
+	}
+	return rsaKey, nil
+}
+
+// KeyID kid of the signing key
+func (s *Signer) KeyID() string {
+	return s.kid
+}
+
+// Sign sign claims, typ goes to the header
+func (s *Signer) Sign(typ string, claims interface{}) (string, error) {
+	header, err := json.Marshal(Header{Alg: AlgRS256, Typ: typ, Kid: s.kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := encode(header) + "." + encode(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + encode(sig), nil
+}
+
+// Verify check the signature of token and unmarshal its claims, it returns the header
+func (s *Signer) Verify(token string, claims interface{}) (*Header, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	data, err := decode(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	header := new(Header)
+	if err = json.Unmarshal(data, header); err != nil || header.Alg != AlgRS256 || header.Kid != s.kid {
+		return nil, ErrInvalidToken
+	}
+	sig, err := decode(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(&s.key.PublicKey, crypto.SHA256, digest[:], sig) != nil {
+		return nil, ErrInvalidToken
+	}
+	payload, err := decode(parts[1])
+	if err != nil || json.Unmarshal(payload, claims) != nil {
+		return nil, ErrInvalidToken
+	}
+	return header, nil
+}
+
+// JWKS public key set
+func (s *Signer) JWKS() JWKS {
+	return JWKS{
+		Keys: []JWK{{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: AlgRS256,
+			Kid: s.kid,
+			N:   encode(s.key.PublicKey.N.Bytes()),
+			E:   encode(big.NewInt(int64(s.key.PublicKey.E)).Bytes()),
+		}},
+	}
+}
+
+// HalfHash left half of the sha256 of value, as at_hash and c_hash
+func HalfHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return encode(sum[:len(sum)/2])
+}
+
+// thumbprint rfc 7638 thumbprint of public key
+func thumbprint(key *rsa.PublicKey) string {
+	// members in lexicographic order without whitespace
+	data := `{"e":"` + encode(big.NewInt(int64(key.E)).Bytes()) + `","kty":"RSA","n":"` + encode(key.N.Bytes()) + `"}`
+	sum := sha256.Sum256([]byte(data))
+	return encode(sum[:])
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
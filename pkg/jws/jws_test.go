@@ -0,0 +1,111 @@
+package jws
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type claims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+}
+
+func TestSignAndVerify(t *testing.T) {
+	s, err := NewSigner("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.Sign("JWT", claims{Sub: "1", Exp: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(strings.Split(token, ".")))
+
+	got := new(claims)
+	header, err := s.Verify(token, got)
+	assert.NoError(t, err)
+	assert.Equal(t, "JWT", header.Typ)
+	assert.Equal(t, s.KeyID(), header.Kid)
+	assert.Equal(t, claims{Sub: "1", Exp: 100}, *got)
+
+	// payload of another token under the signature of this one
+	other, err := s.Sign("JWT", claims{Sub: "2"})
+	assert.NoError(t, err)
+	parts, otherParts := strings.Split(token, "."), strings.Split(other, ".")
+	_, err = s.Verify(parts[0]+"."+otherParts[1]+"."+parts[2], got)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	another, err := NewSigner("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = another.Verify(token, got)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	_, err = s.Verify("a.b", got)
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestNewSignerFromFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "jws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for typ, der := range map[string][]byte{
+		"RSA PRIVATE KEY": x509.MarshalPKCS1PrivateKey(key),
+		"PRIVATE KEY":     pkcs8,
+	} {
+		file := filepath.Join(dir, "key.pem")
+		err = ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewSigner(file)
+		if !assert.NoError(t, err, typ) {
+			continue
+		}
+		// the key id is stable across restarts
+		assert.Equal(t, NewSignerWithKey(key).KeyID(), s.KeyID())
+		jwks := s.JWKS()
+		assert.Equal(t, 1, len(jwks.Keys))
+		assert.Equal(t, "AQAB", jwks.Keys[0].E)
+		assert.Equal(t, s.KeyID(), jwks.Keys[0].Kid)
+	}
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestHalfHash(t *testing.T) {
+	// first 16 bytes of the sha256, base64 url encoded without padding
+	assert.Equal(t, "9nZYRIfiRb2rDSSME9eoyw", HalfHash("jHkWEdUXMU1BwAsC4vtUsZwnNDEMfd8M2Ek7ekpH5Zg"))
+}
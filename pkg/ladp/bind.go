@@ -0,0 +1,172 @@
+package ldap
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+)
+
+const (
+	defaultFilter  = "(mail=%s)"
+	defaultTimeout = 10 * time.Second
+)
+
+// Directory the directory serving the mail domain of userName for the tenant
+func Directory(conf configs.Ldap, tenantID, userName string) *configs.LdapDirectory {
+	i := strings.LastIndex(userName, "@")
+	if i < 0 {
+		return nil
+	}
+	domain := userName[i+1:]
+	var shared *configs.LdapDirectory
+	for k := range conf.Directories {
+		dir := &conf.Directories[k]
+		if dir.TenantID != "" && dir.TenantID != tenantID {
+			continue
+		}
+		for _, v := range dir.Domains {
+			if !strings.EqualFold(v, domain) {
+				continue
+			}
+			if dir.TenantID != "" {
+				return dir
+			}
+			if shared == nil {
+				shared = dir
+			}
+		}
+	}
+	return shared
+}
+
+// UseLdap whether userName logs in by ldap
+func UseLdap(conf configs.Ldap, tenantID, userName string) bool {
+	if !conf.Open {
+		return false
+	}
+	if Directory(conf, tenantID, userName) != nil {
+		return true
+	}
+	split := strings.Split(userName, "@")
+	return len(split) == 2 && conf.Regex != "" && split[1] == conf.Regex
+}
+
+type bindLdap struct {
+	Ldap
+	conf configs.Ldap
+}
+
+// NewBindLdap auth users of configured directories by binding them, the rest goes to next
+func NewBindLdap(conf configs.Ldap, next Ldap) Ldap {
+	return &bindLdap{
+		Ldap: next,
+		conf: conf,
+	}
+}
+
+// Auth auth
+func (b *bindLdap) Auth(ctx context.Context, header http.Header, r *AuthReq) (*AuthResp, error) {
+	dir := Directory(b.conf, r.TenantID, r.UserName)
+	if dir == nil {
+		return b.Ldap.Auth(ctx, header, r)
+	}
+	flag, err := Bind(dir, r.UserName, r.Password)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthResp{Flag: flag}, nil
+}
+
+// Bind check the password by binding as the entry of userName
+func Bind(dir *configs.LdapDirectory, userName, password string) (bool, error) {
+	// an empty password is an unauthenticated bind, which most servers accept
+	if password == "" {
+		return false, nil
+	}
+	conn, err := Dial(dir)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	filter := dir.Filter
+	if filter == "" {
+		filter = defaultFilter
+	}
+	res, err := conn.Search(goldap.NewSearchRequest(
+		dir.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(filter, goldap.EscapeFilter(userName)), []string{"dn"}, nil,
+	))
+	if err != nil {
+		return false, err
+	}
+	if len(res.Entries) != 1 {
+		return false, nil
+	}
+	err = conn.Bind(res.Entries[0].DN, password)
+	if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Dial connect the directory and bind the search account
+func Dial(dir *configs.LdapDirectory) (*goldap.Conn, error) {
+	timeout := dir.Timeout * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	u, err := url.Parse(dir.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: dir.InsecureSkipVerify,
+	}
+	conn, err := goldap.DialURL(dir.URL,
+		goldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		goldap.DialWithTLSConfig(tlsConf),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	if dir.StartTLS {
+		if err = conn.StartTLS(tlsConf); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if dir.BindDN != "" {
+		if err = conn.Bind(dir.BindDN, dir.BindPassword); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
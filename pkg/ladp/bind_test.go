@@ -0,0 +1,140 @@
+package ldap
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+	"github.com/quanxiang-cloud/organizations/pkg/ladp/ldaptest"
+)
+
+type nextLdap struct {
+	Ldap
+	called bool
+}
+
+func (n *nextLdap) Auth(ctx context.Context, header http.Header, r *AuthReq) (*AuthResp, error) {
+	n.called = true
+	return &AuthResp{Flag: true}, nil
+}
+
+func newDirectory(t *testing.T) (*ldaptest.Server, configs.LdapDirectory) {
+	server, err := ldaptest.NewServer(
+		&ldaptest.Entry{
+			DN:       "cn=reader,dc=test,dc=com",
+			Password: "reader",
+		},
+		&ldaptest.Entry{
+			DN:       "cn=zhangsan,ou=dev,dc=test,dc=com",
+			Password: "654321a..",
+			Attributes: map[string][]string{
+				"objectClass":       {"person"},
+				"mail":              {"zhangsan@test.com"},
+				"userPrincipalName": {"zhangsan@corp.test.com"},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, configs.LdapDirectory{
+		Domains:      []string{"test.com"},
+		URL:          server.URL,
+		BindDN:       "cn=reader,dc=test,dc=com",
+		BindPassword: "reader",
+		BaseDN:       "dc=test,dc=com",
+	}
+}
+
+func TestBind(t *testing.T) {
+	server, dir := newDirectory(t)
+	defer server.Close()
+
+	flag, err := Bind(&dir, "zhangsan@test.com", "654321a..")
+	assert.Nil(t, err)
+	assert.True(t, flag)
+	flag, err = Bind(&dir, "ZHANGSAN@test.com", "654321a..")
+	assert.Nil(t, err)
+	assert.True(t, flag)
+
+	flag, err = Bind(&dir, "zhangsan@test.com", "123456")
+	assert.Nil(t, err)
+	assert.False(t, flag)
+	flag, err = Bind(&dir, "zhangsan@test.com", "")
+	assert.Nil(t, err)
+	assert.False(t, flag)
+	flag, err = Bind(&dir, "lisi@test.com", "654321a..")
+	assert.Nil(t, err)
+	assert.False(t, flag)
+	// the login name is escaped, not a wildcard
+	flag, err = Bind(&dir, "*", "654321a..")
+	assert.Nil(t, err)
+	assert.False(t, flag)
+
+	dir.Filter = "(&(objectClass=person)(userPrincipalName=%s))"
+	flag, err = Bind(&dir, "zhangsan@corp.test.com", "654321a..")
+	assert.Nil(t, err)
+	assert.True(t, flag)
+
+	dir.BindPassword = "wrong"
+	_, err = Bind(&dir, "zhangsan@corp.test.com", "654321a..")
+	assert.NotNil(t, err)
+}
+
+func TestDirectory(t *testing.T) {
+	conf := configs.Ldap{
+		Open:  true,
+		Regex: "yunify.com",
+		Directories: []configs.LdapDirectory{
+			{Domains: []string{"test.com"}, URL: "ldap://shared"},
+			{TenantID: "t1", Domains: []string{"test.com", "corp.test.com"}, URL: "ldap://t1"},
+		},
+	}
+	assert.Equal(t, "ldap://shared", Directory(conf, "", "zhangsan@test.com").URL)
+	assert.Equal(t, "ldap://shared", Directory(conf, "t2", "zhangsan@TEST.com").URL)
+	assert.Equal(t, "ldap://t1", Directory(conf, "t1", "zhangsan@test.com").URL)
+	assert.Equal(t, "ldap://t1", Directory(conf, "t1", "zhangsan@corp.test.com").URL)
+	assert.Nil(t, Directory(conf, "t2", "zhangsan@corp.test.com"))
+	assert.Nil(t, Directory(conf, "", "13688886666"))
+
+	assert.True(t, UseLdap(conf, "", "zhangsan@test.com"))
+	assert.True(t, UseLdap(conf, "", "zhangsan@yunify.com"))
+	assert.False(t, UseLdap(conf, "", "zhangsan@other.com"))
+	assert.False(t, UseLdap(conf, "", "13688886666"))
+	conf.Open = false
+	assert.False(t, UseLdap(conf, "", "zhangsan@test.com"))
+}
+
+func TestBindLdapAuth(t *testing.T) {
+	server, dir := newDirectory(t)
+	defer server.Close()
+
+	next := &nextLdap{}
+	client := NewBindLdap(configs.Ldap{Open: true, Directories: []configs.LdapDirectory{dir}}, next)
+	resp, err := client.Auth(context.Background(), nil, &AuthReq{UserName: "zhangsan@test.com", Password: "654321a.."})
+	assert.Nil(t, err)
+	assert.True(t, resp.Flag)
+	resp, err = client.Auth(context.Background(), nil, &AuthReq{UserName: "zhangsan@test.com", Password: "123456"})
+	assert.Nil(t, err)
+	assert.False(t, resp.Flag)
+	assert.False(t, next.called)
+
+	_, err = client.Auth(context.Background(), nil, &AuthReq{UserName: "zhangsan@yunify.com", Password: "654321a.."})
+	assert.Nil(t, err)
+	assert.True(t, next.called)
+}
@@ -0,0 +1,364 @@
+package ldaptest
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// Entry directory entry, Password is checked by bind
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server in-process ldap server for tests, it speaks simple bind, search and unbind
+type Server struct {
+	// URL ldap://127.0.0.1:port
+	URL string
+
+	listener net.Listener
+	mu       sync.RWMutex
+	entries  []*Entry
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer listen on a random local port
+func NewServer(entries ...*Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Add add entries
+func (s *Server) Add(entries ...*Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+}
+
+// Close stop listening and drop open connections
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value
+		op := packet.Children[1]
+		var replies []*ber.Packet
+		var controls *ber.Packet
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			replies = append(replies, result(goldap.ApplicationBindResponse, s.bind(op), ""))
+		case goldap.ApplicationSearchRequest:
+			replies = s.search(op)
+			if len(packet.Children) > 2 && hasControl(packet.Children[2], goldap.ControlTypePaging) {
+				// everything fits in one page, an empty cookie ends paging
+				controls = ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+				controls.AppendChild(goldap.NewControlPaging(0).Encode())
+			}
+		case goldap.ApplicationUnbindRequest:
+			return
+		case goldap.ApplicationAbandonRequest:
+			continue
+		case goldap.ApplicationExtendedRequest:
+			replies = append(replies, result(goldap.ApplicationExtendedResponse, goldap.LDAPResultProtocolError, "unsupported"))
+		default:
+			return
+		}
+		for i := range replies {
+			if i == len(replies)-1 {
+				replies[i] = message(id, replies[i], controls)
+			} else {
+				replies[i] = message(id, replies[i], nil)
+			}
+		}
+		for _, reply := range replies {
+			if _, err = conn.Write(reply.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func message(id interface{}, op, controls *ber.Packet) *ber.Packet {
+	packet := ber.NewSequence("LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	packet.AppendChild(op)
+	if controls != nil {
+		packet.AppendChild(controls)
+	}
+	return packet
+}
+
+func result(tag ber.Tag, code uint16, diagnostic string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, diagnostic, "diagnosticMessage"))
+	return op
+}
+
+func hasControl(controls *ber.Packet, oid string) bool {
+	for _, control := range controls.Children {
+		if len(control.Children) > 0 && str(control.Children[0]) == oid {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) bind(op *ber.Packet) uint16 {
+	if len(op.Children) < 3 {
+		return goldap.LDAPResultProtocolError
+	}
+	dn := str(op.Children[1])
+	password := str(op.Children[2])
+	if dn == "" && password == "" {
+		return goldap.LDAPResultSuccess
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, entry := range s.entries {
+		if sameDN(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return goldap.LDAPResultSuccess
+		}
+	}
+	return goldap.LDAPResultInvalidCredentials
+}
+
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{result(goldap.ApplicationSearchResultDone, goldap.LDAPResultProtocolError, "")}
+	}
+	base := normalizeDN(str(op.Children[0]))
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	attributes := make([]string, 0, len(op.Children[7].Children))
+	for _, v := range op.Children[7].Children {
+		attributes = append(attributes, str(v))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	replies := make([]*ber.Packet, 0)
+	for _, entry := range s.entries {
+		if !inScope(normalizeDN(entry.DN), base, scope) || !match(entry, filter) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(replies)) >= sizeLimit {
+			return append(replies, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSizeLimitExceeded, ""))
+		}
+		replies = append(replies, encodeEntry(entry, attributes))
+	}
+	return append(replies, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess, ""))
+}
+
+func encodeEntry(entry *Entry, attributes []string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "objectName"))
+	attrs := ber.NewSequence("attributes")
+	for name, values := range entry.Attributes {
+		if !wanted(name, attributes) {
+			continue
+		}
+		attr := ber.NewSequence("attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(vals)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return op
+}
+
+func wanted(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+	for _, v := range attributes {
+		if v == "*" || strings.EqualFold(v, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func inScope(dn, base string, scope int64) bool {
+	switch scope {
+	case goldap.ScopeBaseObject:
+		return dn == base
+	case goldap.ScopeSingleLevel:
+		i := strings.Index(dn, ",")
+		return i >= 0 && dn[i+1:] == base
+	default:
+		return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+// match ldap filter, ordering and approximate matches are not supported
+func match(entry *Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, v := range filter.Children {
+			if !match(entry, v) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, v := range filter.Children {
+			if match(entry, v) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return len(filter.Children) == 1 && !match(entry, filter.Children[0])
+	case goldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := str(filter.Children[1])
+		for _, v := range values(entry, str(filter.Children[0])) {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterPresent:
+		return len(values(entry, string(filter.Data.Bytes()))) > 0
+	case goldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		for _, v := range values(entry, str(filter.Children[0])) {
+			if substrings(strings.ToLower(v), filter.Children[1]) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func substrings(value string, subs *ber.Packet) bool {
+	for _, sub := range subs.Children {
+		part := strings.ToLower(str(sub))
+		switch sub.Tag {
+		case goldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, part) {
+				return false
+			}
+			value = value[len(part):]
+		case goldap.FilterSubstringsAny:
+			i := strings.Index(value, part)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(part):]
+		case goldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, part) {
+				return false
+			}
+			value = ""
+		}
+	}
+	return true
+}
+
+func values(entry *Entry, name string) []string {
+	if strings.EqualFold(name, "dn") || strings.EqualFold(name, "distinguishedName") {
+		return []string{entry.DN}
+	}
+	for k, v := range entry.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func str(p *ber.Packet) string {
+	if v, ok := p.Value.(string); ok {
+		return v
+	}
+	return string(p.Data.Bytes())
+}
+
+func sameDN(a, b string) bool {
+	return normalizeDN(a) == normalizeDN(b)
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, v := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(v))
+	}
+	return strings.Join(parts, ",")
+}
@@ -54,6 +54,8 @@ type data struct {
 
 // Phone Phone
 type Phone struct {
+	To      []string `json:"to"`
+	Content *Content `json:"contents"`
 }
 
 // Letter Letter
@@ -0,0 +1,99 @@
+package message
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"sync"
+
+	"github.com/quanxiang-cloud/cabin/logger"
+)
+
+// Sink keep messages in memory instead of sending them to message server,
+// it lets code, activation and reset flows run offline
+type Sink struct {
+	mu   sync.Mutex
+	reqs []*CreateReq
+}
+
+// NewSink new
+func NewSink() *Sink {
+	return &Sink{}
+}
+
+// SendMessage keep req
+func (s *Sink) SendMessage(ctx context.Context, req []*CreateReq) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reqs = append(s.reqs, req...)
+	if logger.Logger != nil {
+		// contents carry passwords, codes and links, only template and recipients are logged
+		for _, one := range req {
+			if one.Email != nil {
+				logger.Logger.Infof("message sink: email %s to %v", templateID(one.Email.Content), one.Email.To)
+			}
+			if one.Phone != nil {
+				logger.Logger.Infof("message sink: phone %s to %v", templateID(one.Phone.Content), one.Phone.To)
+			}
+		}
+	}
+	return nil
+}
+
+func templateID(content *Content) string {
+	if content == nil {
+		return ""
+	}
+	return content.TemplateID
+}
+
+// Messages messages kept so far
+func (s *Sink) Messages() []*CreateReq {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]*CreateReq, len(s.reqs))
+	copy(res, s.reqs)
+	return res
+}
+
+// Phones phone messages sent to number
+func (s *Sink) Phones(number string) []*Phone {
+	res := make([]*Phone, 0)
+	for _, req := range s.Messages() {
+		if req.Phone == nil {
+			continue
+		}
+		for _, to := range req.Phone.To {
+			if to == number {
+				res = append(res, req.Phone)
+			}
+		}
+	}
+	return res
+}
+
+// Emails email messages sent to address
+func (s *Sink) Emails(address string) []*Email {
+	res := make([]*Email, 0)
+	for _, req := range s.Messages() {
+		if req.Email == nil {
+			continue
+		}
+		for _, to := range req.Email.To {
+			if to == address {
+				res = append(res, req.Email)
+			}
+		}
+	}
+	return res
+}
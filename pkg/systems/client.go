@@ -73,6 +73,15 @@ type SecurityInfo struct {
 	LoginType     int64  `json:"loginType"`
 	PwdChange     bool   `json:"pwdChange"`
 	M2FA          bool   `json:"M2FA"`
+	PwdHistory    int64  `json:"pwdHistory"`
+}
+
+// AllowLogin whether the tenant allows the login type, all but link login are allowed when LoginType is not set
+func (s *SecurityInfo) AllowLogin(loginType int64) bool {
+	if s.LoginType == 0 {
+		return loginType != LoginTypeLink
+	}
+	return s.LoginType&loginType != 0
 }
 
 // GetEnterpriseInfo  get enterprise info
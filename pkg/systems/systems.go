@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	ginheader "github.com/quanxiang-cloud/cabin/tailormade/header"
 
 	"github.com/quanxiang-cloud/organizations/pkg/configs"
 )
@@ -29,13 +30,21 @@ const (
 	defaultPasswordRule = 15 //0x1111
 )
 
+// login types, bits of SecurityInfo.LoginType
+const (
+	LoginTypePwd int64 = 1 << iota
+	LoginTypeCode
+	LoginTypeLdap
+	LoginTypeLink
+)
+
 //GetSecurityInfo get info
 func GetSecurityInfo(ctx context.Context, conf configs.Config, redisClient redis.UniversalClient) *SecurityInfo {
 	newSystems := NewSystems(conf.InternalNet)
 	info, err := newSystems.GetSecurityInfo(ctx)
 	securityInfo := SecurityInfo{}
 	if err != nil {
-		val := redisClient.Get(ctx, systemRedis).Val()
+		val := redisClient.Get(ctx, cacheKey(ctx)).Val()
 		if val != "" {
 			json.Unmarshal([]byte(val), &securityInfo)
 			return &securityInfo
@@ -55,7 +64,16 @@ func GetSecurityInfo(ctx context.Context, conf configs.Config, redisClient redis
 	}
 
 	marshal, _ := json.Marshal(info)
-	redisClient.SetEX(ctx, systemRedis, string(marshal), expireTime*time.Hour)
+	redisClient.SetEX(ctx, cacheKey(ctx), string(marshal), expireTime*time.Hour)
 	return info
 
 }
+
+// cacheKey the security info is cached per tenant, the tenant of ctx
+func cacheKey(ctx context.Context) string {
+	_, tenantID := ginheader.GetTenantID(ctx).Wreck()
+	if tenantID == "" {
+		return systemRedis
+	}
+	return systemRedis + ":" + tenantID
+}
@@ -0,0 +1,34 @@
+package systems
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import "testing"
+
+func TestAllowLogin(t *testing.T) {
+	tests := []struct {
+		loginType int64
+		allow     map[int64]bool
+	}{
+		{0, map[int64]bool{LoginTypePwd: true, LoginTypeCode: true, LoginTypeLdap: true, LoginTypeLink: false}},
+		{LoginTypeCode, map[int64]bool{LoginTypePwd: false, LoginTypeCode: true, LoginTypeLdap: false, LoginTypeLink: false}},
+		{LoginTypePwd | LoginTypeLink, map[int64]bool{LoginTypePwd: true, LoginTypeCode: false, LoginTypeLdap: false, LoginTypeLink: true}},
+	}
+	for _, tt := range tests {
+		info := &SecurityInfo{LoginType: tt.loginType}
+		for loginType, want := range tt.allow {
+			if got := info.AllowLogin(loginType); got != want {
+				t.Errorf("LoginType %d: AllowLogin(%d) = %v, want %v", tt.loginType, loginType, got, want)
+			}
+		}
+	}
+}
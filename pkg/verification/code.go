@@ -0,0 +1,127 @@
+package verification
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+)
+
+const (
+	redisCodeSend    = "organizations:codeSend:"
+	redisCodeAttempt = "organizations:codeAttempt:"
+)
+
+// Code verification code store, sending is limited per recipient and ip,
+// and a code is dropped after too many wrong values
+type Code interface {
+	// Create keep value as the code of model for recipient
+	Create(ctx context.Context, model, recipient, ip, value string) error
+	// Verify compare value with the code of model for recipient
+	Verify(ctx context.Context, model, recipient, value string) error
+	// Del drop the code once it is used
+	Del(ctx context.Context, model, recipient string)
+}
+
+type verifyCode struct {
+	conf        configs.VerificationCode
+	redisClient redis.UniversalClient
+}
+
+// NewCode new
+func NewCode(conf configs.VerificationCode, redisClient redis.UniversalClient) Code {
+	return &verifyCode{
+		conf:        conf,
+		redisClient: redisClient,
+	}
+}
+
+type quota struct {
+	key    string
+	limit  int64
+	window time.Duration
+}
+
+func (v *verifyCode) quotas(recipient, ip string) []quota {
+	quotas := []quota{
+		{redisCodeSend + "minute:" + recipient, v.conf.MinuteLimit, time.Minute},
+		{redisCodeSend + "day:" + recipient, v.conf.DayLimit, 24 * time.Hour},
+	}
+	if ip != "" {
+		quotas = append(quotas,
+			quota{redisCodeSend + "ipMinute:" + ip, v.conf.IPMinuteLimit, time.Minute},
+			quota{redisCodeSend + "ipDay:" + ip, v.conf.IPDayLimit, 24 * time.Hour},
+		)
+	}
+	return quotas
+}
+
+func (v *verifyCode) Create(ctx context.Context, model, recipient, ip, value string) error {
+	key := model + ":" + recipient
+	if v.redisClient.Exists(ctx, key).Val() == 1 {
+		return error2.New(code.ValidVerificationCode)
+	}
+	quotas := v.quotas(recipient, ip)
+	for _, q := range quotas {
+		if q.limit <= 0 {
+			continue
+		}
+		num, _ := v.redisClient.Get(ctx, q.key).Int64()
+		if num >= q.limit {
+			return error2.New(code.LimitVerificationCode)
+		}
+	}
+	for _, q := range quotas {
+		if q.limit <= 0 {
+			continue
+		}
+		if v.redisClient.Incr(ctx, q.key).Val() == 1 {
+			v.redisClient.Expire(ctx, q.key, q.window)
+		}
+	}
+	v.redisClient.Del(ctx, redisCodeAttempt+key)
+	return v.redisClient.SetEX(ctx, key, value, v.conf.ExpireTime*time.Second).Err()
+}
+
+func (v *verifyCode) Verify(ctx context.Context, model, recipient, value string) error {
+	key := model + ":" + recipient
+	val := v.redisClient.Get(ctx, key).Val()
+	if val == "" {
+		return error2.New(code.ExpireVerificationCode)
+	}
+	if value != "" && value == val {
+		return nil
+	}
+	if v.conf.MaxAttempts > 0 {
+		num := v.redisClient.Incr(ctx, redisCodeAttempt+key).Val()
+		if num == 1 {
+			v.redisClient.Expire(ctx, redisCodeAttempt+key, v.conf.ExpireTime*time.Second)
+		}
+		if num >= v.conf.MaxAttempts {
+			v.Del(ctx, model, recipient)
+			return error2.New(code.ExpireVerificationCode)
+		}
+	}
+	return error2.New(code.InvalidVerificationCode)
+}
+
+func (v *verifyCode) Del(ctx context.Context, model, recipient string) {
+	key := model + ":" + recipient
+	v.redisClient.Del(ctx, key, redisCodeAttempt+key)
+}
@@ -0,0 +1,95 @@
+package verification
+
+/*
+Copyright 2022 QuanxiangCloud Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+     http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/organizations/pkg/code"
+	"github.com/quanxiang-cloud/organizations/pkg/configs"
+)
+
+func newTestCode(t *testing.T, conf configs.VerificationCode) (Code, *miniredis.Miniredis) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	conf.ExpireTime = 300
+	return NewCode(conf, redis.NewClient(&redis.Options{Addr: s.Addr()})), s
+}
+
+func assertCode(t *testing.T, err error, want int64) {
+	t.Helper()
+	if err == nil || err.Error() != error2.New(want).Error() {
+		t.Fatalf("got %v want %s", err, error2.New(want).Error())
+	}
+}
+
+func TestSendQuota(t *testing.T) {
+	ctx := context.Background()
+	c, s := newTestCode(t, configs.VerificationCode{DayLimit: 2, IPMinuteLimit: 3})
+
+	if err := c.Create(ctx, "code:login", "a@test.com", "10.0.0.1", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	// unexpired code is not replaced
+	assertCode(t, c.Create(ctx, "code:login", "a@test.com", "10.0.0.1", "abc124"), code.ValidVerificationCode)
+
+	// quotas are shared by all kinds of code
+	if err := c.Create(ctx, "code:forget", "a@test.com", "10.0.0.1", "abc125"); err != nil {
+		t.Fatal(err)
+	}
+	assertCode(t, c.Create(ctx, "code:reset", "a@test.com", "10.0.0.1", "abc126"), code.LimitVerificationCode)
+
+	if err := c.Create(ctx, "code:login", "b@test.com", "10.0.0.1", "abc127"); err != nil {
+		t.Fatal(err)
+	}
+	assertCode(t, c.Create(ctx, "code:login", "c@test.com", "10.0.0.1", "abc128"), code.LimitVerificationCode)
+	if err := c.Create(ctx, "code:login", "c@test.com", "10.0.0.2", "abc129"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.FastForward(61e9)
+	if err := c.Create(ctx, "code:login", "d@test.com", "10.0.0.1", "abc130"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCode(t, configs.VerificationCode{MaxAttempts: 3})
+
+	if err := c.Create(ctx, "code:login", "a@test.com", "", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	assertCode(t, c.Verify(ctx, "code:login", "a@test.com", "000000"), code.InvalidVerificationCode)
+	assertCode(t, c.Verify(ctx, "code:login", "a@test.com", ""), code.InvalidVerificationCode)
+	if err := c.Verify(ctx, "code:login", "a@test.com", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	// the third wrong value drops the code, even the right one is refused then
+	assertCode(t, c.Verify(ctx, "code:login", "a@test.com", "000000"), code.ExpireVerificationCode)
+	assertCode(t, c.Verify(ctx, "code:login", "a@test.com", "abc123"), code.ExpireVerificationCode)
+
+	if err := c.Create(ctx, "code:login", "a@test.com", "", "abc124"); err != nil {
+		t.Fatal(err)
+	}
+	c.Del(ctx, "code:login", "a@test.com")
+	assertCode(t, c.Verify(ctx, "code:login", "a@test.com", "abc124"), code.ExpireVerificationCode)
+}
@@ -0,0 +1,184 @@
+alter table org_user_account modify password varchar(255) null;
+
+create table org_user_account_factor
+(
+    id             varchar(64) not null
+        primary key,
+    user_id        varchar(64) null,
+    secret         varchar(64) null,
+    recovery_codes text        null,
+    status         int         null,
+    created_at     bigint      null,
+    updated_at     bigint      null,
+    updated_by     varchar(64) null,
+    constraint user_id
+        unique (user_id)
+);
+
+alter table org_user_account
+    add password_changed_at bigint null;
+-- updated_at follows every write in seconds, the age of existing passwords starts now in milliseconds
+update org_user_account set password_changed_at = unix_timestamp() * 1000
+where password_changed_at is null or password_changed_at = 0;
+
+create table org_user_password_history
+(
+    id         varchar(64)  not null
+        primary key,
+    user_id    varchar(64)  null,
+    password   varchar(255) null,
+    created_at bigint       null
+);
+
+create index user_id
+    on org_user_password_history (user_id);
+
+create table org_account_unlock_record
+(
+    id         varchar(64) not null
+        primary key,
+    types      varchar(16) null,
+    target     varchar(64) null,
+    fail_count int         null,
+    tenant_id  varchar(64) null,
+    created_at bigint      null,
+    created_by varchar(64) null
+);
+
+create table org_login_event
+(
+    id         varchar(64)  not null
+        primary key,
+    user_id    varchar(64)  null,
+    account    varchar(100) null,
+    tenant_id  varchar(64)  null,
+    types      varchar(16)  null,
+    result     int          null,
+    err_code   bigint       null,
+    ip         varchar(64)  null,
+    user_agent varchar(512) null,
+    created_at bigint       null
+);
+
+create index user_id
+    on org_login_event (user_id);
+
+create index created_at
+    on org_login_event (created_at);
+
+create table org_oidc_client
+(
+    id            varchar(64) not null
+        primary key,
+    name          varchar(64) null,
+    secret        varchar(64) null,
+    redirect_uris text        null,
+    public        int         null,
+    tenant_id     varchar(64) null,
+    created_at    bigint      null,
+    updated_at    bigint      null,
+    created_by    varchar(64) null,
+    updated_by    varchar(64) null
+);
+
+create table org_service_account
+(
+    id          varchar(64)  not null
+        primary key,
+    name        varchar(64)  null,
+    description varchar(255) null,
+    use_status  int          null,
+    tenant_id   varchar(64)  null,
+    created_at  bigint       null,
+    updated_at  bigint       null,
+    created_by  varchar(64)  null,
+    updated_by  varchar(64)  null
+);
+
+create table org_api_key
+(
+    id                 varchar(64) not null
+        primary key,
+    service_account_id varchar(64) null,
+    prefix             varchar(16) null,
+    key_hash           varchar(64) null,
+    expire_at          bigint      null,
+    last_used_at       bigint      null,
+    last_used_ip       varchar(64) null,
+    tenant_id          varchar(64) null,
+    created_at         bigint      null,
+    created_by         varchar(64) null,
+    constraint key_hash
+        unique (key_hash)
+);
+
+create index service_account_id
+    on org_api_key (service_account_id);
+
+create table org_invitation
+(
+    id           varchar(64) not null
+        primary key,
+    user_id      varchar(64) null,
+    send_to      varchar(64) null,
+    send_channel int         null,
+    nonce_hash   varchar(64) null,
+    status       int         null,
+    expire_at    bigint      null,
+    accepted_at  bigint      null,
+    tenant_id    varchar(64) null,
+    created_at   bigint      null,
+    updated_at   bigint      null,
+    created_by   varchar(64) null,
+    updated_by   varchar(64) null
+);
+
+create index user_id
+    on org_invitation (user_id);
+
+create table org_impersonation_event
+(
+    id         varchar(64)  not null
+        primary key,
+    session_id varchar(64)  null,
+    actor_id   varchar(64)  null,
+    user_id    varchar(64)  null,
+    action     varchar(16)  null,
+    reason     varchar(255) null,
+    method     varchar(16)  null,
+    path       varchar(255) null,
+    status     int          null,
+    ip         varchar(64)  null,
+    tenant_id  varchar(64)  null,
+    created_at bigint       null
+);
+
+create index session_id
+    on org_impersonation_event (session_id);
+
+create index actor_id
+    on org_impersonation_event (actor_id);
+
+create table org_import_job
+(
+    id           varchar(64)  not null
+        primary key,
+    file_name    varchar(255) null,
+    use_status   int          null,
+    is_update    int          null,
+    create_dep   int          null,
+    status       int          null,
+    total        int          null,
+    processed    int          null,
+    add_total    int          null,
+    update_total int          null,
+    fail_total   int          null,
+    message      varchar(255) null,
+    created_deps text         null,
+    fail_file    longblob     null,
+    tenant_id    varchar(64)  null,
+    finished_at  bigint       null,
+    created_at   bigint       null,
+    updated_at   bigint       null,
+    created_by   varchar(64)  null
+);